@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+var sampleTodos = []models.Todo{
+	{Title: "Buy groceries", Description: "Milk, eggs, bread"},
+	{Title: "Write quarterly report", Description: "Include the churn numbers"},
+	{Title: "Book dentist appointment"},
+	{Title: "Water the plants", Completed: true},
+	{Title: "Renew passport", Description: "Photos are in the top drawer"},
+	{Title: "Call the plumber"},
+	{Title: "Plan team offsite", Description: "Shortlist three venues"},
+	{Title: "Pay electricity bill", Completed: true},
+	{Title: "Read design review notes"},
+	{Title: "Back up laptop", Completed: true},
+}
+
+// secretField matches configuration fields whose values are not printed.
+// A database DSN may hold a PostgreSQL password.
+var secretField = regexp.MustCompile(`(?i)secret|token|password|key$|^dsn$`)
+
+func openDatabase(cfg *config.Config) (*database.DB, error) {
+	return database.NewConnection(&cfg.Database)
+}
+
+// requireSQLite refuses commands working on the SQLite file when the
+// database is PostgreSQL, which has its own tools.
+func requireSQLite(cfg *config.Config, name string) error {
+	if cfg.Database.Postgres() {
+		return fmt.Errorf("%s works on SQLite databases only; use PostgreSQL's tools, such as pg_dump, instead", name)
+	}
+	return nil
+}
+
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return usageError("migrate")
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dir := cfg.Database.MigrationsDir
+
+	switch args[0] {
+	case "up":
+		return db.Migrate(dir)
+
+	case "down":
+		fs := newFlagSet("migrate")
+		steps := fs.Int("n", 1, "number of migrations to revert")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return fmt.Errorf("-n must be at least 1")
+		}
+		return db.MigrateDown(dir, *steps)
+
+	case "status":
+		migrations, err := db.MigrationStatus(dir)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDOWN")
+		for _, migration := range migrations {
+			status, appliedAt := "pending", "-"
+			if migration.Applied {
+				status = "applied"
+				appliedAt = migration.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			if migration.Missing {
+				status = "applied, file missing"
+			}
+
+			down := "no"
+			if migration.Reversible {
+				down = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", migration.Version, status, appliedAt, down)
+		}
+		return w.Flush()
+
+	default:
+		return usageError("migrate")
+	}
+}
+
+// runSeed inserts sample todos through the todo service, so they are
+// validated and queue webhook deliveries like any other change.
+func runSeed(cfg *config.Config, args []string) error {
+	fs := newFlagSet("seed")
+	count := fs.Int("n", len(sampleTodos), "number of todos to insert")
+	email := fs.String("user", "", "email of the account that owns the todos (default: none)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Migrate(cfg.Database.MigrationsDir); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if *email != "" {
+		user, _, err := repositories.NewUserRepository(db).GetByEmail(*email)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("no account is registered with %s", *email)
+		}
+		if err != nil {
+			return err
+		}
+		ctx = auth.NewContext(ctx, &auth.Principal{UserID: user.ID, Name: "seed"})
+	}
+
+	broker := events.NewBroker(1, 1)
+	defer broker.Close()
+
+	// Seeding is done by operators, so it is not held to the daily quota.
+	webhookRepo := repositories.NewWebhookRepository(db)
+	service := services.NewTodoService(repositories.NewTodoRepository(db, webhookRepo.Enqueue), repositories.NewGrantRepository(db), broker, 0)
+
+	for i := 0; i < *count; i++ {
+		todo := sampleTodos[i%len(sampleTodos)]
+		if round := i / len(sampleTodos); round > 0 {
+			todo.Title = fmt.Sprintf("%s (%d)", todo.Title, round+1)
+		}
+
+		if err := service.Create(ctx, &todo); err != nil {
+			return fmt.Errorf("failed to create %q: %w", todo.Title, err)
+		}
+	}
+
+	fmt.Printf("Inserted %d todos\n", *count)
+	return nil
+}
+
+func runBackup(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return usageError("backup")
+	}
+	if err := requireSQLite(cfg, "backup"); err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Backup(args[0]); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %s to %s (%d bytes)\n", cfg.Database.Path, args[0], info.Size())
+	return nil
+}
+
+func runRestore(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return usageError("restore")
+	}
+	if err := requireSQLite(cfg, "restore"); err != nil {
+		return err
+	}
+
+	kept, err := database.Restore(args[0], cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %s from %s\n", cfg.Database.Path, args[0])
+	if kept != "" {
+		fmt.Printf("The previous database was kept as %s\n", kept)
+	}
+	return nil
+}
+
+func runVacuum(cfg *config.Config, args []string) error {
+	if len(args) != 0 {
+		return usageError("vacuum")
+	}
+	if err := requireSQLite(cfg, "vacuum"); err != nil {
+		return err
+	}
+
+	before, err := fileSize(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Vacuum(); err != nil {
+		return err
+	}
+
+	after, err := fileSize(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Vacuumed %s: %d bytes -> %d bytes\n", cfg.Database.Path, before, after)
+	return nil
+}
+
+func runCheck(cfg *config.Config, args []string) error {
+	if len(args) != 0 {
+		return usageError("check")
+	}
+	if err := requireSQLite(cfg, "check"); err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	problems, err := db.IntegrityCheck()
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems in %s", len(problems), cfg.Database.Path)
+	}
+
+	fmt.Println("ok")
+	return nil
+}
+
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return usageError("config")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printConfig(w, "", reflect.ValueOf(*cfg))
+	return w.Flush()
+}
+
+// printConfig writes one line per setting, named by its path through the
+// config structs, with secrets masked.
+func printConfig(w *tabwriter.Writer, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if value.Kind() == reflect.Struct && value.Type().PkgPath() == t.PkgPath() {
+			printConfig(w, prefix+strings.ToLower(field.Name)+".", value)
+			continue
+		}
+		name := prefix + snakeCase(field.Name)
+
+		text := fmt.Sprint(value.Interface())
+		if value.Kind() == reflect.Slice {
+			items := make([]string, value.Len())
+			for j := range items {
+				items[j] = fmt.Sprint(value.Index(j).Interface())
+			}
+			text = strings.Join(items, ",")
+		}
+		if secretField.MatchString(field.Name) && text != "" {
+			text = "********"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", name, text)
+	}
+}
+
+// snakeCase turns a Go field name such as GCInterval into gc_interval.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		upper := unicode.IsUpper(r)
+		if upper && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
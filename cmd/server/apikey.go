@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-api/internal/config"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// runAPIKey manages API keys directly in the database, which is how the
+// first admin key is created once authentication is enabled.
+func runAPIKey(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return usageError("apikey")
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Migrate(cfg.Database.MigrationsDir); err != nil {
+		return err
+	}
+
+	service := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db))
+
+	switch args[0] {
+	case "create":
+		fs := newFlagSet("apikey")
+		name := fs.String("name", "", "name describing the key's holder")
+		scopes := fs.String("scopes", models.ScopeAdmin, "comma-separated scopes: todos:read, todos:write, admin")
+		expires := fs.Duration("expires", 0, "lifetime of the key, such as 720h (default: never expires)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		key := models.APIKey{Name: *name, Scopes: strings.Split(*scopes, ",")}
+		if *expires > 0 {
+			expiresAt := time.Now().Add(*expires)
+			key.ExpiresAt = &expiresAt
+		}
+		if err := service.Create(&key); err != nil {
+			return err
+		}
+
+		fmt.Printf("Created API key %d (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+		fmt.Println("Store the key now; it cannot be shown again:")
+		fmt.Println(key.Secret)
+		return nil
+
+	case "ls":
+		keys, err := service.GetAll()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tUSER\tEXPIRES\tLAST USED")
+		for _, key := range keys {
+			user := "-"
+			if key.UserID != nil {
+				user = strconv.FormatInt(*key.UserID, 10)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix,
+				strings.Join(key.Scopes, ","), user, formatOptionalTime(key.ExpiresAt), formatOptionalTime(key.LastUsedAt))
+		}
+		return w.Flush()
+
+	case "rm":
+		if len(args) != 2 {
+			return usageError("apikey")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid api key id %q", args[1])
+		}
+		if err := service.Delete(id); err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked API key %d\n", id)
+		return nil
+
+	default:
+		return usageError("apikey")
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -5,35 +5,106 @@
 // @host            localhost:8082
 // @BasePath        /api/v1
 // @schemes         http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description API key or OpenID Connect JWT sent as "Bearer <token>". GET requests may pass it as the access_token query parameter instead.
 package main
 
 import (
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"sort"
+	"strings"
 
 	_ "todo-api/docs"
-	"todo-api/internal/server"
+	"todo-api/internal/config"
+	"todo-api/internal/logging"
 )
 
+// command is a subcommand of the server binary.
+type command struct {
+	usage   string
+	summary string
+	run     func(cfg *config.Config, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"serve":   {"serve [-addr :8082]", "Run the HTTP and gRPC servers (the default)", runServe},
+		"migrate": {"migrate up | down [-n steps] | status", "Apply, revert or list database migrations", runMigrate},
+		"seed":    {"seed [-n count] [-user email]", "Insert sample todos", runSeed},
+		"backup":  {"backup <file>", "Write a consistent copy of the database, also while serving", runBackup},
+		"restore": {"restore <file>", "Replace the database with a backup; stop the server first", runRestore},
+		"vacuum":  {"vacuum", "Rebuild the database file to reclaim free space", runVacuum},
+		"check":   {"check", "Run SQLite's integrity and foreign key checks", runCheck},
+		"config":  {"config print", "Print the effective configuration", runConfig},
+		"apikey":  {"apikey create -name <name> -scopes <scope,...> [-expires 720h] | ls | rm <id>", "Manage API keys, such as the first admin key", runAPIKey},
+	}
+}
+
 func main() {
-	log.Println("TODO API starting...")
-	srv, err := server.NewServer()
-	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
-	}
-	defer srv.Close()
-		
-	go func() {
-		if err := srv.Start(":8082"); err != nil {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+	args := os.Args[1:]
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	
+	if name == "help" {
+		printUsage()
+		return
+	}
 	
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+	
+	cfg := config.NewConfig()
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	
-	log.Println("Shutting down server...")
+	if err := cmd.run(cfg, args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		slog.Error(name+" failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	fmt.Fprintln(os.Stderr, "Usage: server [command] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+}
+
+// newFlagSet returns a flag set printing the command's usage on errors.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: server %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func usageError(name string) error {
+	return fmt.Errorf("usage: server %s", commands[name].usage)
 }
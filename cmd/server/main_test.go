@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"todo-api/internal/config"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// newConfig returns the configuration of a server on a fresh SQLite
+// database with the repository's migrations.
+func newConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	t.Setenv("TODO_DB_PATH", filepath.Join(t.TempDir(), "todos.db"))
+	t.Setenv("TODO_DATABASE_URL", "")
+	t.Setenv("TODO_MIGRATIONS_DIR", filepath.Join("..", "..", "migrations"))
+	return config.NewConfig()
+}
+
+// runCommand runs the subcommand name and returns what it printed.
+func runCommand(t *testing.T, cfg *config.Config, name string, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+
+	err = commands[name].run(cfg, args)
+	w.Close()
+	return <-output, err
+}
+
+func TestAPIKey(t *testing.T) {
+	cfg := newConfig(t)
+
+	out, err := runCommand(t, cfg, "apikey", "create", "-name", "ci", "-scopes", "todos:read,todos:write", "-expires", "720h")
+	if err != nil {
+		t.Fatalf("apikey create: %v", err)
+	}
+	if !strings.HasPrefix(out, "Created API key 1 (ci) with scopes todos:read,todos:write\n") {
+		t.Errorf("apikey create printed %q", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	secret := lines[len(lines)-1]
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		t.Fatalf("openDatabase: %v", err)
+	}
+	defer db.Close()
+	keys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db))
+
+	key, err := keys.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate with the printed secret: %v", err)
+	}
+	if key.ExpiresAt == nil {
+		t.Error("key created with -expires never expires")
+	}
+
+	out, err = runCommand(t, cfg, "apikey", "ls")
+	if err != nil {
+		t.Fatalf("apikey ls: %v", err)
+	}
+	if !strings.Contains(out, "ID  NAME  PREFIX") || !strings.Contains(out, fmt.Sprintf("1   ci    %s  todos:read,todos:write", key.Prefix)) {
+		t.Errorf("apikey ls printed:\n%s", out)
+	}
+
+	out, err = runCommand(t, cfg, "apikey", "rm", "1")
+	if err != nil || out != "Revoked API key 1\n" {
+		t.Fatalf("apikey rm printed %q (%v)", out, err)
+	}
+	if _, err := keys.Authenticate(secret); !errors.Is(err, services.ErrUnauthorized) {
+		t.Errorf("Authenticate with a revoked key: %v, want ErrUnauthorized", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want error
+	}{
+		{"unknown scope", []string{"create", "-name", "ci", "-scopes", "todos:delete"}, services.ErrInvalidInput},
+		{"missing name", []string{"create"}, services.ErrInvalidInput},
+		{"revoking a revoked key", []string{"rm", "1"}, repositories.ErrNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := runCommand(t, cfg, "apikey", tc.args...); !errors.Is(err, tc.want) {
+				t.Errorf("apikey %s: %v, want %v", strings.Join(tc.args, " "), err, tc.want)
+			}
+		})
+	}
+
+	for _, args := range [][]string{nil, {"rm"}, {"rm", "one"}, {"revoke", "1"}} {
+		if _, err := runCommand(t, cfg, "apikey", args...); err == nil {
+			t.Errorf("apikey %s succeeded", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	cfg := newConfig(t)
+
+	files, err := filepath.Glob(filepath.Join(cfg.Database.MigrationsDir, "*.down.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations in %s (%v)", cfg.Database.MigrationsDir, err)
+	}
+	last := strings.TrimSuffix(filepath.Base(files[len(files)-1]), ".down.sql")
+
+	// status counts each migration as applied or pending.
+	status := func() (applied, pending int) {
+		t.Helper()
+		out, err := runCommand(t, cfg, "migrate", "status")
+		if err != nil {
+			t.Fatalf("migrate status: %v", err)
+		}
+		applied = strings.Count(out, " applied ")
+		pending = strings.Count(out, " pending ")
+		if applied+pending != len(files) {
+			t.Fatalf("migrate status lists %d migrations, want %d:\n%s", applied+pending, len(files), out)
+		}
+		return applied, pending
+	}
+
+	if applied, _ := status(); applied != 0 {
+		t.Fatalf("%d migrations applied to a new database", applied)
+	}
+
+	if _, err := runCommand(t, cfg, "migrate", "up"); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if _, pending := status(); pending != 0 {
+		t.Fatalf("%d migrations pending after migrate up", pending)
+	}
+	if _, err := runCommand(t, cfg, "migrate", "up"); err != nil {
+		t.Fatalf("repeated migrate up: %v", err)
+	}
+
+	if _, err := runCommand(t, cfg, "migrate", "down", "-n", "2"); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	if _, pending := status(); pending != 2 {
+		t.Fatalf("%d migrations pending after reverting 2", pending)
+	}
+	out, _ := runCommand(t, cfg, "migrate", "status")
+	if !strings.Contains(out, last+"  pending") {
+		t.Errorf("%s is not pending after migrate down:\n%s", last, out)
+	}
+
+	if _, err := runCommand(t, cfg, "migrate", "up"); err != nil {
+		t.Fatalf("migrate up after down: %v", err)
+	}
+	if applied, _ := status(); applied != len(files) {
+		t.Fatalf("%d migrations applied after migrate up, want %d", applied, len(files))
+	}
+
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "-n", "0"}} {
+		if _, err := runCommand(t, cfg, "migrate", args...); err == nil {
+			t.Errorf("migrate %s succeeded", strings.Join(args, " "))
+		}
+	}
+}
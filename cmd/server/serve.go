@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"todo-api/internal/config"
+	"todo-api/internal/server"
+)
+
+func runServe(_ *config.Config, args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", ":8082", "HTTP listen address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	
+	slog.Info("TODO API starting...")
+	srv, err := server.NewServer()
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	defer srv.Close()
+		
+	go func() {
+		if err := srv.Start(*addr); err != nil {
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
+		}
+	}()
+	
+	// SIGHUP reloads the TLS certificate, for renewals written in place.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := srv.ReloadTLS(); err != nil {
+				slog.Error("Failed to reload TLS certificate", "error", err)
+			}
+		}
+	}()
+	
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	signal.Stop(hup)
+	
+	slog.Info("Shutting down server...")
+	srv.Shutdown()
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/pkg/client"
+)
+
+func runAdd(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "add")
+	description := fs.String("d", "", "description")
+	done := fs.Bool("done", false, "create the todo as completed")
+	output := fs.String("o", "text", "output format: text or json")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("usage: todo %s", commands["add"].usage)
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	todo, err := c.CreateTodo(ctx, &models.Todo{
+		Title:       strings.Join(args, " "),
+		Description: *description,
+		Completed:   *done,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return writeJSON(a.stdout, todo)
+	}
+	fmt.Fprintf(a.stdout, "Created todo %d: %s\n", todo.ID, todo.Title)
+	return nil
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "ls")
+	open := fs.Bool("open", false, "only list open todos")
+	done := fs.Bool("done", false, "only list completed todos")
+	query := fs.String("q", "", "only list todos whose title or description contains this text")
+	limit := fs.Int("n", 0, "list at most this many todos (0 lists all)")
+	output := fs.String("o", "table", "output format: table or json")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usagef("ls takes no arguments")
+	}
+	if *open && *done {
+		return usagef("--open and --done cannot be combined")
+	}
+	if *limit < 0 {
+		return usagef("-n must not be negative")
+	}
+	if *output != "table" && *output != "json" {
+		return usagef("unknown output format %q", *output)
+	}
+
+	opts := client.ListTodosOptions{Query: *query}
+	if *open || *done {
+		opts.Completed = done
+	}
+	if *limit > 0 && *limit <= 100 {
+		opts.Limit = *limit
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	todos := []models.Todo{}
+	for todo, err := range c.Todos(ctx, opts) {
+		if err != nil {
+			return err
+		}
+		todos = append(todos, todo)
+		if len(todos) == *limit {
+			break
+		}
+	}
+
+	if *output == "json" {
+		return writeJSON(a.stdout, todos)
+	}
+	writeTable(a.stdout, todos)
+	return nil
+}
+
+func runShow(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "show")
+	output := fs.String("o", "text", "output format: text or json")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usagef("usage: todo %s", commands["show"].usage)
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	todo, err := c.GetTodo(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return writeJSON(a.stdout, todo)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", todo.ID)
+	fmt.Fprintf(w, "Title:\t%s\n", todo.Title)
+	fmt.Fprintf(w, "Status:\t%s\n", status(todo.Completed))
+	fmt.Fprintf(w, "Comments:\t%d\n", todo.CommentCount)
+	fmt.Fprintf(w, "Created:\t%s\n", formatTime(todo.CreatedAt))
+	fmt.Fprintf(w, "Updated:\t%s\n", formatTime(todo.UpdatedAt))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if todo.Description != "" {
+		fmt.Fprintf(a.stdout, "\n%s\n", todo.Description)
+	}
+	return nil
+}
+
+func runDone(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "done")
+	undo := fs.Bool("undo", false, "mark the todos as open again")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args, "done")
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		todo, err := c.GetTodo(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		todo.Completed = !*undo
+		if _, err := c.UpdateTodo(ctx, todo); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Marked todo %d as %s: %s\n", todo.ID, status(todo.Completed), todo.Title)
+	}
+	return nil
+}
+
+func runRemove(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "rm")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args, "rm")
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := c.DeleteTodo(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Deleted todo %d\n", id)
+	}
+	return nil
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, usagef("invalid todo id %q", arg)
+	}
+	return id, nil
+}
+
+func parseIDs(args []string, name string) ([]int64, error) {
+	if len(args) == 0 {
+		return nil, usagef("usage: todo %s", commands[name].usage)
+	}
+
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeTable(w io.Writer, todos []models.Todo) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDONE\tTITLE\tUPDATED")
+	for _, todo := range todos {
+		done := " "
+		if todo.Completed {
+			done = "x"
+		}
+		fmt.Fprintf(tw, "%d\t[%s]\t%s\t%s\n", todo.ID, done, todo.Title, formatTime(todo.UpdatedAt))
+	}
+	tw.Flush()
+}
+
+func status(completed bool) string {
+	if completed {
+		return "done"
+	}
+	return "open"
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/pkg/client"
+)
+
+// completeCommand is the hidden command the completion scripts call with
+// the words typed so far. It prints one candidate per line, optionally
+// followed by a tab and a description.
+const completeCommand = "__complete"
+
+const bashCompletion = `# bash completion for todo; load with: source <(todo completion bash)
+_todo() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local candidates
+    candidates=$(todo __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1)
+    COMPREPLY=($(compgen -W "$candidates" -- "$cur"))
+}
+complete -o default -F _todo todo
+`
+
+const zshCompletion = `#compdef todo
+# zsh completion for todo; load with: source <(todo completion zsh)
+_todo() {
+    local -a candidates
+    local line
+    for line in "${(@f)$(todo __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -n $line ]] && candidates+=("${line/$'\t'/:}")
+    done
+    _describe 'todo' candidates
+}
+compdef _todo todo
+`
+
+const fishCompletion = `# fish completion for todo; load with: todo completion fish | source
+complete -c todo -f -a '(todo __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`
+
+// globalValueFlags are the global flags that take a value.
+var globalValueFlags = []string{"--config", "--profile", "--server", "--token"}
+
+// flagValues lists the values completed after a flag; flags missing here
+// take free-form values.
+var flagValues = map[string][]string{
+	"-o":       {"table", "text", "json"},
+	"--format": {"json", "csv"},
+}
+
+// valueFlags are the command flags that take a value.
+var valueFlags = map[string]bool{
+	"-d": true, "-t": true, "-q": true, "-n": true, "-o": true, "-f": true, "--format": true,
+}
+
+func runCompletion(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return usagef("usage: todo %s", commands["completion"].usage)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Fprint(a.stdout, bashCompletion)
+	case "zsh":
+		fmt.Fprint(a.stdout, zshCompletion)
+	case "fish":
+		fmt.Fprint(a.stdout, fishCompletion)
+	default:
+		return usagef("unsupported shell %q; use bash, zsh or fish", args[0])
+	}
+	return nil
+}
+
+// runComplete prints completions for the last of words, the word being
+// typed.
+func runComplete(ctx context.Context, a *app, words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, before := words[len(words)-1], words[:len(words)-1]
+
+	for _, candidate := range complete(ctx, a, before, current) {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Fprintln(a.stdout, candidate)
+		}
+	}
+	return nil
+}
+
+func complete(ctx context.Context, a *app, before []string, current string) []string {
+	// Skip the global flags to find the command.
+	i := 0
+	for i < len(before) && strings.HasPrefix(before[i], "-") {
+		if contains(globalValueFlags, normalizeFlag(before[i])) && !strings.Contains(before[i], "=") {
+			i++
+		}
+		i++
+	}
+
+	if i >= len(before) {
+		if len(before) > 0 && normalizeFlag(before[len(before)-1]) == "--profile" {
+			return profileNames(a)
+		}
+		if strings.HasPrefix(current, "-") {
+			return globalValueFlags
+		}
+		return append(commandNames(), "help")
+	}
+
+	name, args := before[i], before[i+1:]
+	cmd, ok := commands[name]
+	if !ok {
+		return nil
+	}
+
+	if len(args) > 0 {
+		if previous := normalizeFlag(args[len(args)-1]); valueFlags[previous] {
+			return flagValues[previous]
+		}
+	}
+	if strings.HasPrefix(current, "-") {
+		return cmd.flags
+	}
+
+	positional := 0
+	for j := 0; j < len(args); j++ {
+		switch {
+		case valueFlags[normalizeFlag(args[j])]:
+			j++
+		case !strings.HasPrefix(args[j], "-"):
+			positional++
+		}
+	}
+
+	switch name {
+	case "config":
+		switch {
+		case positional == 0:
+			return []string{"show", "ls", "set", "use"}
+		case positional == 1 && args[len(args)-1] == "set":
+			return []string{"server", "token"}
+		case positional == 1 && args[len(args)-1] == "use":
+			return profileNames(a)
+		}
+	case "completion":
+		if positional == 0 {
+			return []string{"bash", "zsh", "fish"}
+		}
+	}
+
+	if cmd.completesIDs {
+		return todoIDs(ctx, a)
+	}
+	return nil
+}
+
+// todoIDs returns the IDs of the newest todos with their titles, or nothing
+// when the server cannot be reached quickly.
+func todoIDs(ctx context.Context, a *app) []string {
+	c, err := a.client()
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	page, err := c.ListTodos(ctx, client.ListTodosOptions{Limit: 100})
+	if err != nil {
+		return nil
+	}
+
+	ids := make([]string, len(page.Items))
+	for i, todo := range page.Items {
+		ids[i] = strconv.FormatInt(todo.ID, 10) + "\t" + todo.Title
+	}
+	return ids
+}
+
+func profileNames(a *app) []string {
+	names := make([]string, 0, len(a.config.Profiles))
+	for name := range a.config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeFlag returns flag without a value attached with "=", and with
+// the long form of global flags given with a single dash.
+func normalizeFlag(flag string) string {
+	flag, _, _ = strings.Cut(flag, "=")
+	if strings.HasPrefix(flag, "-") && !strings.HasPrefix(flag, "--") && len(flag) > 2 {
+		return "-" + flag
+	}
+	return flag
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultProfile = "default"
+
+// config is the CLI config file: named profiles and the one in use.
+type config struct {
+	Current  string             `json:"current"`
+	Profiles map[string]profile `json:"profiles"`
+}
+
+// profile is a server to talk to and the token to send it.
+type profile struct {
+	Server string `json:"server,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// resolveConfigPath returns path, or the default location under the user
+// config directory.
+func resolveConfigPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "todo", "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file yields an empty default
+// profile.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{Current: defaultProfile, Profiles: map[string]profile{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if cfg.Current == "" {
+		cfg.Current = defaultProfile
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+
+	return cfg, nil
+}
+
+// save writes the config readable by the current user only, as it holds
+// tokens.
+func (c *config) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func runConfig(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: todo %s", commands["config"].usage)
+	}
+
+	switch args[0] {
+	case "show":
+		fmt.Fprintf(a.stdout, "profile: %s\n", a.profileName)
+		fmt.Fprintf(a.stdout, "server:  %s\n", a.profile.Server)
+		fmt.Fprintf(a.stdout, "token:   %s\n", maskToken(a.profile.Token))
+		fmt.Fprintf(a.stdout, "file:    %s\n", a.configPath)
+		return nil
+
+	case "ls":
+		names := make([]string, 0, len(a.config.Profiles))
+		for name := range a.config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			marker := " "
+			if name == a.config.Current {
+				marker = "*"
+			}
+			fmt.Fprintf(a.stdout, "%s %-12s %s\n", marker, name, a.config.Profiles[name].Server)
+		}
+		return nil
+
+	case "set":
+		if len(args) != 3 {
+			return usagef("usage: todo config set server|token <value>")
+		}
+
+		// Only the stored profile is changed, not --server or --token overrides.
+		p := a.config.Profiles[a.profileName]
+		switch args[1] {
+		case "server":
+			p.Server = strings.TrimRight(args[2], "/")
+		case "token":
+			p.Token = args[2]
+		default:
+			return usagef("unknown config key %q; use server or token", args[1])
+		}
+		a.config.Profiles[a.profileName] = p
+
+		if err := a.config.save(a.configPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Updated %s of profile %s\n", args[1], a.profileName)
+		return nil
+
+	case "use":
+		if len(args) != 2 {
+			return usagef("usage: todo config use <profile>")
+		}
+		if _, ok := a.config.Profiles[args[1]]; !ok {
+			return fmt.Errorf("profile %q does not exist; create it with: todo --profile %s config set server <url>", args[1], args[1])
+		}
+
+		a.config.Current = args[1]
+		if err := a.config.save(a.configPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Using profile %s\n", args[1])
+		return nil
+
+	default:
+		return usagef("unknown config command %q", args[0])
+	}
+}
+
+func maskToken(token string) string {
+	switch {
+	case token == "":
+		return "(none)"
+	case len(token) <= 8:
+		return "********"
+	default:
+		return token[:4] + "…" + token[len(token)-4:]
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"todo-api/internal/models"
+)
+
+const editHelp = `
+# Edit the title on the first line and the description below it.
+# Lines starting with '#' are ignored; an empty title aborts the edit.
+`
+
+func runEdit(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "edit")
+	title := fs.String("t", "", "set the title without opening an editor")
+	description := fs.String("d", "", "set the description without opening an editor")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usagef("usage: todo %s", commands["edit"].usage)
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	todo, err := c.GetTodo(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	edited := *todo
+	if flagSet(fs, "t") || flagSet(fs, "d") {
+		if flagSet(fs, "t") {
+			edited.Title = *title
+		}
+		if flagSet(fs, "d") {
+			edited.Description = *description
+		}
+	} else {
+		if edited.Title, edited.Description, err = editInEditor(ctx, todo); err != nil {
+			return err
+		}
+	}
+
+	if edited.Title == todo.Title && edited.Description == todo.Description {
+		fmt.Fprintln(a.stdout, "No changes")
+		return nil
+	}
+
+	updated, err := c.UpdateTodo(ctx, &edited)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Updated todo %d: %s\n", updated.ID, updated.Title)
+	return nil
+}
+
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// editInEditor opens the todo's title and description in the user's editor
+// and returns them as saved.
+func editInEditor(ctx context.Context, todo *models.Todo) (string, string, error) {
+	file, err := os.CreateTemp("", fmt.Sprintf("todo-%d-*.txt", todo.ID))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	content := todo.Title + "\n\n"
+	if todo.Description != "" {
+		content += todo.Description + "\n"
+	}
+	content += editHelp
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor := editorCommand()
+	cmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$@"`, editor, file.Name())
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, editor, file.Name())
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+
+	title, description := parseEdited(string(data))
+	if title == "" {
+		return "", "", fmt.Errorf("aborting edit due to empty title")
+	}
+	return title, description, nil
+}
+
+// editorCommand returns $VISUAL or $EDITOR, or vi. Like git, the command
+// is run by the shell so it may carry arguments.
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// parseEdited splits an edited file into the title on its first non-empty
+// line and the description after it, ignoring comment lines.
+func parseEdited(content string) (string, string) {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(lines) == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return "", ""
+	}
+	return strings.TrimSpace(lines[0]), strings.TrimSpace(strings.Join(lines[1:], "\n"))
+}
@@ -0,0 +1,281 @@
+// Command todo manages todos from the terminal through the HTTP API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"todo-api/pkg/client"
+)
+
+// command is a subcommand. run receives the arguments after the command name.
+type command struct {
+	usage   string
+	summary string
+	flags   []string
+	// completesIDs is set when the command's arguments are todo IDs.
+	completesIDs bool
+	run          func(ctx context.Context, app *app, args []string) error
+}
+
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"add": {
+			usage:   "add [-d description] [--done] <title>",
+			summary: "Create a todo",
+			flags:   []string{"-d", "--done", "-o"},
+			run:     runAdd,
+		},
+		"ls": {
+			usage:   "ls [--open | --done] [-q query] [-n limit] [-o table|json]",
+			summary: "List todos, newest first",
+			flags:   []string{"--open", "--done", "-q", "-n", "-o"},
+			run:     runList,
+		},
+		"show": {
+			usage:        "show [-o text|json] <id>",
+			summary:      "Show a todo",
+			flags:        []string{"-o"},
+			completesIDs: true,
+			run:          runShow,
+		},
+		"edit": {
+			usage:        "edit [-t title] [-d description] <id>",
+			summary:      "Edit a todo in $EDITOR, or set its fields with flags",
+			flags:        []string{"-t", "-d"},
+			completesIDs: true,
+			run:          runEdit,
+		},
+		"done": {
+			usage:        "done [--undo] <id>...",
+			summary:      "Mark todos as completed",
+			flags:        []string{"--undo"},
+			completesIDs: true,
+			run:          runDone,
+		},
+		"rm": {
+			usage:        "rm <id>...",
+			summary:      "Delete todos",
+			completesIDs: true,
+			run:          runRemove,
+		},
+		"import": {
+			usage:   "import [--format json|csv] [file]",
+			summary: "Create todos from a JSON or CSV file, or stdin",
+			flags:   []string{"--format"},
+			run:     runImport,
+		},
+		"export": {
+			usage:   "export [--format json|csv] [-f file]",
+			summary: "Write every todo as JSON or CSV",
+			flags:   []string{"--format", "-f"},
+			run:     runExport,
+		},
+		"config": {
+			usage:   "config show | ls | set server|token <value> | use <profile>",
+			summary: "Manage server profiles",
+			run:     runConfig,
+		},
+		"completion": {
+			usage:   "completion bash|zsh|fish",
+			summary: "Print a shell completion script",
+			run:     runCompletion,
+		},
+	}
+}
+
+// app holds what commands share: the selected profile and the output streams.
+type app struct {
+	configPath  string
+	config      *config
+	profileName string
+	profile     profile
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+// client returns an API client for the selected profile.
+func (a *app) client() (*client.Client, error) {
+	if a.profile.Server == "" {
+		return nil, fmt.Errorf("no server configured for profile %q; run: todo config set server <url>", a.profileName)
+	}
+
+	var opts []client.Option
+	opts = append(opts, client.WithUserAgent("todo-cli"))
+	if a.profile.Token != "" {
+		opts = append(opts, client.WithToken(a.profile.Token))
+	}
+
+	return client.New(a.profile.Server, opts...)
+}
+
+// usageError is returned for invalid invocations and exits with status 2.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err == nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "todo: %v\n", err)
+	os.Exit(exitCode(err))
+}
+
+// exitCode is the status to exit with after err: 0 on success, 2 for
+// invalid invocations and 1 for anything else, such as an API error.
+func exitCode(err error) int {
+	var usage *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage) || errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		return 1
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	global := flag.NewFlagSet("todo", flag.ContinueOnError)
+	global.SetOutput(io.Discard)
+	configPath := global.String("config", os.Getenv("TODO_CONFIG"), "path of the config file")
+	profileName := global.String("profile", os.Getenv("TODO_PROFILE"), "profile to use")
+	server := global.String("server", os.Getenv("TODO_SERVER"), "server URL, overriding the profile")
+	token := global.String("token", os.Getenv("TODO_TOKEN"), "API token, overriding the profile")
+
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printUsage(stdout)
+			return nil
+		}
+		return usagef("%v", err)
+	}
+	if global.NArg() == 0 {
+		printUsage(stderr)
+		return usagef("no command given")
+	}
+
+	name, rest := global.Arg(0), global.Args()[1:]
+
+	if name == "help" {
+		printUsage(stdout)
+		return nil
+	}
+
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	var err error
+	if a.configPath, err = resolveConfigPath(*configPath); err != nil {
+		return err
+	}
+	if a.config, err = loadConfig(a.configPath); err != nil {
+		return err
+	}
+
+	a.profileName = *profileName
+	if a.profileName == "" {
+		a.profileName = a.config.Current
+	}
+	a.profile = a.config.Profiles[a.profileName]
+	if *server != "" {
+		a.profile.Server = *server
+	}
+	if *token != "" {
+		a.profile.Token = *token
+	}
+
+	if name == completeCommand {
+		return runComplete(ctx, a, rest)
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		return usagef("unknown command %q; run 'todo help' for a list", name)
+	}
+
+	if err := cmd.run(ctx, a, rest); !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: todo [--profile name] [--server url] [--token token] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "  %-11s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'todo <command> -h' for the flags of a command.")
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newFlagSet returns a flag set for a command whose usage is printed on -h.
+func newFlagSet(a *app, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: todo %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, allowing flags to follow positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usagef("%v", err)
+		}
+
+		// Parse consumes a "--" terminator; everything after it is positional.
+		consumed := len(args) - fs.NArg()
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
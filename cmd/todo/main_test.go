@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/server"
+)
+
+// api is the real server on a fresh database with authentication disabled,
+// recording the todo listings it is asked for.
+type api struct {
+	url string
+
+	mu       sync.Mutex
+	listings []string
+}
+
+func startAPI(t *testing.T) *api {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("TODO_DB_PATH", filepath.Join(dir, "todos.db"))
+	t.Setenv("TODO_BLOB_DIR", filepath.Join(dir, "blobs"))
+	t.Setenv("TODO_MIGRATIONS_DIR", filepath.Join("..", "..", "migrations"))
+	t.Setenv("TODO_AUTH_ENABLED", "false")
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	a := &api{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api/v1/todos" {
+			a.mu.Lock()
+			a.listings = append(a.listings, r.URL.RawQuery)
+			a.mu.Unlock()
+		}
+		srv.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	a.url = ts.URL
+	return a
+}
+
+// lastListing returns the query string of the last todo listing.
+func (a *api) lastListing() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.listings) == 0 {
+		return ""
+	}
+	return a.listings[len(a.listings)-1]
+}
+
+// runCLI runs the command line against the server at url, with a config
+// file of its own, and returns its output and exit code.
+func runCLI(t *testing.T, url string, args ...string) (stdout, stderr string, code int, err error) {
+	t.Helper()
+
+	for _, name := range []string{"TODO_CONFIG", "TODO_PROFILE", "TODO_SERVER", "TODO_TOKEN"} {
+		t.Setenv(name, "")
+	}
+
+	global := []string{"--config", filepath.Join(t.TempDir(), "config.json")}
+	if url != "" {
+		global = append(global, "--server", url)
+	}
+
+	var out, errOut bytes.Buffer
+	err = run(context.Background(), append(global, args...), strings.NewReader(""), &out, &errOut)
+	return out.String(), errOut.String(), exitCode(err), err
+}
+
+func TestCommands(t *testing.T) {
+	a := startAPI(t)
+
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		want    []string
+		listing string
+	}{
+		{
+			name: "add",
+			args: []string{"add", "-d", "Milk and eggs", "Buy", "groceries"},
+			want: []string{"Created todo 1: Buy groceries\n"},
+		},
+		{
+			name: "add with flags after the title",
+			args: []string{"add", "Pay rent", "--done"},
+			want: []string{"Created todo 2: Pay rent\n"},
+		},
+		{
+			name:    "ls",
+			args:    []string{"ls"},
+			want:    []string{"ID  DONE  TITLE          UPDATED", "1   [ ]   Buy groceries", "2   [x]   Pay rent"},
+			listing: "limit=100",
+		},
+		{
+			name:    "ls open todos matching a query",
+			args:    []string{"ls", "--open", "-q", "groceries", "-n", "5"},
+			want:    []string{"1   [ ]   Buy groceries"},
+			listing: "completed=false&limit=5&q=groceries",
+		},
+		{
+			name:    "ls completed todos",
+			args:    []string{"ls", "--done"},
+			want:    []string{"2   [x]   Pay rent"},
+			listing: "completed=true&limit=100",
+		},
+		{
+			name: "show",
+			args: []string{"show", "1"},
+			want: []string{"ID:       1\n", "Title:    Buy groceries\n", "Status:   open\n", "Comments: 0\n", "\nMilk and eggs\n"},
+		},
+		{
+			name: "done",
+			args: []string{"done", "1"},
+			want: []string{"Marked todo 1 as done: Buy groceries\n"},
+		},
+		{
+			name: "done --undo",
+			args: []string{"done", "--undo", "1", "2"},
+			want: []string{"Marked todo 1 as open: Buy groceries\n", "Marked todo 2 as open: Pay rent\n"},
+		},
+		{
+			name: "rm",
+			args: []string{"rm", "2"},
+			want: []string{"Deleted todo 2\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr, code, err := runCLI(t, a.url, tc.args...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %v\n%s", code, err, stderr)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("output lacks %q:\n%s", want, stdout)
+				}
+			}
+			if tc.listing != "" && a.lastListing() != tc.listing {
+				t.Errorf("listed todos with %q, want %q", a.lastListing(), tc.listing)
+			}
+		})
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	a := startAPI(t)
+
+	stdout, _, code, err := runCLI(t, a.url, "add", "-o", "json", "--done", "Write report")
+	if code != 0 {
+		t.Fatalf("add: exit code %d: %v", code, err)
+	}
+	var created models.Todo
+	if err := json.Unmarshal([]byte(stdout), &created); err != nil || created.Title != "Write report" || !created.Completed {
+		t.Fatalf("add -o json printed %q (%v)", stdout, err)
+	}
+
+	stdout, _, code, err = runCLI(t, a.url, "ls", "-o", "json")
+	if code != 0 {
+		t.Fatalf("ls: exit code %d: %v", code, err)
+	}
+	var todos []models.Todo
+	if err := json.Unmarshal([]byte(stdout), &todos); err != nil || len(todos) != 1 || todos[0].ID != created.ID {
+		t.Fatalf("ls -o json printed %q (%v)", stdout, err)
+	}
+
+	stdout, _, _, _ = runCLI(t, a.url, "show", "-o", "json", "1")
+	var shown models.Todo
+	if err := json.Unmarshal([]byte(stdout), &shown); err != nil || shown.Title != "Write report" {
+		t.Fatalf("show -o json printed %q (%v)", stdout, err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	a := startAPI(t)
+
+	for _, tc := range []struct {
+		name   string
+		server string
+		args   []string
+		code   int
+		want   string
+	}{
+		{"help", a.url, []string{"help"}, 0, ""},
+		{"command help", a.url, []string{"ls", "-h"}, 0, ""},
+		{"no command", a.url, nil, 2, "no command given"},
+		{"unknown command", a.url, []string{"list"}, 2, `unknown command "list"`},
+		{"unknown flag", a.url, []string{"add", "--urgent", "Call mom"}, 2, "flag provided but not defined"},
+		{"missing title", a.url, []string{"add"}, 2, "usage: todo add"},
+		{"conflicting flags", a.url, []string{"ls", "--open", "--done"}, 2, "cannot be combined"},
+		{"unknown output format", a.url, []string{"ls", "-o", "yaml"}, 2, `unknown output format "yaml"`},
+		{"negative limit", a.url, []string{"ls", "-n", "-1"}, 2, "must not be negative"},
+		{"invalid id", a.url, []string{"show", "abc"}, 2, `invalid todo id "abc"`},
+		{"no server", "", []string{"ls"}, 1, "no server configured"},
+		{"todo not found", a.url, []string{"show", "999"}, 1, "status 404"},
+		{"deleting a missing todo", a.url, []string{"rm", "999"}, 1, "status 404"},
+		{"validation error", a.url, []string{"add", "ab"}, 1, "status 400"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, code, err := runCLI(t, tc.server, tc.args...)
+			if code != tc.code {
+				t.Errorf("exit code %d (%v), want %d", code, err, tc.code)
+			}
+			if tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+				t.Errorf("error %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
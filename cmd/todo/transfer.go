@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/pkg/client"
+)
+
+var csvHeader = []string{"id", "title", "description", "completed", "created_at", "updated_at"}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "export")
+	format := fs.String("format", "", "json or csv (default: from the file extension, else json)")
+	path := fs.String("f", "", "write to this file instead of stdout")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usagef("usage: todo %s", commands["export"].usage)
+	}
+
+	kind, err := transferFormat(*format, *path)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	todos := []models.Todo{}
+	for todo, err := range c.Todos(ctx, client.ListTodosOptions{}) {
+		if err != nil {
+			return err
+		}
+		todos = append(todos, todo)
+	}
+
+	w := a.stdout
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if kind == "csv" {
+		err = writeCSV(w, todos)
+	} else {
+		err = writeJSON(w, todos)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	if *path != "" {
+		fmt.Fprintf(a.stderr, "Exported %d todos to %s\n", len(todos), *path)
+	}
+	return nil
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "import")
+	format := fs.String("format", "", "json or csv (default: from the file extension, else json)")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return usagef("usage: todo %s", commands["import"].usage)
+	}
+
+	path := ""
+	r := a.stdin
+	if len(args) == 1 && args[0] != "-" {
+		path = args[0]
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	kind, err := transferFormat(*format, path)
+	if err != nil {
+		return err
+	}
+
+	var todos []models.Todo
+	if kind == "csv" {
+		todos, err = readCSV(r)
+	} else {
+		err = json.NewDecoder(r).Decode(&todos)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read import: %w", err)
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	// IDs and timestamps are assigned by the server; only the content is
+	// imported.
+	for i, todo := range todos {
+		created, err := c.CreateTodo(ctx, &models.Todo{
+			Title:       todo.Title,
+			Description: todo.Description,
+			Completed:   todo.Completed,
+		})
+		if err != nil {
+			return fmt.Errorf("todo %d of %d (%q): %w; %d imported", i+1, len(todos), todo.Title, err, i)
+		}
+		fmt.Fprintf(a.stderr, "Created todo %d: %s\n", created.ID, created.Title)
+	}
+
+	fmt.Fprintf(a.stdout, "Imported %d todos\n", len(todos))
+	return nil
+}
+
+// transferFormat picks the import or export format from the --format flag
+// or the file extension.
+func transferFormat(format, path string) (string, error) {
+	switch format {
+	case "json", "csv":
+		return format, nil
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			return "csv", nil
+		}
+		return "json", nil
+	default:
+		return "", usagef("unknown format %q; use json or csv", format)
+	}
+}
+
+func writeCSV(w io.Writer, todos []models.Todo) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, todo := range todos {
+		record := []string{
+			strconv.FormatInt(todo.ID, 10),
+			todo.Title,
+			todo.Description,
+			strconv.FormatBool(todo.Completed),
+			todo.CreatedAt.Format(time.RFC3339),
+			todo.UpdatedAt.Format(time.RFC3339),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV reads todos from CSV with a header row. Only the title column is
+// required; columns are matched by name.
+func readCSV(r io.Reader) ([]models.Todo, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("csv header has no title column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var todos []models.Todo
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return todos, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		todo := models.Todo{
+			Title:       field(record, "title"),
+			Description: field(record, "description"),
+		}
+		if completed := field(record, "completed"); completed != "" {
+			if todo.Completed, err = strconv.ParseBool(completed); err != nil {
+				line, _ := cr.FieldPos(columns["completed"])
+				return nil, fmt.Errorf("line %d: invalid completed value %q", line, completed)
+			}
+		}
+		todos = append(todos, todo)
+	}
+}
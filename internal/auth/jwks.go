@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minRefetchInterval limits how often a token naming an unknown key can
+// make the key set fetch its source again.
+const minRefetchInterval = 30 * time.Second
+
+// ErrUnknownKey is returned for a key ID that is not in the key set, even
+// after fetching it again.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the public keys of a JWKS document read from a URL or a
+// file. Keys are cached and fetched again after the refresh interval, or
+// sooner when a token names a key that is not in the set, which is how a
+// rotated key is picked up.
+type KeySet struct {
+	source  string
+	client  *http.Client
+	refresh time.Duration
+	now     func() time.Time
+
+	// fetches lets concurrent callers share one read of the source, which
+	// happens without holding mu, so the cached keys stay readable.
+	fetches singleflight.Group
+
+	mu      sync.RWMutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewKeySet returns a key set read from source, an http(s) URL or a file
+// path. Nothing is fetched until a key is first needed.
+func NewKeySet(source string, refresh time.Duration) *KeySet {
+	return &KeySet{
+		source:  source,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		refresh: refresh,
+		now:     time.Now,
+	}
+}
+
+// Key returns the key with ID kid. An empty kid matches the only key of a
+// set holding one key. Expired keys keep being served while they are
+// refreshed in the background.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	now := s.now()
+	keys, fetched := s.cached()
+
+	var refreshing <-chan singleflight.Result
+	switch {
+	case keys == nil:
+		if err := s.wait(ctx, s.fetch(ctx, now)); err != nil {
+			return nil, err
+		}
+		keys, fetched = s.cached()
+	case now.Sub(fetched) >= s.refresh:
+		refreshing = s.fetch(ctx, now)
+	}
+
+	if key, ok := lookup(keys, kid); ok {
+		return key, nil
+	}
+
+	// A key missing from a set being refreshed may be in the new one.
+	if refreshing == nil && now.Sub(fetched) >= minRefetchInterval {
+		refreshing = s.fetch(ctx, now)
+	}
+	if refreshing != nil {
+		if err := s.wait(ctx, refreshing); err != nil {
+			return nil, err
+		}
+		keys, _ = s.cached()
+		if key, ok := lookup(keys, kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+func (s *KeySet) cached() (map[string]crypto.PublicKey, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys, s.fetched
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// fetch starts reading the source, unless a read is already under way, and
+// returns the channel its result is sent on. The read outlives ctx, since
+// other callers may be waiting for it.
+func (s *KeySet) fetch(ctx context.Context, now time.Time) <-chan singleflight.Result {
+	ctx = context.WithoutCancel(ctx)
+	return s.fetches.DoChan(s.source, func() (any, error) {
+		return nil, s.load(ctx, now)
+	})
+}
+
+func (s *KeySet) wait(ctx context.Context, fetch <-chan singleflight.Result) error {
+	select {
+	case result := <-fetch:
+		return result.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// load reads the source and replaces the keys. A failed read keeps the
+// previous keys, so a JWKS endpoint outage does not lock everyone out, but
+// still counts as a fetch at now, so the source is not retried on every
+// request.
+func (s *KeySet) load(ctx context.Context, now time.Time) error {
+	keys, err := s.readKeys(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched = now
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+func (s *KeySet) readKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	data, err := s.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS from %s: %w", s.source, err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS from %s: %w", s.source, err)
+	}
+	return keys, nil
+}
+
+func (s *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// jwk is a JSON Web Key as in RFC 7517. Only the members of RSA and EC
+// public keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signing keys of a JWKS document by key ID. Keys of
+// other types or uses are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsaKey()
+		case "EC":
+			key, err = k.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA or EC signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent out of range")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
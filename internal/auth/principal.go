@@ -0,0 +1,110 @@
+// Package auth carries the authenticated caller of a request through its
+// context, so layers below the transport can make access decisions.
+package auth
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// APIKeyID is the key the caller authenticated with, or zero for a JWT.
+	APIKeyID int64
+	// UserID is the user the key belongs to, or zero for keys created by
+	// admins, which are not limited to one user's todos.
+	UserID int64
+	// Subject and Roles are the sub and roles claims of a JWT.
+	Subject string
+	Roles   []string
+	Name    string
+	Scopes  []string
+}
+
+// FromAPIKey returns the principal authenticated by key.
+func FromAPIKey(key *models.APIKey) *Principal {
+	p := &Principal{APIKeyID: key.ID, Name: key.Name, Scopes: key.Scopes}
+	if key.UserID != nil {
+		p.UserID = *key.UserID
+	}
+	return p
+}
+
+// HasScope reports whether the principal was granted scope. The admin scope
+// grants every scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || granted == models.ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx. There is none when
+// authentication is disabled.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Allowed reports whether the caller of ctx may use scope. Without a
+// principal authentication is disabled and everything is allowed.
+func Allowed(ctx context.Context, scope string) bool {
+	p, ok := FromContext(ctx)
+	return !ok || p.HasScope(scope)
+}
+
+// Owner returns the user whose todos the caller of ctx is limited to, or
+// zero when the caller is not tied to a user and sees every todo.
+func Owner(ctx context.Context) int64 {
+	if p, ok := FromContext(ctx); ok {
+		return p.UserID
+	}
+	return 0
+}
+
+// LimitKey names the rate limit bucket of the caller of ctx. Every key of a
+// user shares the user's bucket, so logging in again does not reset it. It
+// is empty for callers without a user or API key, who are limited by
+// address instead.
+func LimitKey(ctx context.Context) string {
+	if p, ok := FromContext(ctx); ok {
+		if p.UserID != 0 {
+			return "user:" + strconv.FormatInt(p.UserID, 10)
+		}
+		if p.APIKeyID != 0 {
+			return "key:" + strconv.FormatInt(p.APIKeyID, 10)
+		}
+	}
+	return ""
+}
+
+// CanSee reports whether the caller of ctx may see event: the caller owns
+// its todo, was granted a role on it or is not tied to a user.
+func CanSee(ctx context.Context, event events.Event) bool {
+	owner := Owner(ctx)
+	return owner == 0 || slices.Contains(event.Viewers(), owner)
+}
+
+// VisibleEvents narrows filter, which may be nil, to the events of todos the
+// caller of ctx may see.
+func VisibleEvents(ctx context.Context, filter events.Filter) events.Filter {
+	if Owner(ctx) == 0 {
+		return filter
+	}
+	return func(event events.Event) bool {
+		return CanSee(ctx, event) && (filter == nil || filter(event))
+	}
+}
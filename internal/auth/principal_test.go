@@ -0,0 +1,36 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+func TestCanSeeOwnedAndGrantedTodos(t *testing.T) {
+	owner := int64(1)
+	event := events.New(events.TodoUpdated, &models.Todo{ID: 7, Title: "Shared todo", OwnerID: &owner})
+	event.Grantees = []int64{2}
+
+	for _, tc := range []struct {
+		name      string
+		principal *auth.Principal
+		want      bool
+	}{
+		{"the owner", &auth.Principal{UserID: 1}, true},
+		{"a grantee", &auth.Principal{UserID: 2}, true},
+		{"another user", &auth.Principal{UserID: 3}, false},
+		{"an admin key", &auth.Principal{APIKeyID: 4}, true},
+	} {
+		ctx := auth.NewContext(context.Background(), tc.principal)
+		if got := auth.CanSee(ctx, event); got != tc.want {
+			t.Errorf("CanSee for %s = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if !auth.CanSee(context.Background(), event) {
+		t.Error("CanSee without authentication = false, want true")
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is wrapped by every error of TokenVerifier.Verify.
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenOptions configures a TokenVerifier.
+type TokenOptions struct {
+	// Issuer and Audience must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// RolesClaim names the claim holding the roles, as a path through
+	// nested objects such as "realm_access.roles".
+	RolesClaim string
+	// Leeway is the clock skew tolerated on exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// Identity is what a verified token says about its holder.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Roles         []string
+	// Scopes are the OAuth scopes of the scope claim.
+	Scopes []string
+}
+
+// TokenVerifier validates JWTs signed with RS256 or ES256 by a key of a
+// KeySet, as issued by an OpenID Connect provider.
+type TokenVerifier struct {
+	keys    *KeySet
+	parser  *jwt.Parser
+	options TokenOptions
+}
+
+func NewTokenVerifier(keys *KeySet, options TokenOptions) *TokenVerifier {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(options.Issuer),
+		jwt.WithAudience(options.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(options.Leeway),
+	)
+	return &TokenVerifier{keys: keys, parser: parser, options: options}
+}
+
+// IsJWT reports whether token has the shape of a JWT rather than of an API
+// key.
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the signature, issuer, audience and lifetime of token and
+// returns the identity it carries.
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	identity := &Identity{Issuer: v.options.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Roles = stringList(claimAt(claims, v.options.RolesClaim))
+	identity.Scopes = stringList(claims["scope"])
+
+	return identity, nil
+}
+
+// claimAt follows a dotted path through nested claim objects.
+func claimAt(claims map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// stringList reads a claim holding either a JSON array of strings or a
+// space-separated string, as the scope claim does.
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,353 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "todo-api"
+)
+
+// signingKey is a locally generated key pair published in the test JWKS.
+type signingKey struct {
+	kid     string
+	private crypto.Signer
+	method  jwt.SigningMethod
+}
+
+func newRSAKey(t *testing.T, kid string) *signingKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return &signingKey{kid: kid, private: key, method: jwt.SigningMethodRS256}
+}
+
+func newECKey(t *testing.T, kid string) *signingKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return &signingKey{kid: kid, private: key, method: jwt.SigningMethodES256}
+}
+
+func (k *signingKey) jwk() map[string]string {
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+
+	switch pub := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "use": "sig", "n": encode(pub.N), "e": encode(big.NewInt(int64(pub.E)))}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": k.kid, "use": "sig", "crv": "P-256", "x": encode(pub.X), "y": encode(pub.Y)}
+	}
+	panic("unsupported key")
+}
+
+func (k *signingKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+// jwksServer serves the public halves of its keys, which tests can swap to
+// simulate a rotation.
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     []*signingKey
+	requests atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...*signingKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(jwksDocument(s.keys))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...*signingKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func jwksDocument(keys []*signingKey) map[string]any {
+	jwks := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		jwks[i] = key.jwk()
+	}
+	return map[string]any{"keys": jwks}
+}
+
+func newTestVerifier(source string) (*TokenVerifier, *KeySet) {
+	keys := NewKeySet(source, time.Hour)
+	return NewTokenVerifier(keys, TokenOptions{
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		RolesClaim: "realm_access.roles",
+	}), keys
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"aud":            testAudience,
+		"sub":            "user-1",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"scope":          "openid todos:read",
+		"realm_access":   map[string]any{"roles": []string{"admin", "user"}},
+	}
+}
+
+func TestVerifyAcceptsRS256AndES256(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t, "rsa-1"), newECKey(t, "ec-1")
+	verifier, _ := newTestVerifier(newJWKSServer(t, rsaKey, ecKey).URL)
+
+	for _, key := range []*signingKey{rsaKey, ecKey} {
+		identity, err := verifier.Verify(context.Background(), key.sign(t, validClaims()))
+		if err != nil {
+			t.Fatalf("Verify %s: %v", key.method.Alg(), err)
+		}
+
+		want := Identity{Issuer: testIssuer, Subject: "user-1", Email: "alice@example.com", EmailVerified: true}
+		if identity.Issuer != want.Issuer || identity.Subject != want.Subject || identity.Email != want.Email || !identity.EmailVerified {
+			t.Fatalf("Verify %s = %+v, want %+v", key.method.Alg(), identity, want)
+		}
+		if len(identity.Roles) != 2 || identity.Roles[0] != "admin" || identity.Roles[1] != "user" {
+			t.Fatalf("Verify %s roles = %v", key.method.Alg(), identity.Roles)
+		}
+		if len(identity.Scopes) != 2 || identity.Scopes[1] != "todos:read" {
+			t.Fatalf("Verify %s scopes = %v", key.method.Alg(), identity.Scopes)
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	key := newRSAKey(t, "rsa-1")
+	verifier, _ := newTestVerifier(newJWKSServer(t, key).URL)
+
+	with := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	tampered := key.sign(t, validClaims())
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+
+	tests := map[string]string{
+		"wrong issuer":     key.sign(t, with("iss", "https://evil.example.com")),
+		"wrong audience":   key.sign(t, with("aud", "another-api")),
+		"expired":          key.sign(t, with("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":        key.sign(t, with("exp", nil)),
+		"not yet valid":    key.sign(t, with("nbf", time.Now().Add(time.Hour).Unix())),
+		"no subject":       key.sign(t, with("sub", nil)),
+		"unknown key":      newRSAKey(t, "rsa-2").sign(t, validClaims()),
+		"HS256":            hs256,
+		"none":             none,
+		"bad signature":    tampered,
+		"not a JWT at all": "todo_3f1c9a7b",
+	}
+
+	for name, token := range tests {
+		if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: got %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestKeySetPicksUpRotatedKeys(t *testing.T) {
+	oldKey, newKey := newRSAKey(t, "2026-01"), newECKey(t, "2026-02")
+	jwks := newJWKSServer(t, oldKey)
+	verifier, keys := newTestVerifier(jwks.URL)
+
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	if _, err := verifier.Verify(context.Background(), oldKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify with the old key: %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), oldKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify with the old key again: %v", err)
+	}
+	if got := jwks.requests.Load(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want it cached after the first", got)
+	}
+
+	jwks.setKeys(oldKey, newKey)
+
+	// An unknown key is only fetched again after the minimum interval, so
+	// tokens with made-up key IDs cannot hammer the provider.
+	if _, err := verifier.Verify(context.Background(), newKey.sign(t, validClaims())); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify with the new key right away: got %v, want ErrInvalidToken", err)
+	}
+
+	now = now.Add(minRefetchInterval)
+	if _, err := verifier.Verify(context.Background(), newKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify with the new key: %v", err)
+	}
+
+	// Expired keys keep verifying tokens while they are refreshed in the
+	// background, so the retired key is refused once the refresh is done.
+	jwks.setKeys(newKey)
+	now = now.Add(time.Hour)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		_, err := verifier.Verify(context.Background(), oldKey.sign(t, validClaims()))
+		if errors.Is(err, ErrInvalidToken) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Verify with the retired key: got %v, want ErrInvalidToken", err)
+		}
+	}
+	if got := jwks.requests.Load(); got != 3 {
+		t.Fatalf("JWKS fetched %d times, want 3", got)
+	}
+}
+
+func TestKeySetKeepsKeysWhenTheSourceFails(t *testing.T) {
+	key := newECKey(t, "")
+	jwks := newJWKSServer(t, key)
+	verifier, keys := newTestVerifier(jwks.URL)
+
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	// A token without a kid is checked against the only key of the set.
+	if _, err := verifier.Verify(context.Background(), key.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	jwks.Close()
+	now = now.Add(2 * time.Hour)
+	if _, err := verifier.Verify(context.Background(), key.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify while the JWKS is unreachable: %v", err)
+	}
+}
+
+func TestKeySetServesCachedKeysWhileRefreshing(t *testing.T) {
+	key := newECKey(t, "2026-01")
+	jwks := newJWKSServer(t, key)
+	verifier, keys := newTestVerifier(jwks.URL)
+
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	if _, err := verifier.Verify(context.Background(), key.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// The JWKS endpoint hangs until the test ends.
+	jwks.mu.Lock()
+	defer jwks.mu.Unlock()
+	now = now.Add(2 * time.Hour)
+
+	verify := func() {
+		t.Helper()
+		verified := make(chan error, 1)
+		go func() {
+			_, err := verifier.Verify(context.Background(), key.sign(t, validClaims()))
+			verified <- err
+		}()
+
+		select {
+		case err := <-verified:
+			if err != nil {
+				t.Fatalf("Verify during the refresh: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Verify waited for the JWKS refresh")
+		}
+	}
+
+	verify()
+	for deadline := time.Now().Add(5 * time.Second); jwks.requests.Load() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the expired keys were not refreshed")
+		}
+	}
+
+	verify()
+	verify()
+	if got := jwks.requests.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want one refresh shared by all callers", got)
+	}
+}
+
+func TestKeySetReadsFiles(t *testing.T) {
+	key := newRSAKey(t, "file-1")
+	data, err := json.Marshal(jwksDocument([]*signingKey{key}))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	verifier, _ := newTestVerifier(path)
+	if _, err := verifier.Verify(context.Background(), key.sign(t, validClaims())); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestStringListReadsArraysAndSpaceSeparatedStrings(t *testing.T) {
+	if got := stringList("a  b c"); len(got) != 3 || got[2] != "c" {
+		t.Fatalf("stringList(string) = %v", got)
+	}
+	if got := stringList([]any{"a", 1, "", "b"}); len(got) != 2 || got[1] != "b" {
+		t.Fatalf("stringList(array) = %v", got)
+	}
+	if got := claimAt(map[string]any{"a": map[string]any{"b": "c"}}, "a.b.c"); got != nil {
+		t.Fatalf("claimAt past a leaf = %v, want nil", got)
+	}
+}
@@ -0,0 +1,319 @@
+// Package certs builds the server's TLS configuration from certificate
+// files and replaces the certificate and client CAs without a restart when
+// the files change.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"todo-api/internal/config"
+)
+
+// Client certificate policies accepted by TLSConfig.ClientAuth.
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+// selfSignedValidity is how long a generated development certificate is
+// valid.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// Reloader serves the certificate and client CAs last read from the
+// configured files. Each handshake uses the configuration current at the
+// time, so a reload applies to new connections only.
+type Reloader struct {
+	base    *tls.Config
+	files   []string
+	cfg     config.TLSConfig
+	current atomic.Pointer[tls.Config]
+
+	mu    sync.Mutex
+	stamp map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader reads the certificate, key and client CAs named by cfg, or
+// generates a self-signed certificate when cfg asks for one without naming
+// a certificate.
+func NewReloader(cfg config.TLSConfig) (*Reloader, error) {
+	base, err := baseConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{base: base, cfg: cfg}
+	if cfg.CertFile == "" {
+		if !cfg.SelfSigned {
+			return nil, errors.New("TLS requires a certificate file or a self-signed certificate")
+		}
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("client certificates cannot be verified with a self-signed certificate")
+		}
+
+		cert, err := SelfSigned(selfSignedHosts()...)
+		if err != nil {
+			return nil, err
+		}
+		slog.Warn("Serving a self-signed TLS certificate; do not use it in production",
+			"sha256", fingerprint(cert.Leaf))
+
+		current := base.Clone()
+		current.Certificates = []tls.Certificate{cert}
+		r.current.Store(current)
+		return r, nil
+	}
+
+	if cfg.KeyFile == "" {
+		return nil, errors.New("TLS certificate file set without a key file")
+	}
+	r.files = []string{cfg.CertFile, cfg.KeyFile}
+	if cfg.ClientCAFile != "" {
+		r.files = append(r.files, cfg.ClientCAFile)
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns the configuration to serve with. It hands every handshake
+// the certificate current at the time.
+func (r *Reloader) Config() *tls.Config {
+	cfg := r.base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return r.current.Load(), nil
+	}
+	return cfg
+}
+
+// Reload reads the certificate files again. On failure the previous
+// certificate stays in use. A self-signed certificate is kept as it is.
+func (r *Reloader) Reload() error {
+	if len(r.files) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp := r.stat()
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	current := r.base.Clone()
+	current.Certificates = []tls.Certificate{cert}
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.cfg.ClientCAFile)
+		}
+		current.ClientCAs = pool
+	}
+
+	r.current.Store(current)
+	r.stamp = stamp
+	slog.Info("TLS certificate loaded", "file", r.cfg.CertFile,
+		"sha256", fingerprint(cert.Leaf), "not_after", cert.Leaf.NotAfter)
+	return nil
+}
+
+// Watch reloads the certificate whenever one of its files changes, checking
+// every interval until stop is closed. Failed reloads are logged and
+// retried on the next check, which covers a certificate and key replaced
+// one after the other.
+func (r *Reloader) Watch(stop <-chan struct{}, interval time.Duration) {
+	if len(r.files) == 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("Failed to reload TLS certificate", "error", err)
+			}
+		}
+	}
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for file, stamp := range r.stat() {
+		if r.stamp[file] != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// stat records the files' modification times and sizes. Files that cannot
+// be read are left out, so they count as changed once they reappear.
+func (r *Reloader) stat() map[string]fileStamp {
+	stamp := make(map[string]fileStamp, len(r.files))
+	for _, file := range r.files {
+		// Stat follows symlinks, so a Kubernetes secret volume swapping
+		// its data directory counts as a change.
+		if info, err := os.Stat(file); err == nil {
+			stamp[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamp
+}
+
+// baseConfig returns the settings shared by every certificate: protocol
+// versions, cipher suites and client certificate policy. HTTP/2 is
+// offered ahead of HTTP/1.1.
+func baseConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	base := &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+
+	switch cfg.MinVersion {
+	case "", "1.2":
+		base.MinVersion = tls.VersionTLS12
+	case "1.3":
+		base.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("invalid TLS minimum version %q; use 1.2 or 1.3", cfg.MinVersion)
+	}
+
+	suites, err := cipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	base.CipherSuites = suites
+
+	switch {
+	case cfg.ClientCAFile == "" && cfg.ClientAuth != "":
+		return nil, errors.New("TLS client authentication requires a client CA file")
+	case cfg.ClientCAFile == "":
+		base.ClientAuth = tls.NoClientCert
+	case cfg.ClientAuth == "" || cfg.ClientAuth == ClientAuthRequire:
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	case cfg.ClientAuth == ClientAuthOptional:
+		base.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("invalid TLS client authentication %q; use %s or %s",
+			cfg.ClientAuth, ClientAuthRequire, ClientAuthOptional)
+	}
+
+	return base, nil
+}
+
+// cipherSuites looks up TLS 1.2 cipher suites by name. Only suites Go
+// considers secure are accepted. TLS 1.3 suites are not configurable.
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure TLS cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SelfSigned generates an ECDSA certificate for hosts, which may be names
+// or IP addresses, signed by its own key.
+func SelfSigned(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"todo-api development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// selfSignedHosts are the names a development certificate is issued for.
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "localhost" {
+		hosts = append(hosts, name)
+	}
+	return hosts
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,333 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-api/internal/certs"
+	"todo-api/internal/config"
+)
+
+// testCA issues certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate for 127.0.0.1 with the given serial number
+// and usage.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeCA writes the CA certificate as a PEM file in dir and returns its
+// path.
+func (ca *testCA) write(t *testing.T, dir string) string {
+	t.Helper()
+
+	file := filepath.Join(dir, "ca.crt")
+	writeFile(t, file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+	return file
+}
+
+// writeCert writes cert and its key as PEM files in dir and returns their
+// paths.
+func writeCert(t *testing.T, dir string, cert tls.Certificate) (string, string) {
+	t.Helper()
+
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+// serve runs a server with the reloader's configuration and returns its
+// URL.
+func serve(t *testing.T, r *certs.Reloader) string {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.EnableHTTP2 = true
+	ts.TLS = r.Config()
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	return ts.URL
+}
+
+// get requests url over a new connection with cfg and returns the
+// response, whose body is closed.
+func get(t *testing.T, url string, cfg *tls.Config) (*http.Response, error) {
+	t.Helper()
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true},
+		Timeout:   5 * time.Second,
+	}
+	defer httpClient.CloseIdleConnections()
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// servedSerial returns the serial number of the certificate presented to a
+// new connection.
+func servedSerial(t *testing.T, url string, ca *testCA) int64 {
+	t.Helper()
+
+	resp, err := get(t, url, &tls.Config{RootCAs: ca.pool})
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestReloaderVerifiesClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, ca.issue(t, 2, x509.ExtKeyUsageServerAuth))
+
+	r, err := certs.NewReloader(config.TLSConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: ca.write(t, dir),
+		ClientAuth:   certs.ClientAuthRequire,
+		MinVersion:   "1.3",
+	})
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	url := serve(t, r)
+
+	clientCert := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+	resp, err := get(t, url, &tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{clientCert}})
+	if err != nil {
+		t.Fatalf("GET with client certificate: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Errorf("status %d over %s, want 200 over HTTP/2", resp.StatusCode, resp.Proto)
+	}
+
+	if _, err := get(t, url, &tls.Config{RootCAs: ca.pool}); err == nil {
+		t.Error("GET without client certificate succeeded")
+	}
+
+	_, err = get(t, url, &tls.Config{
+		RootCAs:      ca.pool,
+		Certificates: []tls.Certificate{clientCert},
+		MaxVersion:   tls.VersionTLS12,
+	})
+	if err == nil {
+		t.Error("GET over TLS 1.2 succeeded with a TLS 1.3 minimum")
+	}
+}
+
+func TestReloaderReloadsCertificates(t *testing.T) {
+	ca := newTestCA(t)
+
+	t.Run("on Reload", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeCert(t, dir, ca.issue(t, 10, x509.ExtKeyUsageServerAuth))
+		r, err := certs.NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		if err != nil {
+			t.Fatalf("NewReloader: %v", err)
+		}
+		url := serve(t, r)
+
+		if serial := servedSerial(t, url, ca); serial != 10 {
+			t.Fatalf("served serial %d, want 10", serial)
+		}
+
+		writeCert(t, dir, ca.issue(t, 11, x509.ExtKeyUsageServerAuth))
+		if serial := servedSerial(t, url, ca); serial != 10 {
+			t.Errorf("served serial %d before reload, want 10", serial)
+		}
+		if err := r.Reload(); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if serial := servedSerial(t, url, ca); serial != 11 {
+			t.Errorf("served serial %d after reload, want 11", serial)
+		}
+
+		writeFile(t, keyFile, []byte("not a key"))
+		if err := r.Reload(); err == nil {
+			t.Error("Reload succeeded with a broken key")
+		}
+		if serial := servedSerial(t, url, ca); serial != 11 {
+			t.Errorf("served serial %d after failed reload, want 11", serial)
+		}
+	})
+
+	t.Run("on file change", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeCert(t, dir, ca.issue(t, 20, x509.ExtKeyUsageServerAuth))
+		r, err := certs.NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		if err != nil {
+			t.Fatalf("NewReloader: %v", err)
+		}
+		url := serve(t, r)
+
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			r.Watch(stop, 20*time.Millisecond)
+			close(done)
+		}()
+		t.Cleanup(func() {
+			close(stop)
+			<-done
+		})
+
+		writeCert(t, dir, ca.issue(t, 21, x509.ExtKeyUsageServerAuth))
+		deadline := time.Now().Add(5 * time.Second)
+		for servedSerial(t, url, ca) != 21 {
+			if time.Now().After(deadline) {
+				t.Fatal("certificate was not reloaded after its files changed")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+}
+
+func TestReloaderSelfSignedDevelopmentCertificate(t *testing.T) {
+	r, err := certs.NewReloader(config.TLSConfig{SelfSigned: true})
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	url := serve(t, r)
+
+	if _, err := get(t, url, &tls.Config{}); err == nil {
+		t.Error("self-signed certificate verified against system roots")
+	}
+
+	resp, err := get(t, url, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	cert := resp.TLS.PeerCertificates[0]
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("VerifyHostname(%s): %v", host, err)
+		}
+	}
+
+	// There are no files to reload.
+	if err := r.Reload(); err != nil {
+		t.Errorf("Reload: %v", err)
+	}
+}
+
+func TestNewReloaderRejectsInvalidConfig(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, ca.issue(t, 2, x509.ExtKeyUsageServerAuth))
+	caFile := ca.write(t, dir)
+
+	for _, tc := range []struct {
+		name string
+		cfg  config.TLSConfig
+	}{
+		{"without a certificate", config.TLSConfig{}},
+		{"without a key", config.TLSConfig{CertFile: certFile}},
+		{"with a missing certificate", config.TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile}},
+		{"with an unknown minimum version", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.1"}},
+		{"with an insecure cipher suite", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}},
+		{"with client auth but no client CA", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: certs.ClientAuthRequire}},
+		{"with an unknown client auth", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: "maybe"}},
+		{"with a client CA and a self-signed certificate", config.TLSConfig{SelfSigned: true, ClientCAFile: caFile}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := certs.NewReloader(tc.cfg); err == nil {
+				t.Errorf("NewReloader(%+v) succeeded", tc.cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package collab
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"todo-api/internal/events"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+	sendBufferSize = 64
+)
+
+// Message is the envelope of every frame exchanged on the collaboration
+// channel. Clients send subscribe, unsubscribe, editing, stop_editing and
+// ping messages; the server sends welcome, subscribed, event, presence,
+// lock, lock_denied, pong and error messages.
+type Message struct {
+	Type     string        `json:"type"`
+	Topic    string        `json:"topic,omitempty"`
+	TodoID   int64         `json:"todo_id,omitempty"`
+	Action   string        `json:"action,omitempty"`
+	ClientID string        `json:"client_id,omitempty"`
+	User     string        `json:"user,omitempty"`
+	Members  []string      `json:"members,omitempty"`
+	Event    *events.Event `json:"event,omitempty"`
+	Lock     *Lock         `json:"lock,omitempty"`
+	Locks    []*Lock       `json:"locks,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Client is one WebSocket connection of a user.
+type Client struct {
+	id     string
+	user   string
+	owner  int64
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	topics map[string]bool
+}
+
+// Serve runs the connection of user until it is closed. owner is the
+// account the connection authenticated as, or zero when it is not tied to
+// one and sees every todo.
+func (h *Hub) Serve(conn *websocket.Conn, user string, owner int64) {
+	client := &Client{
+		user:   user,
+		owner:  owner,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, sendBufferSize),
+		topics: make(map[string]bool),
+	}
+
+	h.register(client)
+	h.mu.Lock()
+	h.sendLocked(client, Message{Type: "welcome", User: user, ClientID: client.id})
+	h.mu.Unlock()
+
+	go client.writePump()
+	client.readPump()
+}
+
+// enqueue queues a frame without blocking. A client that cannot keep up is
+// disconnected. Callers must hold the hub lock.
+func (c *Client) enqueue(data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		c.conn.Close()
+	}
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(Message{Type: "error", Error: "invalid message: " + err.Error()})
+			continue
+		}
+
+		c.handle(msg)
+	}
+}
+
+func (c *Client) handle(msg Message) {
+	switch msg.Type {
+	case "subscribe":
+		if err := c.hub.subscribe(c, msg.Topic); err != nil {
+			c.reply(Message{Type: "error", Topic: msg.Topic, Error: err.Error()})
+		}
+	case "unsubscribe":
+		c.hub.unsubscribe(c, msg.Topic)
+	case "editing":
+		c.hub.acquireLock(c, msg.TodoID)
+	case "stop_editing":
+		c.hub.releaseLock(c, msg.TodoID)
+	case "ping":
+		c.reply(Message{Type: "pong"})
+	default:
+		c.reply(Message{Type: "error", Error: "unknown message type: " + msg.Type})
+	}
+}
+
+func (c *Client) reply(msg Message) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	c.hub.sendLocked(c, msg)
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
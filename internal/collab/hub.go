@@ -0,0 +1,414 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-api/internal/events"
+	"todo-api/internal/repositories"
+)
+
+// TopicTodos is the topic of a user's todo list. A single todo is
+// subscribed to as "todo:<id>".
+const TopicTodos = "todos"
+
+// Hub tracks WebSocket clients, the topics they subscribe to, who is present
+// on each topic and the soft "is editing" locks on todos. Todo changes are
+// taken from the same broker that feeds the SSE stream, so REST writes reach
+// WebSocket subscribers too.
+//
+// Clients tied to a user only hear about the todos that user may see: those
+// they own and those shared with them. Presence on a todo is shared by
+// everyone subscribed to it, presence on the list only by the clients of
+// one user, whose lists differ. Clients not tied to a user see every todo.
+type Hub struct {
+	broker  *events.Broker
+	lockTTL time.Duration
+	viewers Viewers
+
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	topics  map[string]map[*Client]struct{}
+	locks   map[int64]*Lock
+	nextID  uint64
+}
+
+// Viewers looks up the users who may see a todo: its owner and the users it
+// is shared with.
+type Viewers func(ctx context.Context, todoID int64) ([]int64, error)
+
+// Lock is an advisory claim that a user is editing a todo. It expires
+// unless the holder renews it.
+type Lock struct {
+	TodoID    int64     `json:"todo_id"`
+	User      string    `json:"user"`
+	ClientID  string    `json:"client_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// viewers are the users who may see the todo, looked up when the lock
+	// was taken or last renewed.
+	viewers []int64
+}
+
+func NewHub(broker *events.Broker, lockTTL time.Duration, viewers Viewers) *Hub {
+	return &Hub{
+		broker:  broker,
+		lockTTL: lockTTL,
+		viewers: viewers,
+		clients: make(map[*Client]struct{}),
+		topics:  make(map[string]map[*Client]struct{}),
+		locks:   make(map[int64]*Lock),
+	}
+}
+
+// Run forwards broker events to subscribed clients and expires locks until
+// stop is closed.
+func (h *Hub) Run(stop <-chan struct{}) {
+	sub, _, _ := h.broker.Subscribe(0, nil)
+	defer func() { h.broker.Unsubscribe(sub) }()
+
+	ticker := time.NewTicker(h.lockTTL / 4)
+	defer ticker.Stop()
+
+	var lastSeq uint64
+	for {
+		select {
+		case <-stop:
+			h.closeAll()
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// Fell behind the broker: resubscribe from the last
+				// forwarded event and replay what is still logged.
+				var backlog []events.Event
+				sub, backlog, _ = h.broker.Subscribe(lastSeq, nil)
+				for _, event := range backlog {
+					h.dispatch(event)
+					lastSeq = event.Seq
+				}
+				continue
+			}
+			h.dispatch(event)
+			lastSeq = event.Seq
+		case now := <-ticker.C:
+			h.expireLocks(now)
+		}
+	}
+}
+
+func (h *Hub) register(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	client.id = strconv.FormatUint(h.nextID, 10)
+	h.clients[client] = struct{}{}
+}
+
+// unregister removes a disconnected client, announcing that it left its
+// topics and releasing its locks.
+func (h *Hub) unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+
+	for topic := range client.topics {
+		h.leaveLocked(client, topic)
+	}
+
+	for todoID, lock := range h.locks {
+		if lock.ClientID == client.id {
+			delete(h.locks, todoID)
+			h.broadcastLockLocked("released", lock)
+		}
+	}
+
+	close(client.send)
+}
+
+func (h *Hub) subscribe(client *Client, topic string) error {
+	todoID, err := parseTopic(topic)
+	if err != nil {
+		return err
+	}
+	if todoID != 0 {
+		if _, err := h.lookup(client, todoID); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client.topics[topic] {
+		return nil
+	}
+
+	members, ok := h.topics[topic]
+	if !ok {
+		members = make(map[*Client]struct{})
+		h.topics[topic] = members
+	}
+	members[client] = struct{}{}
+	client.topics[topic] = true
+
+	h.sendLocked(client, Message{Type: "subscribed", Topic: topic, Locks: h.locksFor(client, topic)})
+	h.broadcastPresenceLocked(client, topic, "join")
+	return nil
+}
+
+func (h *Hub) unsubscribe(client *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client.topics[topic] {
+		h.leaveLocked(client, topic)
+	}
+}
+
+func (h *Hub) leaveLocked(client *Client, topic string) {
+	delete(client.topics, topic)
+
+	members := h.topics[topic]
+	delete(members, client)
+	if len(members) == 0 {
+		delete(h.topics, topic)
+	}
+
+	h.broadcastPresenceLocked(client, topic, "leave")
+}
+
+// acquireLock grants or renews the editing lock of a todo the client may
+// see. It fails while another client holds an unexpired lock.
+func (h *Hub) acquireLock(client *Client, todoID int64) {
+	if todoID <= 0 {
+		client.reply(Message{Type: "error", Error: fmt.Sprintf("invalid todo id: %d", todoID)})
+		return
+	}
+
+	viewers, err := h.lookup(client, todoID)
+	if err != nil {
+		client.reply(Message{Type: "error", TodoID: todoID, Error: err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if lock, ok := h.locks[todoID]; ok && lock.ClientID != client.id && lock.ExpiresAt.After(now) {
+		h.sendLocked(client, Message{Type: "lock_denied", TodoID: todoID, Lock: lock})
+		return
+	}
+
+	lock, renewed := h.locks[todoID]
+	renewed = renewed && lock.ClientID == client.id
+	lock = &Lock{TodoID: todoID, User: client.user, ClientID: client.id, ExpiresAt: now.Add(h.lockTTL), viewers: viewers}
+	h.locks[todoID] = lock
+
+	if renewed {
+		h.sendLocked(client, Message{Type: "lock", Action: "renewed", TodoID: todoID, Lock: lock})
+		return
+	}
+	h.broadcastLockLocked("acquired", lock)
+}
+
+func (h *Hub) releaseLock(client *Client, todoID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lock, ok := h.locks[todoID]; ok && lock.ClientID == client.id {
+		delete(h.locks, todoID)
+		h.broadcastLockLocked("released", lock)
+	}
+}
+
+func (h *Hub) expireLocks(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for todoID, lock := range h.locks {
+		if !lock.ExpiresAt.After(now) {
+			delete(h.locks, todoID)
+			h.broadcastLockLocked("expired", lock)
+		}
+	}
+}
+
+// lookup returns who may see a todo. Todos the client may not see are
+// reported as not found, like the REST API does.
+func (h *Hub) lookup(client *Client, todoID int64) ([]int64, error) {
+	viewers, err := h.viewers(context.Background(), todoID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		slog.Error("Failed to look up who may see a todo", "todo_id", todoID, "error", err)
+		return nil, fmt.Errorf("failed to load todo %d", todoID)
+	}
+	if err != nil || !client.sees(viewers) {
+		return nil, fmt.Errorf("todo %d not found", todoID)
+	}
+	return viewers, nil
+}
+
+// dispatch sends a todo change to the subscribers of the list and of the
+// todo who may see it. A deleted todo also loses its lock.
+func (h *Hub) dispatch(event events.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	viewers := event.Viewers()
+	for _, topic := range []string{TopicTodos, todoTopic(event.TodoID)} {
+		h.broadcastLocked(topic, viewers, Message{Type: "event", Topic: topic, Event: &event})
+	}
+
+	if event.Type == events.TodoDeleted {
+		if lock, ok := h.locks[event.TodoID]; ok {
+			delete(h.locks, event.TodoID)
+			h.broadcastLockLocked("released", lock)
+		}
+	}
+}
+
+// broadcastPresenceLocked announces that client joined or left topic to the
+// clients present with it.
+func (h *Hub) broadcastPresenceLocked(client *Client, topic, action string) {
+	peers := h.peersLocked(client, topic)
+
+	users := make(map[string]bool)
+	members := []string{}
+	for _, peer := range peers {
+		if !users[peer.user] {
+			users[peer.user] = true
+			members = append(members, peer.user)
+		}
+	}
+	sort.Strings(members)
+
+	h.sendAllLocked(peers, Message{
+		Type:    "presence",
+		Topic:   topic,
+		Action:  action,
+		User:    client.user,
+		Members: members,
+	})
+}
+
+// peersLocked lists the clients present on topic with client, client
+// included while it is subscribed: everyone on a todo topic, and the
+// clients of the same user on the list.
+func (h *Hub) peersLocked(client *Client, topic string) []*Client {
+	var peers []*Client
+	for member := range h.topics[topic] {
+		if topic != TopicTodos || member.owner == client.owner {
+			peers = append(peers, member)
+		}
+	}
+	return peers
+}
+
+// broadcastLockLocked announces a lock change on the list and the todo
+// topics to the subscribers who may see the todo.
+func (h *Hub) broadcastLockLocked(action string, lock *Lock) {
+	for _, topic := range []string{TopicTodos, todoTopic(lock.TodoID)} {
+		h.broadcastLocked(topic, lock.viewers, Message{Type: "lock", Topic: topic, Action: action, TodoID: lock.TodoID, Lock: lock})
+	}
+}
+
+// broadcastLocked sends msg to the subscribers of topic who may see a todo
+// with viewers.
+func (h *Hub) broadcastLocked(topic string, viewers []int64, msg Message) {
+	var recipients []*Client
+	for client := range h.topics[topic] {
+		if client.sees(viewers) {
+			recipients = append(recipients, client)
+		}
+	}
+
+	h.sendAllLocked(recipients, msg)
+}
+
+func (h *Hub) sendAllLocked(clients []*Client, msg Message) {
+	if len(clients) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to encode collaboration message", "error", err)
+		return
+	}
+
+	for _, client := range clients {
+		client.enqueue(data)
+	}
+}
+
+func (h *Hub) sendLocked(client *Client, msg Message) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+
+	h.sendAllLocked([]*Client{client}, msg)
+}
+
+// locksFor lists the locks shown to client on subscribing to topic.
+func (h *Hub) locksFor(client *Client, topic string) []*Lock {
+	locks := []*Lock{}
+	for todoID, lock := range h.locks {
+		if (topic == TopicTodos || topic == todoTopic(todoID)) && client.sees(lock.viewers) {
+			locks = append(locks, lock)
+		}
+	}
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].TodoID < locks[j].TodoID })
+	return locks
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.conn.Close()
+	}
+}
+
+// sees reports whether the client may see a todo with viewers.
+func (c *Client) sees(viewers []int64) bool {
+	return c.owner == 0 || slices.Contains(viewers, c.owner)
+}
+
+func todoTopic(todoID int64) string {
+	return "todo:" + strconv.FormatInt(todoID, 10)
+}
+
+// parseTopic returns the todo of a "todo:<id>" topic, or zero for the list.
+func parseTopic(topic string) (int64, error) {
+	if topic == TopicTodos {
+		return 0, nil
+	}
+
+	if raw, ok := strings.CutPrefix(topic, "todo:"); ok {
+		if id, err := strconv.ParseInt(raw, 10, 64); err == nil && id > 0 {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown topic %q: use %q or \"todo:<id>\"", topic, TopicTodos)
+}
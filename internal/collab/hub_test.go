@@ -0,0 +1,279 @@
+package collab_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"todo-api/internal/collab"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// viewers knows who may see todos 1 to 3: user 1 owns them all and shares
+// todo 1 with user 2.
+func viewers(_ context.Context, todoID int64) ([]int64, error) {
+	switch todoID {
+	case 1:
+		return []int64{1, 2}, nil
+	case 2, 3:
+		return []int64{1}, nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// startHub runs a hub whose clients connect to the returned URL, naming
+// themselves with the user query parameter and acting for the account of
+// the owner parameter, if any.
+func startHub(t *testing.T, lockTTL time.Duration) (*events.Broker, string) {
+	t.Helper()
+
+	broker := events.NewBroker(100, 16)
+	hub := collab.NewHub(broker, lockTTL, viewers)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		hub.Run(stop)
+		close(done)
+	}()
+
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		owner, _ := strconv.ParseInt(r.URL.Query().Get("owner"), 10, 64)
+		hub.Serve(conn, r.URL.Query().Get("user"), owner)
+	}))
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+		ts.Close()
+		broker.Close()
+	})
+
+	return broker, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// participant is one connection to the hub.
+type participant struct {
+	t    *testing.T
+	conn *websocket.Conn
+	id   string
+}
+
+// join connects as user, who is not tied to an account and sees every todo.
+func join(t *testing.T, url, user string) *participant {
+	t.Helper()
+
+	return joinAs(t, url, user, 0)
+}
+
+// joinAs connects as user acting for the account owner.
+func joinAs(t *testing.T, url, user string, owner int64) *participant {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?user="+user+"&owner="+strconv.FormatInt(owner, 10), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	p := &participant{t: t, conn: conn}
+	welcome := p.expect("welcome", "")
+	p.id = welcome.ClientID
+	return p
+}
+
+func (p *participant) send(msg collab.Message) {
+	p.t.Helper()
+
+	if err := p.conn.WriteJSON(msg); err != nil {
+		p.t.Fatalf("WriteJSON: %v", err)
+	}
+}
+
+// subscribe subscribes to topic and waits for the confirmation.
+func (p *participant) subscribe(topic string) collab.Message {
+	p.t.Helper()
+
+	p.send(collab.Message{Type: "subscribe", Topic: topic})
+	return p.expect("subscribed", "")
+}
+
+// expect skips messages until one of type msgType, with action unless it
+// is empty, arrives.
+func (p *participant) expect(msgType, action string) collab.Message {
+	p.t.Helper()
+
+	p.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, data, err := p.conn.ReadMessage()
+		if err != nil {
+			p.t.Fatalf("waiting for %s %s: %v", msgType, action, err)
+		}
+
+		var msg collab.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			p.t.Fatalf("decoding %s: %v", data, err)
+		}
+		if msg.Type == msgType && (action == "" || msg.Action == action) {
+			return msg
+		}
+	}
+}
+
+func TestHubBroadcastsPresenceAndChanges(t *testing.T) {
+	broker, url := startHub(t, time.Minute)
+	alice := join(t, url, "alice")
+	alice.subscribe(collab.TopicTodos)
+	bob := join(t, url, "bob")
+	bob.subscribe(collab.TopicTodos)
+
+	// Alice first sees her own join.
+	if presence := alice.expect("presence", "join"); presence.User != "alice" {
+		t.Errorf("alice first saw %s join, want herself", presence.User)
+	}
+	if presence := alice.expect("presence", "join"); presence.User != "bob" || strings.Join(presence.Members, ",") != "alice,bob" {
+		t.Errorf("alice saw %s join with members %v, want bob with alice,bob", presence.User, presence.Members)
+	}
+
+	broker.Publish(events.New(events.TodoCreated, &models.Todo{ID: 7, Title: "Buy groceries"}))
+	for _, p := range []*participant{alice, bob} {
+		msg := p.expect("event", "")
+		if msg.Topic != collab.TopicTodos || msg.Event == nil || msg.Event.TodoID != 7 {
+			t.Errorf("got %+v, want the creation of todo 7 on %s", msg, collab.TopicTodos)
+		}
+	}
+
+	bob.conn.Close()
+	if presence := alice.expect("presence", "leave"); presence.User != "bob" || strings.Join(presence.Members, ",") != "alice" {
+		t.Errorf("alice saw %s leave with members %v, want bob with alice", presence.User, presence.Members)
+	}
+}
+
+func TestHubLocksAndReleases(t *testing.T) {
+	_, url := startHub(t, time.Minute)
+	alice := join(t, url, "alice")
+	alice.subscribe("todo:1")
+	bob := join(t, url, "bob")
+	bob.subscribe("todo:1")
+
+	alice.send(collab.Message{Type: "editing", TodoID: 1})
+	if lock := bob.expect("lock", "acquired").Lock; lock == nil || lock.User != "alice" || lock.ClientID != alice.id {
+		t.Fatalf("bob saw lock %+v, want alice's", lock)
+	}
+
+	bob.send(collab.Message{Type: "editing", TodoID: 1})
+	if denied := bob.expect("lock_denied", ""); denied.Lock == nil || denied.Lock.User != "alice" {
+		t.Errorf("bob was denied with %+v, want alice's lock", denied.Lock)
+	}
+
+	alice.send(collab.Message{Type: "editing", TodoID: 1})
+	alice.expect("lock", "renewed")
+
+	// Only the holder releases a lock.
+	bob.send(collab.Message{Type: "stop_editing", TodoID: 1})
+	bob.send(collab.Message{Type: "editing", TodoID: 1})
+	bob.expect("lock_denied", "")
+	alice.send(collab.Message{Type: "stop_editing", TodoID: 1})
+	if lock := bob.expect("lock", "released").Lock; lock == nil || lock.User != "alice" {
+		t.Errorf("bob saw %+v released, want alice's lock", lock)
+	}
+
+	bob.send(collab.Message{Type: "editing", TodoID: 1})
+	if lock := alice.expect("lock", "acquired").Lock; lock == nil || lock.User != "bob" {
+		t.Errorf("alice saw lock %+v, want bob's", lock)
+	}
+
+	// Disconnecting releases the client's locks.
+	bob.conn.Close()
+	if lock := alice.expect("lock", "released").Lock; lock == nil || lock.User != "bob" {
+		t.Errorf("alice saw %+v released, want bob's lock", lock)
+	}
+}
+
+func TestHubExpiresLocks(t *testing.T) {
+	_, url := startHub(t, 100*time.Millisecond)
+	alice := join(t, url, "alice")
+	alice.subscribe(collab.TopicTodos)
+	bob := join(t, url, "bob")
+	bob.subscribe(collab.TopicTodos)
+
+	alice.send(collab.Message{Type: "editing", TodoID: 3})
+	acquired := bob.expect("lock", "acquired").Lock
+	expired := bob.expect("lock", "expired").Lock
+	if expired == nil || expired.TodoID != 3 || expired.User != "alice" {
+		t.Fatalf("bob saw %+v expire, want alice's lock on todo 3", expired)
+	}
+	if time.Now().Before(acquired.ExpiresAt) {
+		t.Errorf("lock expired before its expiry %v", acquired.ExpiresAt)
+	}
+
+	if subscribed := join(t, url, "carol").subscribe(collab.TopicTodos); len(subscribed.Locks) != 0 {
+		t.Errorf("new subscriber sees locks %+v after expiry", subscribed.Locks)
+	}
+	bob.send(collab.Message{Type: "editing", TodoID: 3})
+	bob.expect("lock", "acquired")
+}
+
+func TestHubRoutesByAccessToEachTodo(t *testing.T) {
+	broker, url := startHub(t, time.Minute)
+	alice := joinAs(t, url, "alice", 1)
+	alice.subscribe(collab.TopicTodos)
+	bob := joinAs(t, url, "bob", 2)
+	bob.subscribe(collab.TopicTodos)
+	carol := joinAs(t, url, "carol", 3)
+
+	// Lists are private: carol is alone on hers.
+	carol.subscribe(collab.TopicTodos)
+	if presence := carol.expect("presence", "join"); strings.Join(presence.Members, ",") != "carol" {
+		t.Errorf("carol joined her list with members %v, want only herself", presence.Members)
+	}
+
+	// Only those a todo is shared with may join it or lock it.
+	carol.send(collab.Message{Type: "subscribe", Topic: "todo:1"})
+	if msg := carol.expect("error", ""); !strings.Contains(msg.Error, "todo 1 not found") {
+		t.Errorf("carol subscribing to todo 1 got %q, want not found", msg.Error)
+	}
+	carol.send(collab.Message{Type: "editing", TodoID: 2})
+	if msg := carol.expect("error", ""); !strings.Contains(msg.Error, "todo 2 not found") {
+		t.Errorf("carol locking todo 2 got %q, want not found", msg.Error)
+	}
+	bob.subscribe("todo:1")
+	bob.expect("presence", "join")
+	alice.subscribe("todo:1")
+	if presence := bob.expect("presence", "join"); presence.Topic != "todo:1" || strings.Join(presence.Members, ",") != "alice,bob" {
+		t.Errorf("bob saw presence %+v, want alice joining todo 1", presence)
+	}
+
+	// The grantee's lock reaches the owner's list.
+	bob.send(collab.Message{Type: "editing", TodoID: 1})
+	if lock := alice.expect("lock", "acquired"); lock.Topic != collab.TopicTodos || lock.Lock.User != "bob" {
+		t.Errorf("alice saw %+v, want bob's lock on her list", lock)
+	}
+
+	// Changes reach the owner and grantees only; carol's first event is
+	// her own.
+	owner, other := int64(1), int64(3)
+	shared := events.New(events.TodoUpdated, &models.Todo{ID: 1, Title: "Shared todo", OwnerID: &owner})
+	shared.Grantees = []int64{2}
+	broker.Publish(shared)
+	broker.Publish(events.New(events.TodoCreated, &models.Todo{ID: 9, Title: "Carol's todo", OwnerID: &other}))
+	for _, p := range []*participant{alice, bob} {
+		if msg := p.expect("event", ""); msg.Event.TodoID != 1 {
+			t.Errorf("got the event of todo %d, want the shared todo", msg.Event.TodoID)
+		}
+	}
+	if msg := carol.expect("event", ""); msg.Event.TodoID != 9 {
+		t.Errorf("carol got the event of todo %d, want only her own todo 9", msg.Event.TodoID)
+	}
+}
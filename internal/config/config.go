@@ -3,25 +3,373 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
+	HTTP     HTTPConfig
+	TLS      TLSConfig
 	Database DatabaseConfig
+	Storage  StorageConfig
+	Webhooks WebhookConfig
+	Stream   StreamConfig
+	Collab   CollabConfig
+	GRPC     GRPCConfig
+	GraphQL  GraphQLConfig
+	Auth     AuthConfig
+	Share    ShareConfig
+	Metrics  MetricsConfig
+	Tracing  TracingConfig
+	Log      LogConfig
+	Health   HealthConfig
+	Limits   LimitsConfig
+}
+
+// HTTPConfig controls how the REST API treats incoming requests.
+type HTTPConfig struct {
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For and
+	// X-Forwarded-Proto headers are believed when working out a client's
+	// IP and whether it connected over HTTPS.
+	TrustedProxies []string
+	// IdempotencyTTL is how long the response to a request with an
+	// Idempotency-Key header is kept for replay.
+	IdempotencyTTL time.Duration
+	// MaxBodySize bounds request bodies other than attachment uploads,
+	// which are bounded by StorageConfig.MaxAttachmentSize.
+	MaxBodySize int64
+	// HSTSMaxAge is announced in Strict-Transport-Security over HTTPS;
+	// zero leaves the header out.
+	HSTSMaxAge time.Duration
+	CORS       CORSConfig
+}
+
+// CORSConfig controls which browser origins may call the API. With no
+// AllowedOrigins, no CORS headers are sent.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as "https://app.example.com",
+	// or "*" for any.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization with
+	// cross-origin requests. It cannot be combined with the "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// TLSConfig controls HTTPS on the API and gRPC ports. Without a
+// certificate both are served in plain text.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is 1.2 or 1.3.
+	MinVersion string
+	// CipherSuites names the TLS 1.2 suites offered, such as
+	// TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256; empty uses Go's defaults.
+	CipherSuites []string
+	// ClientCAFile holds the CAs that client certificates are verified
+	// against, enabling mutual TLS.
+	ClientCAFile string
+	// ClientAuth is require, refusing clients without a certificate, or
+	// optional, verifying certificates only when clients present one.
+	ClientAuth string
+	// SelfSigned generates a certificate for localhost when no CertFile is
+	// set, for development.
+	SelfSigned bool
+	// ReloadInterval is how often the certificate files are checked for
+	// changes; zero disables the check, leaving SIGHUP.
+	ReloadInterval time.Duration
 }
 
 type DatabaseConfig struct {
-	DSN string
+	// Path is the SQLite database file; DSN opens it with the pragmas the
+	// server relies on, unless DSN is a postgres:// URL.
+	Path          string
+	DSN           string
+	MigrationsDir string
+}
+
+// StorageConfig controls the content-addressed blob store used for attachments.
+type StorageConfig struct {
+	BlobDir                string
+	MaxAttachmentSize      int64
+	AllowedAttachmentTypes []string
+	GCInterval             time.Duration
+}
+
+// WebhookConfig controls delivery of outgoing webhooks.
+type WebhookConfig struct {
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	BatchSize      int
+}
+
+// StreamConfig controls the Server-Sent Events change stream.
+type StreamConfig struct {
+	LogSize           int
+	BufferSize        int
+	HeartbeatInterval time.Duration
+}
+
+// CollabConfig controls the WebSocket collaboration channel.
+type CollabConfig struct {
+	LockTTL time.Duration
+}
+
+// GRPCConfig controls the gRPC API. An Addr of "off" disables it.
+type GRPCConfig struct {
+	Addr string
+}
+
+// GraphQLConfig bounds the operations accepted by the GraphQL endpoint.
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// AuthConfig controls API authentication. When disabled every route is
+// open, as before API keys existed.
+type AuthConfig struct {
+	Enabled bool
+	// Registration allows anyone to create an account.
+	Registration bool
+	// SessionTTL is the lifetime of the token issued by a login.
+	SessionTTL time.Duration
+	OIDC       OIDCConfig
+}
+
+// OIDCConfig lets the API accept JWTs issued by an OpenID Connect provider
+// alongside API keys. It is enabled by setting a JWKS URL or file.
+type OIDCConfig struct {
+	Issuer   string
+	Audience string
+	// JWKS is the URL or file path of the provider's signing keys.
+	JWKS        string
+	JWKSRefresh time.Duration
+	// RolesClaim is the dotted path of the claim listing the caller's
+	// roles, such as "realm_access.roles" for Keycloak.
+	RolesClaim string
+	// AdminRole is the role granting the admin scope.
+	AdminRole string
+	// DefaultScopes are granted to every valid token.
+	DefaultScopes []string
+	Leeway        time.Duration
+}
+
+// ShareConfig controls public share links.
+type ShareConfig struct {
+	// RateLimit is the number of share link requests allowed per minute
+	// from one client IP.
+	RateLimit int
+}
+
+// MetricsConfig controls the Prometheus metrics endpoint. Without an Addr,
+// /metrics is served on the API port to admin keys; with one, it is served
+// on that admin address without authentication instead.
+type MetricsConfig struct {
+	Addr string
+}
+
+// TracingConfig controls OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter is none, stdout, file or otlp.
+	Exporter string
+	// File receives the spans of the file exporter, one JSON object each.
+	File string
+	// Endpoint is the OTLP/HTTP URL of the otlp exporter; when empty, the
+	// standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the share of new traces recorded; traces started by a
+	// caller follow the caller's decision.
+	SampleRatio float64
+}
+
+// LogConfig controls the server's log output.
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// Format is json or text.
+	Format string
+}
+
+// HealthConfig controls the readiness checks.
+type HealthConfig struct {
+	// Timeout bounds each readiness check.
+	Timeout time.Duration
+	// ShutdownDelay is how long the server keeps serving after a shutdown
+	// signal while failing readiness, so load balancers stop routing to it
+	// before connections are closed.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is how long requests in flight at Close get to
+	// finish before their connections are closed.
+	ShutdownTimeout time.Duration
+}
+
+// LimitsConfig bounds what one client may do. Clients are told apart by
+// user, API key or, when unauthenticated, IP. A limit of zero disables it.
+type LimitsConfig struct {
+	// ReadsPerMinute and WritesPerMinute size separate token buckets for
+	// safe and unsafe requests.
+	ReadsPerMinute  int
+	WritesPerMinute int
+	// DailyTodos is how many todos a user may create per UTC day.
+	DailyTodos int
+}
+
+// Postgres reports whether DSN names a PostgreSQL database rather than a
+// SQLite file.
+func (c DatabaseConfig) Postgres() bool {
+	return strings.HasPrefix(c.DSN, "postgres://") || strings.HasPrefix(c.DSN, "postgresql://")
+}
+
+// Enabled reports whether the server should serve TLS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.SelfSigned
+}
+
+// Enabled reports whether a JWKS source is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.JWKS != ""
 }
 
 func NewConfig() *Config {
+	databasePath := getDatabasePath()
+	
 	return &Config{
-		Database: DatabaseConfig{
-			DSN: getDatabasePath(),
+		HTTP: HTTPConfig{
+			TrustedProxies: getEnvList("TODO_TRUSTED_PROXIES", []string{"127.0.0.1", "::1"}),
+			IdempotencyTTL: getEnvDuration("TODO_IDEMPOTENCY_TTL", 24*time.Hour),
+			MaxBodySize:    getEnvInt64("TODO_MAX_BODY_SIZE", 1<<20),
+			HSTSMaxAge:     getEnvDuration("TODO_HSTS_MAX_AGE", 180*24*time.Hour),
+			CORS: CORSConfig{
+				AllowedOrigins:   getEnvList("TODO_CORS_ALLOWED_ORIGINS", nil),
+				AllowedMethods:   getEnvList("TODO_CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+				AllowedHeaders:   getEnvList("TODO_CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Idempotency-Key", "Last-Event-ID", "X-Request-ID", "X-Share-Password"}),
+				AllowCredentials: getEnvBool("TODO_CORS_ALLOW_CREDENTIALS", false),
+				MaxAge:           getEnvDuration("TODO_CORS_MAX_AGE", 10*time.Minute),
+			},
+		},
+		TLS: TLSConfig{
+			CertFile:       getEnv("TODO_TLS_CERT_FILE", ""),
+			KeyFile:        getEnv("TODO_TLS_KEY_FILE", ""),
+			MinVersion:     getEnv("TODO_TLS_MIN_VERSION", "1.2"),
+			CipherSuites:   getEnvList("TODO_TLS_CIPHER_SUITES", nil),
+			ClientCAFile:   getEnv("TODO_TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:     getEnv("TODO_TLS_CLIENT_AUTH", ""),
+			SelfSigned:     getEnvBool("TODO_TLS_SELF_SIGNED", false),
+			ReloadInterval: getEnvDuration("TODO_TLS_RELOAD_INTERVAL", 10*time.Second),
+		},
+		Database: getDatabaseConfig(databasePath),
+		Storage: StorageConfig{
+			BlobDir:           getEnv("TODO_BLOB_DIR", filepath.Join("data", "blobs")),
+			MaxAttachmentSize: getEnvInt64("TODO_MAX_ATTACHMENT_SIZE", 10<<20),
+			AllowedAttachmentTypes: getEnvList("TODO_ALLOWED_ATTACHMENT_TYPES", []string{
+				"image/png",
+				"image/jpeg",
+				"image/gif",
+				"image/webp",
+				"application/pdf",
+				"text/plain",
+			}),
+			GCInterval: getEnvDuration("TODO_BLOB_GC_INTERVAL", time.Hour),
+		},
+		Webhooks: WebhookConfig{
+			PollInterval:   getEnvPositiveDuration("TODO_WEBHOOK_POLL_INTERVAL", 2*time.Second),
+			Timeout:        getEnvPositiveDuration("TODO_WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:    int(getEnvInt64("TODO_WEBHOOK_MAX_ATTEMPTS", 8)),
+			InitialBackoff: getEnvPositiveDuration("TODO_WEBHOOK_INITIAL_BACKOFF", 10*time.Second),
+			MaxBackoff:     getEnvPositiveDuration("TODO_WEBHOOK_MAX_BACKOFF", time.Hour),
+			BatchSize:      50,
+		},
+		Stream: StreamConfig{
+			LogSize:           int(getEnvInt64("TODO_STREAM_LOG_SIZE", 1000)),
+			BufferSize:        64,
+			HeartbeatInterval: getEnvPositiveDuration("TODO_STREAM_HEARTBEAT", 15*time.Second),
+		},
+		Collab: CollabConfig{
+			LockTTL: getEnvDuration("TODO_EDIT_LOCK_TTL", 30*time.Second),
+		},
+		GRPC: GRPCConfig{
+			Addr: getEnv("TODO_GRPC_ADDR", ":9090"),
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      int(getEnvInt64("TODO_GRAPHQL_MAX_DEPTH", 8)),
+			MaxComplexity: int(getEnvInt64("TODO_GRAPHQL_MAX_COMPLEXITY", 5000)),
+		},
+		Auth: AuthConfig{
+			Enabled:      getEnvBool("TODO_AUTH_ENABLED", true),
+			Registration: getEnvBool("TODO_AUTH_REGISTRATION", true),
+			SessionTTL:   getEnvDuration("TODO_SESSION_TTL", 30*24*time.Hour),
+			OIDC: OIDCConfig{
+				Issuer:        getEnv("TODO_OIDC_ISSUER", ""),
+				Audience:      getEnv("TODO_OIDC_AUDIENCE", ""),
+				JWKS:          getEnv("TODO_OIDC_JWKS_URL", getEnv("TODO_OIDC_JWKS_FILE", "")),
+				JWKSRefresh:   getEnvDuration("TODO_OIDC_JWKS_REFRESH", time.Hour),
+				RolesClaim:    getEnv("TODO_OIDC_ROLES_CLAIM", "roles"),
+				AdminRole:     getEnv("TODO_OIDC_ADMIN_ROLE", "admin"),
+				DefaultScopes: getEnvList("TODO_OIDC_DEFAULT_SCOPES", []string{"todos:read", "todos:write"}),
+				Leeway:        getEnvDuration("TODO_OIDC_LEEWAY", time.Minute),
+			},
+		},
+		Share: ShareConfig{
+			RateLimit: int(getEnvInt64("TODO_SHARE_RATE_LIMIT", 30)),
+		},
+		Metrics: MetricsConfig{
+			Addr: getEnv("TODO_METRICS_ADDR", ""),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TODO_TRACING_EXPORTER", "none"),
+			File:        getEnv("TODO_TRACING_FILE", filepath.Join("data", "traces.jsonl")),
+			Endpoint:    getEnv("TODO_TRACING_ENDPOINT", ""),
+			ServiceName: getEnv("TODO_TRACING_SERVICE_NAME", "todo-api"),
+			SampleRatio: getEnvFloat("TODO_TRACING_SAMPLE_RATIO", 1),
+		},
+		Log: LogConfig{
+			Level:  getEnv("TODO_LOG_LEVEL", "info"),
+			Format: getEnv("TODO_LOG_FORMAT", "json"),
+		},
+		Health: HealthConfig{
+			Timeout:         getEnvDuration("TODO_HEALTH_TIMEOUT", 2*time.Second),
+			ShutdownDelay:   getEnvDuration("TODO_SHUTDOWN_DELAY", 0),
+			ShutdownTimeout: getEnvPositiveDuration("TODO_SHUTDOWN_TIMEOUT", 15*time.Second),
+		},
+		Limits: LimitsConfig{
+			ReadsPerMinute:  int(getEnvInt64("TODO_RATE_LIMIT_READS", 600)),
+			WritesPerMinute: int(getEnvInt64("TODO_RATE_LIMIT_WRITES", 120)),
+			DailyTodos:      int(getEnvInt64("TODO_DAILY_TODO_QUOTA", 1000)),
 		},
 	}
 }
 
+// getDatabaseConfig opens the SQLite file at path unless TODO_DATABASE_URL
+// names another database. PostgreSQL has its own migrations.
+func getDatabaseConfig(path string) DatabaseConfig {
+	cfg := DatabaseConfig{
+		Path: path,
+		DSN:  getEnv("TODO_DATABASE_URL", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"),
+	}
+	
+	migrationsDir := "migrations"
+	if cfg.Postgres() {
+		migrationsDir = filepath.Join("migrations", "postgres")
+	}
+	cfg.MigrationsDir = getEnv("TODO_MIGRATIONS_DIR", migrationsDir)
+	
+	return cfg
+}
+
 func getDatabasePath() string {
+	if path := os.Getenv("TODO_DB_PATH"); path != "" {
+		return path
+	}
+	
 	dataDir := "data"
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return "todos.db"
@@ -29,3 +377,66 @@ func getDatabasePath() string {
 	
 	return filepath.Join(dataDir, "todos.db")
 }
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvPositiveDuration is getEnvDuration for settings that must be
+// positive, such as ticker intervals. Zero and negative values fall back.
+func getEnvPositiveDuration(key string, fallback time.Duration) time.Duration {
+	if value := getEnvDuration(key, fallback); value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func getEnvList(key string, fallback []string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -1,22 +1,48 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"todo-api/internal/config"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
 )
 
+// SQL dialects a DB speaks, chosen by the scheme of its DSN.
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+// PostgreSQL error codes of constraint failures.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
 type DB struct {
 	*sql.DB
+	// Dialect is SQLite or Postgres.
+	Dialect string
 }
 
 func NewConnection(cfg *config.DatabaseConfig) (*DB, error) {
-	db, err := sql.Open("sqlite", cfg.DSN)
+	driver, dialect, name := "sqlite", SQLite, cfg.Path
+	if cfg.Postgres() {
+		driver, dialect, name = "pgx", Postgres, redact(cfg.DSN)
+	}
+
+	db, err := sql.Open(driver, cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -29,19 +55,133 @@ func NewConnection(cfg *config.DatabaseConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Printf("Database connected successfully: %s", cfg.DSN)
-	return &DB{DB: db}, nil
+	slog.Info("Database connected successfully", "dialect", dialect, "path", name)
+	return &DB{DB: db, Dialect: dialect}, nil
+}
+
+// Check pings the database and reads its schema, which fails while another
+// process holds the file locked.
+func (db *DB) Check(ctx context.Context) error {
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	query := `SELECT COUNT(*) FROM sqlite_master`
+	if db.Dialect == Postgres {
+		query = `SELECT COUNT(*) FROM pg_catalog.pg_tables`
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx, query).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to read database schema: %w", err)
+	}
+
+	return nil
 }
 
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
+// WithTx runs fn inside a transaction, committing when fn returns nil and
+// rolling back otherwise.
+func (db *DB) WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 func (db *DB) RunMigration(migrationSQL string) error {
 	_, err := db.Exec(migrationSQL)
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
-	log.Println("Migration executed successfully")
+	slog.Info("Migration executed successfully")
 	return nil
 }
+
+// Rebind rewrites the ? placeholders of a query written for both dialects
+// as $1, $2, ... for PostgreSQL. Question marks inside string literals,
+// quoted identifiers and comments are left alone. Queries for SQLite are
+// returned as they are.
+func (db *DB) Rebind(query string) string {
+	if db.Dialect != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// IsUniqueViolation reports whether err is a unique or primary key
+// constraint failure, in either dialect.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key constraint
+// failure, in either dialect.
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgForeignKeyViolation
+	}
+	return err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed")
+}
+
+// redact hides the password of a database URL for logging.
+func redact(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "(unparseable URL)"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"todo-api/internal/database"
+)
+
+func TestRebind(t *testing.T) {
+	postgres := &database.DB{Dialect: database.Postgres}
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{`SELECT id FROM todos WHERE id = ? AND owner_id = ?`, `SELECT id FROM todos WHERE id = $1 AND owner_id = $2`},
+		{`SELECT '?', "a?b" FROM t WHERE x = ?`, `SELECT '?', "a?b" FROM t WHERE x = $1`},
+		{`SELECT 'it''s?' WHERE x = ?`, `SELECT 'it''s?' WHERE x = $1`},
+		{"SELECT 1 -- why?\nWHERE x = ?", "SELECT 1 -- why?\nWHERE x = $1"},
+		{`SELECT /* a? */ ? /* b?`, `SELECT /* a? */ $1 /* b?`},
+		{`SELECT 'open ?`, `SELECT 'open ?`},
+	} {
+		if got := postgres.Rebind(tc.query); got != tc.want {
+			t.Errorf("Rebind(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+
+	sqlite := &database.DB{Dialect: database.SQLite}
+	if query := `SELECT ? WHERE x = ?`; sqlite.Rebind(query) != query {
+		t.Errorf("SQLite Rebind(%q) = %q, want it unchanged", query, sqlite.Rebind(query))
+	}
+}
+
+func TestConstraintViolations(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		err        error
+		unique, fk bool
+	}{
+		{"SQLite unique", errors.New("constraint failed: UNIQUE constraint failed: users.email (2067)"), true, false},
+		{"SQLite foreign key", errors.New("constraint failed: FOREIGN KEY constraint failed (787)"), false, true},
+		{"PostgreSQL unique", &pgconn.PgError{Code: "23505"}, true, false},
+		{"PostgreSQL foreign key", &pgconn.PgError{Code: "23503"}, false, true},
+		{"other", errors.New("disk I/O error"), false, false},
+		{"none", nil, false, false},
+	} {
+		if got := database.IsUniqueViolation(tc.err); got != tc.unique {
+			t.Errorf("%s: IsUniqueViolation = %v, want %v", tc.name, got, tc.unique)
+		}
+		if got := database.IsForeignKeyViolation(tc.err); got != tc.fk {
+			t.Errorf("%s: IsForeignKeyViolation = %v, want %v", tc.name, got, tc.fk)
+		}
+	}
+}
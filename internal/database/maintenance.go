@@ -0,0 +1,169 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Backup writes a consistent copy of the database to path. It uses VACUUM
+// INTO, so it is safe while the server is running. path must not exist.
+func (db *DB) Backup(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file %s already exists", path)
+	}
+
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	return nil
+}
+
+// Vacuum rebuilds the database file, returning free pages to the file system.
+func (db *DB) Vacuum() error {
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuum failed: %w", err)
+	}
+	return nil
+}
+
+// IntegrityCheck runs SQLite's integrity_check and foreign_key_check and
+// returns the problems they report. No problems means the database is
+// consistent.
+func (db *DB) IntegrityCheck() ([]string, error) {
+	return integrityCheck(db.DB)
+}
+
+func integrityCheck(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("integrity check failed: %w", err)
+	}
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("integrity check failed: %w", err)
+	}
+
+	rows, err = db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("foreign key check failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var foreignKey int64
+		if err := rows.Scan(&table, &rowID, &parent, &foreignKey); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key check: %w", err)
+		}
+		problems = append(problems, fmt.Sprintf("row %d of %s references a missing row of %s", rowID.Int64, table, parent))
+	}
+
+	return problems, rows.Err()
+}
+
+// Restore replaces the database at dbPath with the backup at backupPath,
+// after checking the backup's integrity. The replaced database is kept and
+// its new path returned, empty when there was none. Nothing may have dbPath
+// open while it is restored.
+func Restore(backupPath, dbPath string) (string, error) {
+	if err := checkBackup(backupPath); err != nil {
+		return "", err
+	}
+
+	// A journal next to the database would be replayed into the restored
+	// file, so refuse while one exists.
+	for _, suffix := range []string{"-journal", "-wal"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil {
+			return "", fmt.Errorf("%s%s exists; stop the server before restoring", dbPath, suffix)
+		}
+	}
+
+	tmp := dbPath + ".restore"
+	if err := copyFile(backupPath, tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to copy backup: %w", err)
+	}
+
+	kept := ""
+	if _, err := os.Stat(dbPath); err == nil {
+		kept = fmt.Sprintf("%s.%s.bak", dbPath, time.Now().UTC().Format("20060102T150405Z"))
+		if err := os.Rename(dbPath, kept); err != nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("failed to move current database aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return kept, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+
+	return kept, nil
+}
+
+// checkBackup opens the backup read-only and verifies its integrity.
+func checkBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("backup file: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer db.Close()
+
+	problems, err := integrityCheck(db)
+	if err != nil {
+		return fmt.Errorf("backup %s is not a usable database: %w", path, err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup %s failed its integrity check: %s", path, problems[0])
+	}
+
+	var migrations int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&migrations); err != nil {
+		return fmt.Errorf("backup %s is not a todo database: %w", path, err)
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
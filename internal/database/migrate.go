@@ -0,0 +1,254 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// downSuffix marks the file that reverts the migration of the same name,
+// such as 001_create_todos_table.down.sql.
+const downSuffix = ".down.sql"
+
+// Migration describes a migration and whether it has been applied.
+type Migration struct {
+	Version   string
+	Applied   bool
+	AppliedAt *time.Time
+	// Reversible is set when a down file exists for the migration.
+	Reversible bool
+	// Missing is set when the migration is recorded as applied but its
+	// file is no longer in the migrations directory.
+	Missing bool
+}
+
+// Migrate applies every migration file in dir that has not been recorded
+// in schema_migrations yet, in lexical order.
+func (db *DB) Migrate(dir string) error {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return err
+	}
+
+	files, err := migrationFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".sql")
+		if applied[version] {
+			continue
+		}
+
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+
+		if err := db.applyMigration(version, string(migrationSQL)); err != nil {
+			return err
+		}
+		slog.Info("Migration applied", "version", version)
+	}
+
+	return nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, by
+// running their down files.
+func (db *DB) MigrateDown(dir string, steps int) error {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(db.Rebind(`SELECT version FROM schema_migrations ORDER BY version DESC LIMIT ?`), steps)
+	if err != nil {
+		return fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	var versions []string
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	for _, version := range versions {
+		downSQL, err := os.ReadFile(filepath.Join(dir, version+downSuffix))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("migration %s has no down migration", version)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read down migration %s: %w", version, err)
+		}
+
+		if err := db.revertMigration(version, string(downSQL)); err != nil {
+			return err
+		}
+		slog.Info("Migration reverted", "version", version)
+	}
+
+	return nil
+}
+
+// MigrationStatus lists the migrations in dir and those recorded as
+// applied, in version order.
+func (db *DB) MigrationStatus(dir string) ([]Migration, error) {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	files, err := migrationFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[string]*Migration)
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".sql")
+		_, err := os.Stat(filepath.Join(dir, version+downSuffix))
+		byVersion[version] = &Migration{Version: version, Reversible: err == nil}
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Missing: true}
+			byVersion[version] = migration
+		}
+		migration.Applied = true
+		migration.AppliedAt = &appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (db *DB) ensureMigrationsTable() error {
+	timestamp := "DATETIME"
+	if db.Dialect == Postgres {
+		timestamp = "TIMESTAMPTZ"
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at ` + timestamp + ` DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) applyMigration(version, migrationSQL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrationSQL); err != nil {
+		return fmt.Errorf("migration %s failed: %w", version, err)
+	}
+
+	if _, err := tx.Exec(db.Rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", version, err)
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) revertMigration(version, downSQL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin reverting migration %s: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(downSQL); err != nil {
+		return fmt.Errorf("reverting migration %s failed: %w", version, err)
+	}
+
+	if _, err := tx.Exec(db.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %s: %w", version, err)
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) appliedMigrations() (map[string]bool, error) {
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// migrationFiles returns the up migrations in dir, in lexical order.
+func migrationFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var files []string
+	for _, file := range matches {
+		if !strings.HasSuffix(file, downSuffix) {
+			files = append(files, file)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
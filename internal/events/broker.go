@@ -0,0 +1,163 @@
+package events
+
+import "sync"
+
+// Publisher receives events once the change they describe is committed.
+type Publisher interface {
+	Publish(event Event) Event
+}
+
+// Filter selects the events a subscriber is interested in. A nil Filter
+// accepts every event.
+type Filter func(event Event) bool
+
+// Subscription delivers events published after it was created. C is closed
+// when the subscriber falls too far behind or the broker is closed; the
+// subscriber should then resume from the last sequence it saw.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter Filter
+}
+
+// Broker is an in-process broadcaster. Every published event gets a
+// sequence number and is kept in a bounded log so that reconnecting
+// subscribers can resume where they stopped.
+type Broker struct {
+	mu         sync.Mutex
+	seq        uint64
+	log        []Event
+	logSize    int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+	closed     bool
+}
+
+func NewBroker(logSize, bufferSize int) *Broker {
+	return &Broker{
+		logSize:    logSize,
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next sequence number to event, records it in the log
+// and fans it out without blocking. Subscribers whose buffer is full are
+// dropped.
+func (b *Broker) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.Seq = b.seq
+
+	b.log = append(b.log, event)
+	if len(b.log) > b.logSize {
+		b.log = b.log[len(b.log)-b.logSize:]
+	}
+
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+
+	return event
+}
+
+// Subscribe registers a subscriber and returns the logged events after
+// lastSeq that match filter. A lastSeq of 0 subscribes to new events only.
+// complete is false when events after lastSeq have already left the log or
+// lastSeq is unknown, so the subscriber may have missed some of them.
+func (b *Broker) Subscribe(lastSeq uint64, filter Filter) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, b.bufferSize)
+	sub = &Subscription{C: ch, ch: ch, filter: filter}
+	if b.closed {
+		close(ch)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastSeq == 0 {
+		return sub, nil, true
+	}
+
+	switch {
+	case lastSeq > b.seq:
+		// The sequence comes from before a restart.
+		complete = false
+	case lastSeq < b.seq:
+		complete = len(b.log) > 0 && b.log[0].Seq <= lastSeq+1
+	default:
+		complete = true
+	}
+
+	for _, event := range b.log {
+		if event.Seq <= lastSeq {
+			continue
+		}
+		if filter == nil || filter(event) {
+			backlog = append(backlog, event)
+		}
+	}
+
+	return sub, backlog, complete
+}
+
+// Unsubscribe stops delivery to sub and closes its channel.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.drop(sub)
+}
+
+// Close ends every subscription. Events published afterwards are only logged.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+	b.closed = true
+}
+
+// SeqOf returns the sequence number of the logged event with id, such as
+// the id a webhook delivery carried. ok is false once the event has left
+// the log.
+func (b *Broker) SeqOf(id string) (seq uint64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := len(b.log) - 1; i >= 0; i-- {
+		if b.log[i].ID == id {
+			return b.log[i].Seq, true
+		}
+	}
+	return 0, false
+}
+
+// LastSeq returns the sequence number of the most recent event.
+func (b *Broker) LastSeq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seq
+}
+
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
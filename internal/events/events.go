@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// Type identifies what happened to a todo.
+type Type string
+
+const (
+	TodoCreated   Type = "todo.created"
+	TodoUpdated   Type = "todo.updated"
+	TodoDeleted   Type = "todo.deleted"
+	TodoCompleted Type = "todo.completed"
+)
+
+// Types lists every event type in a stable order.
+var Types = []Type{TodoCreated, TodoUpdated, TodoDeleted, TodoCompleted}
+
+// Valid reports whether t is a known event type.
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event describes a change to a todo. Todo holds the state after the change,
+// or the last known state for deletions. Seq is assigned by the Broker.
+type Event struct {
+	ID         string       `json:"id" example:"5f2b9c1e8a7d4e3f9b0c1d2e3f4a5b6c"`
+	Seq        uint64       `json:"seq,omitempty" example:"42"`
+	Type       Type         `json:"type" example:"todo.created"`
+	TodoID     int64        `json:"todo_id" example:"1"`
+	Todo       *models.Todo `json:"data,omitempty"`
+	OccurredAt time.Time    `json:"occurred_at" example:"2026-02-16T09:00:00Z"`
+	// Grantees are the users the todo was shared with when the event was
+	// published. They see the event besides the todo's owner.
+	Grantees []int64 `json:"-"`
+}
+
+// Viewers lists the users who may see the event: the todo's owner, if it
+// has one, and its grantees.
+func (e Event) Viewers() []int64 {
+	viewers := make([]int64, 0, len(e.Grantees)+1)
+	if e.Todo != nil && e.Todo.OwnerID != nil {
+		viewers = append(viewers, *e.Todo.OwnerID)
+	}
+	return append(viewers, e.Grantees...)
+}
+
+// New builds an event with a fresh random ID.
+func New(eventType Type, todo *models.Todo) Event {
+	snapshot := *todo
+	return Event{
+		ID:         newID(),
+		Type:       eventType,
+		TodoID:     todo.ID,
+		Todo:       &snapshot,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type recorderKey struct{}
+
+// Recorder collects the events a todo write produces. The writer hands them
+// to its TxHooks and records them, so that the events published once the
+// write commits carry the same ids as those stored in the webhook outbox.
+type Recorder struct {
+	events []Event
+}
+
+// WithRecorder returns a context in which writes record their events in the
+// returned Recorder.
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	recorder := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, recorder), recorder
+}
+
+// Record adds event to the Recorder of ctx. It does nothing when ctx has
+// none.
+func Record(ctx context.Context, event Event) {
+	if recorder, ok := ctx.Value(recorderKey{}).(*Recorder); ok {
+		recorder.events = append(recorder.events, event)
+	}
+}
+
+// Events returns the recorded events in the order they were produced.
+func (r *Recorder) Events() []Event {
+	return r.events
+}
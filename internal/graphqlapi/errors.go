@@ -0,0 +1,52 @@
+package graphqlapi
+
+import (
+	"errors"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// Error codes reported in the "extensions" of a GraphQL error.
+const (
+	CodeNotFound           = "NOT_FOUND"
+	CodeBadUserInput       = "BAD_USER_INPUT"
+	CodeQueryTooComplex    = "QUERY_TOO_COMPLEX"
+	CodeInternalError      = "INTERNAL_SERVER_ERROR"
+	CodeOperationForbidden = "OPERATION_NOT_ALLOWED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeQuotaExceeded      = "QUOTA_EXCEEDED"
+)
+
+// errMutationScope rejects mutations from callers limited to reading.
+var errMutationScope = &Error{Message: "mutations require the " + models.ScopeTodosWrite + " scope", Code: CodeForbidden}
+
+// Error is a resolver error carrying a machine-readable code.
+type Error struct {
+	Message string
+	Code    string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// toError maps a service error to a GraphQL error the way the REST handlers
+// map it to an HTTP status.
+func toError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		return &Error{Message: err.Error(), Code: CodeNotFound}
+	case errors.Is(err, services.ErrInvalidInput):
+		return &Error{Message: err.Error(), Code: CodeBadUserInput}
+	case errors.Is(err, services.ErrForbidden):
+		return &Error{Message: err.Error(), Code: CodeForbidden}
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return &Error{Message: err.Error(), Code: CodeQuotaExceeded}
+	default:
+		return &Error{Message: err.Error(), Code: CodeInternalError}
+	}
+}
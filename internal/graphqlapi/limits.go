@@ -0,0 +1,145 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"todo-api/internal/services"
+)
+
+// Limits bounds the shape of accepted operations before they reach the
+// database. A zero value disables the corresponding check.
+type Limits struct {
+	// MaxDepth is the deepest allowed field nesting; root fields are at 1.
+	MaxDepth int
+	// MaxComplexity caps the estimated number of resolved fields. Every
+	// field costs 1 and the fields below a list count once per expected item.
+	MaxComplexity int
+}
+
+// listSizes is the item count assumed for list fields when the operation
+// does not pass a limit argument.
+var listSizes = map[string]int{
+	"todos":       services.DefaultListLimit,
+	"comments":    services.DefaultListLimit,
+	"attachments": 10,
+}
+
+type measure struct {
+	depth int
+	cost  int
+}
+
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	measured  map[string]measure
+}
+
+// check measures op and reports an error when it exceeds the limits.
+// Introspection fields are not counted. The document must be validated,
+// which rules out fragment cycles.
+func (l Limits) check(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}) error {
+	a := &analyzer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		measured:  make(map[string]measure),
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			a.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	m := a.selectionSet(op.SelectionSet)
+	if l.MaxDepth > 0 && m.depth > l.MaxDepth {
+		return &Error{
+			Message: fmt.Sprintf("query depth %d exceeds the maximum of %d", m.depth, l.MaxDepth),
+			Code:    CodeQueryTooComplex,
+		}
+	}
+	if l.MaxComplexity > 0 && m.cost > l.MaxComplexity {
+		return &Error{
+			Message: fmt.Sprintf("query complexity %d exceeds the maximum of %d", m.cost, l.MaxComplexity),
+			Code:    CodeQueryTooComplex,
+		}
+	}
+
+	return nil
+}
+
+func (a *analyzer) selectionSet(set *ast.SelectionSet) measure {
+	var total measure
+	if set == nil {
+		return total
+	}
+
+	for _, selection := range set.Selections {
+		var m measure
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			children := a.selectionSet(selection.SelectionSet)
+			m = measure{depth: children.depth + 1, cost: 1 + a.listSize(selection)*children.cost}
+		case *ast.InlineFragment:
+			m = a.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			m = a.fragment(selection.Name.Value)
+		}
+
+		total.depth = max(total.depth, m.depth)
+		total.cost += m.cost
+	}
+
+	return total
+}
+
+func (a *analyzer) fragment(name string) measure {
+	if m, ok := a.measured[name]; ok {
+		return m
+	}
+
+	var m measure
+	if fragment := a.fragments[name]; fragment != nil {
+		m = a.selectionSet(fragment.SelectionSet)
+	}
+	a.measured[name] = m
+	return m
+}
+
+// listSize is the number of items a field is expected to return: its limit
+// argument when given, otherwise the default for known list fields.
+func (a *analyzer) listSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := a.variables[value.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+	}
+
+	if size, ok := listSizes[field.Name.Value]; ok {
+		return size
+	}
+	return 1
+}
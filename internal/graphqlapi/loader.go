@@ -0,0 +1,120 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"todo-api/internal/models"
+)
+
+// loader batches lookups by key. Resolvers call Load for every parent
+// object and return the thunk; the executor runs thunks level by level, so
+// the first thunk to run fetches every key requested at that level with a
+// single call. Results are not cached beyond the batch, which keeps
+// subscription payloads fresh.
+type loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	seen    map[K]bool
+	done    bool
+	results map[K]V
+	err     error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch}
+}
+
+// Load queues key for the next batch and returns a thunk yielding its value.
+func (l *loader[K, V]) Load(key K) func() (V, error) {
+	l.mu.Lock()
+	b := l.pending
+	if b == nil {
+		b = &batch[K, V]{seen: make(map[K]bool)}
+		l.pending = b
+	}
+	if !b.seen[key] {
+		b.seen[key] = true
+		b.keys = append(b.keys, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if !b.done {
+			if l.pending == b {
+				l.pending = nil
+			}
+			b.results, b.err = l.fetch(b.keys)
+			b.done = true
+		}
+
+		return b.results[key], b.err
+	}
+}
+
+// loaders holds the batch loaders of a single request.
+type loaders struct {
+	todo        *loader[int64, *models.Todo]
+	comments    *loader[commentsKey, []models.Comment]
+	attachments *loader[int64, []models.Attachment]
+}
+
+// commentsKey identifies the comments of a todo loaded with a given limit,
+// so fields asking for different limits are batched separately.
+type commentsKey struct {
+	todoID int64
+	limit  int
+}
+
+type loadersKey struct{}
+
+func (s *Server) withLoaders(ctx context.Context) context.Context {
+	l := &loaders{
+		todo: newLoader(func(ids []int64) (map[int64]*models.Todo, error) {
+			todos, err := s.todos.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byID := make(map[int64]*models.Todo, len(todos))
+			for i := range todos {
+				byID[todos[i].ID] = &todos[i]
+			}
+			return byID, nil
+		}),
+		comments: newLoader(func(keys []commentsKey) (map[commentsKey][]models.Comment, error) {
+			byLimit := make(map[int][]int64)
+			for _, key := range keys {
+				byLimit[key.limit] = append(byLimit[key.limit], key.todoID)
+			}
+
+			results := make(map[commentsKey][]models.Comment, len(keys))
+			for limit, todoIDs := range byLimit {
+				comments, err := s.comments.ListByTodoIDs(todoIDs, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, todoID := range todoIDs {
+					results[commentsKey{todoID: todoID, limit: limit}] = comments[todoID]
+				}
+			}
+			return results, nil
+		}),
+		attachments: newLoader(s.attachments.ListByTodoIDs),
+	}
+
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,177 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+func (s *Server) resolveTodos(p graphql.ResolveParams) (interface{}, error) {
+	filter := models.TodoFilter{
+		Limit:  p.Args["limit"].(int),
+		Offset: p.Args["offset"].(int),
+	}
+	if completed, ok := p.Args["completed"].(bool); ok {
+		filter.Completed = &completed
+	}
+	if query, ok := p.Args["query"].(string); ok {
+		filter.Query = query
+	}
+
+	todos, total, err := s.todos.List(p.Context, filter)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	items := make([]*models.Todo, len(todos))
+	for i := range todos {
+		items[i] = &todos[i]
+	}
+
+	return map[string]interface{}{"items": items, "total": total}, nil
+}
+
+func (s *Server) resolveCreateTodo(p graphql.ResolveParams) (interface{}, error) {
+	todo := todoFromInput(p.Args["input"])
+	if err := s.todos.Create(p.Context, todo); err != nil {
+		return nil, toError(err)
+	}
+
+	return todo, nil
+}
+
+func (s *Server) resolveUpdateTodo(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	todo := todoFromInput(p.Args["input"])
+	todo.ID = id
+	if err := s.todos.Update(p.Context, todo); err != nil {
+		return nil, toError(err)
+	}
+
+	return todo, nil
+}
+
+func (s *Server) resolveDeleteTodo(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.todos.Delete(p.Context, id); err != nil {
+		return nil, toError(err)
+	}
+
+	return true, nil
+}
+
+func (s *Server) resolveCreateComment(p graphql.ResolveParams) (interface{}, error) {
+	todoID, err := parseID(p.Args["todoId"])
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]interface{})
+	comment := &models.Comment{
+		TodoID: todoID,
+		Body:   input["body"].(string),
+	}
+	if raw, ok := input["parentCommentId"]; ok && raw != nil {
+		parentID, err := parseID(raw)
+		if err != nil {
+			return nil, err
+		}
+		comment.ParentCommentID = &parentID
+	}
+
+	if err := s.comments.Create(p.Context, comment); err != nil {
+		return nil, toError(err)
+	}
+
+	return *comment, nil
+}
+
+// subscribeTodoChanged feeds broker events matching the arguments to the
+// subscription until its context ends.
+func (s *Server) subscribeTodoChanged(p graphql.ResolveParams) (interface{}, error) {
+	var types map[events.Type]bool
+	if raw, ok := p.Args["types"].([]interface{}); ok && len(raw) > 0 {
+		types = make(map[events.Type]bool)
+		for _, name := range raw {
+			eventType := events.Type(name.(string))
+			if !eventType.Valid() {
+				return nil, &Error{Message: fmt.Sprintf("unknown event type: %s", eventType), Code: CodeBadUserInput}
+			}
+			types[eventType] = true
+		}
+	}
+
+	var todoID int64
+	if raw, ok := p.Args["todoId"]; ok && raw != nil {
+		id, err := parseID(raw)
+		if err != nil {
+			return nil, err
+		}
+		todoID = id
+	}
+
+	sub, _, _ := s.broker.Subscribe(0, auth.VisibleEvents(p.Context, func(event events.Event) bool {
+		if types != nil && !types[event.Type] {
+			return false
+		}
+		return todoID == 0 || event.TodoID == todoID
+	}))
+
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer s.broker.Unsubscribe(sub)
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				select {
+				case out <- event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func todoFromInput(raw interface{}) *models.Todo {
+	input := raw.(map[string]interface{})
+	todo := &models.Todo{Title: input["title"].(string)}
+	if description, ok := input["description"].(string); ok {
+		todo.Description = description
+	}
+	if completed, ok := input["completed"].(bool); ok {
+		todo.Completed = completed
+	}
+
+	return todo
+}
+
+func parseID(raw interface{}) (int64, error) {
+	id, err := strconv.ParseInt(fmt.Sprint(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, &Error{Message: fmt.Sprintf("invalid id: %v", raw), Code: CodeBadUserInput}
+	}
+
+	return id, nil
+}
@@ -0,0 +1,281 @@
+package graphqlapi
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+)
+
+func (s *Server) buildSchema() (graphql.Schema, error) {
+	todoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Todo",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"completed": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"description": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if todo := p.Source.(*models.Todo); todo.Description != "" {
+						return todo.Description, nil
+					}
+					return nil, nil
+				},
+			},
+			"commentCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"createdAt":    &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt":    &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	resolveTodo := func(todoID func(source interface{}) int64) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			load := loadersFrom(p.Context).todo.Load(todoID(p.Source))
+			return func() (interface{}, error) {
+				todo, err := load()
+				if err != nil {
+					return nil, toError(err)
+				}
+				return todo, nil
+			}, nil
+		}
+	}
+
+	commentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Comment",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"todoId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"parentCommentId": &graphql.Field{
+				Type: graphql.ID,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if parentID := p.Source.(models.Comment).ParentCommentID; parentID != nil {
+						return *parentID, nil
+					}
+					return nil, nil
+				},
+			},
+			"author": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"body":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"deleted": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Comment).DeletedAt != nil, nil
+				},
+			},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"editedAt":  &graphql.Field{Type: graphql.DateTime},
+			"todo": &graphql.Field{
+				Type: todoType,
+				Resolve: resolveTodo(func(source interface{}) int64 {
+					return source.(models.Comment).TodoID
+				}),
+			},
+		},
+	})
+
+	attachmentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Attachment",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"todoId":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"filename":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"contentType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"size":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"sha256":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"todo": &graphql.Field{
+				Type: todoType,
+				Resolve: resolveTodo(func(source interface{}) int64 {
+					return source.(models.Attachment).TodoID
+				}),
+			},
+		},
+	})
+
+	todoType.AddFieldConfig("comments", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType))),
+		Description: "The first comments of the todo in chronological order, including deleted ones whose body is hidden.",
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: services.DefaultListLimit},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit := p.Args["limit"].(int)
+			if limit < 1 || limit > services.MaxListLimit {
+				return nil, &Error{
+					Message: "limit must be a number between 1 and " + strconv.Itoa(services.MaxListLimit),
+					Code:    CodeBadUserInput,
+				}
+			}
+
+			load := loadersFrom(p.Context).comments.Load(commentsKey{todoID: p.Source.(*models.Todo).ID, limit: limit})
+			return func() (interface{}, error) {
+				comments, err := load()
+				if err != nil {
+					return nil, toError(err)
+				}
+				if comments == nil {
+					comments = []models.Comment{}
+				}
+				return comments, nil
+			}, nil
+		},
+	})
+
+	todoType.AddFieldConfig("attachments", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(attachmentType))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			load := loadersFrom(p.Context).attachments.Load(p.Source.(*models.Todo).ID)
+			return func() (interface{}, error) {
+				attachments, err := load()
+				if err != nil {
+					return nil, toError(err)
+				}
+				if attachments == nil {
+					attachments = []models.Attachment{}
+				}
+				return attachments, nil
+			}, nil
+		},
+	})
+
+	todoPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoPage",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType)))},
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoEvent",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"seq":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"type":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"todoId":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"occurredAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"todo": &graphql.Field{
+				Type:        todoType,
+				Description: "The todo after the change, or its last state when it was deleted.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if todo := p.Source.(events.Event).Todo; todo != nil {
+						return todo, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	todoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TodoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"completed":   &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		},
+	})
+
+	commentInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CommentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"body":            &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"parentCommentId": &graphql.InputObjectFieldConfig{Type: graphql.ID},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"todos": &graphql.Field{
+				Type:        graphql.NewNonNull(todoPageType),
+				Description: "A page of todos, newest first.",
+				Args: graphql.FieldConfigArgument{
+					"completed": &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"query":     &graphql.ArgumentConfig{Type: graphql.String, Description: "Substring matched against title and description."},
+					"limit":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: services.DefaultListLimit},
+					"offset":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: s.resolveTodos,
+			},
+			"todo": &graphql.Field{
+				Type: todoType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return resolveTodo(func(interface{}) int64 { return id })(p)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(todoInput)},
+				},
+				Resolve: s.resolveCreateTodo,
+			},
+			"updateTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(todoInput)},
+				},
+				Resolve: s.resolveUpdateTodo,
+			},
+			"deleteTodo": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.resolveDeleteTodo,
+			},
+			"createComment": &graphql.Field{
+				Type: graphql.NewNonNull(commentType),
+				Args: graphql.FieldConfigArgument{
+					"todoId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(commentInput)},
+				},
+				Resolve: s.resolveCreateComment,
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"todoChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(eventType),
+				Description: "Todo changes as they are committed, the same events sent over SSE and webhooks.",
+				Args: graphql.FieldConfigArgument{
+					"types":  &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"todoId": &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Subscribe: s.subscribeTodoChanged,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
@@ -0,0 +1,154 @@
+package graphqlapi
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+)
+
+// Server executes GraphQL operations over todos, their comments and
+// attachments. Relations are batch loaded and every operation is checked
+// against Limits before it runs.
+type Server struct {
+	schema      graphql.Schema
+	limits      Limits
+	todos       services.TodoService
+	comments    services.CommentService
+	attachments services.AttachmentService
+	broker      *events.Broker
+}
+
+// Request is a GraphQL request as sent over HTTP or in a WebSocket
+// subscribe message.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+func NewServer(todos services.TodoService, comments services.CommentService, attachments services.AttachmentService, broker *events.Broker, limits Limits) (*Server, error) {
+	s := &Server{
+		limits:      limits,
+		todos:       todos,
+		comments:    comments,
+		attachments: attachments,
+		broker:      broker,
+	}
+
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+
+	return s, nil
+}
+
+// Execute runs a query or mutation. Mutations are rejected unless
+// allowMutations is set, so that they cannot be triggered by a GET request.
+func (s *Server) Execute(ctx context.Context, req Request, allowMutations bool) *graphql.Result {
+	doc, op, result := s.prepare(req)
+	if result != nil {
+		return result
+	}
+
+	switch {
+	case op.Operation == ast.OperationTypeSubscription:
+		return errorResult(&Error{Message: "subscriptions require a WebSocket connection", Code: CodeOperationForbidden})
+	case op.Operation == ast.OperationTypeMutation && !allowMutations:
+		return errorResult(&Error{Message: "mutations must be sent with POST", Code: CodeOperationForbidden})
+	case op.Operation == ast.OperationTypeMutation && !auth.Allowed(ctx, models.ScopeTodosWrite):
+		return errorResult(errMutationScope)
+	}
+
+	return s.execute(ctx, doc, req)
+}
+
+// Subscribe runs any operation and returns its results. A subscription
+// yields one result per matching event until ctx ends; queries and
+// mutations yield a single result. The channel is closed when done and
+// must be drained.
+func (s *Server) Subscribe(ctx context.Context, req Request) <-chan *graphql.Result {
+	doc, op, result := s.prepare(req)
+	if result == nil && op.Operation == ast.OperationTypeMutation && !auth.Allowed(ctx, models.ScopeTodosWrite) {
+		result = errorResult(errMutationScope)
+	}
+	if result == nil && op.Operation != ast.OperationTypeSubscription {
+		result = s.execute(ctx, doc, req)
+	}
+	if result != nil {
+		results := make(chan *graphql.Result, 1)
+		results <- result
+		close(results)
+		return results
+	}
+
+	return graphql.ExecuteSubscription(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       s.withLoaders(ctx),
+	})
+}
+
+func (s *Server) execute(ctx context.Context, doc *ast.Document, req Request) *graphql.Result {
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       s.withLoaders(ctx),
+	})
+}
+
+// prepare parses, validates and measures a request. It returns a result
+// holding the errors when the request must not run.
+func (s *Server) prepare(req Request) (*ast.Document, *ast.OperationDefinition, *graphql.Result) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return nil, nil, &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&s.schema, doc, nil)
+	if !validation.IsValid {
+		return nil, nil, &graphql.Result{Errors: validation.Errors}
+	}
+
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		candidate, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if req.OperationName == "" && op != nil {
+			return nil, nil, errorResult(&Error{Message: "operationName is required when the document has several operations", Code: CodeBadUserInput})
+		}
+		if req.OperationName == "" || (candidate.Name != nil && candidate.Name.Value == req.OperationName) {
+			op = candidate
+		}
+	}
+	if op == nil {
+		return nil, nil, errorResult(&Error{Message: "unknown operation: " + req.OperationName, Code: CodeBadUserInput})
+	}
+
+	if err := s.limits.check(doc, op, req.Variables); err != nil {
+		return nil, nil, errorResult(err)
+	}
+
+	return doc, op, nil
+}
+
+func errorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(gqlerrors.NewError(err.Error(), nil, "", nil, nil, err))}}
+}
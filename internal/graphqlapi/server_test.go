@@ -0,0 +1,259 @@
+package graphqlapi_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/graphqlapi"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+	"todo-api/internal/storage"
+)
+
+// calls counts repository calls by "repository.Method".
+type calls struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func (c *calls) observe(repository, method string, _ time.Duration, _ error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count[repository+"."+method]++
+}
+
+func (c *calls) reset() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count := c.count
+	c.count = make(map[string]int)
+	return count
+}
+
+// newServer returns a server over a migrated SQLite database holding todos
+// todos with two comments and an attachment each, and the counter of its
+// repository calls, which starts at zero.
+func newServer(t *testing.T, todos int, limits graphqlapi.Limits) (*graphqlapi.Server, *calls) {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "todos.db")
+	db, err := database.NewConnection(&config.DatabaseConfig{
+		Path: path,
+		DSN:  path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+	})
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(filepath.Join("..", "..", "migrations")); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	blobs, err := storage.NewBlobStore(filepath.Join(dir, "blobs"), 1<<20)
+	if err != nil {
+		t.Fatalf("NewBlobStore: %v", err)
+	}
+
+	c := &calls{count: make(map[string]int)}
+	grantRepo := repositories.InstrumentGrantRepository(repositories.NewGrantRepository(db), c.observe)
+	todoRepo := repositories.InstrumentTodoRepository(repositories.NewTodoRepository(db), c.observe)
+	commentRepo := repositories.InstrumentCommentRepository(repositories.NewCommentRepository(db), c.observe)
+	attachmentRepo := repositories.InstrumentAttachmentRepository(repositories.NewAttachmentRepository(db), c.observe)
+	userRepo := repositories.InstrumentUserRepository(repositories.NewUserRepository(db), c.observe)
+
+	broker := events.NewBroker(10, 16)
+	t.Cleanup(broker.Close)
+
+	s, err := graphqlapi.NewServer(
+		services.NewTodoService(todoRepo, grantRepo, broker, 0),
+		services.NewCommentService(commentRepo, todoRepo, userRepo, grantRepo),
+		services.NewAttachmentService(attachmentRepo, todoRepo, grantRepo, blobs, nil),
+		broker,
+		limits,
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := range todos {
+		todo := &models.Todo{Title: fmt.Sprintf("Todo %d", i+1)}
+		if err := todoRepo.Create(ctx, todo); err != nil {
+			t.Fatalf("Create todo: %v", err)
+		}
+		for _, body := range []string{"First", "Second"} {
+			if err := commentRepo.Create(&models.Comment{TodoID: todo.ID, Author: "ada", Body: body}); err != nil {
+				t.Fatalf("Create comment: %v", err)
+			}
+		}
+		attachment := &models.Attachment{TodoID: todo.ID, Filename: "notes.txt", ContentType: "text/plain", Size: 1, SHA256: fmt.Sprintf("%064x", i)}
+		if err := attachmentRepo.Create(attachment); err != nil {
+			t.Fatalf("Create attachment: %v", err)
+		}
+	}
+
+	c.reset()
+	return s, c
+}
+
+// codes returns the extension codes of the errors of result.
+func codes(result *graphql.Result) []string {
+	var codes []string
+	for _, err := range result.Errors {
+		code, _ := err.Extensions["code"].(string)
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func TestRelationsAreBatchLoaded(t *testing.T) {
+	for _, todos := range []int{1, 10} {
+		t.Run(fmt.Sprintf("%d todos", todos), func(t *testing.T) {
+			s, c := newServer(t, todos, graphqlapi.Limits{})
+
+			result := s.Execute(context.Background(), graphqlapi.Request{Query: `{
+				todos {
+					total
+					items {
+						title
+						comments(limit: 5) { body todo { title } }
+						attachments { filename todo { id } }
+					}
+				}
+			}`}, false)
+			if len(result.Errors) > 0 {
+				t.Fatalf("Execute: %v", result.Errors)
+			}
+
+			items := result.Data.(map[string]interface{})["todos"].(map[string]interface{})["items"].([]interface{})
+			if len(items) != todos {
+				t.Fatalf("got %d todos, want %d", len(items), todos)
+			}
+			for _, item := range items {
+				todo := item.(map[string]interface{})
+				if comments := todo["comments"].([]interface{}); len(comments) != 2 {
+					t.Errorf("%s has %d comments, want 2", todo["title"], len(comments))
+				}
+				if attachments := todo["attachments"].([]interface{}); len(attachments) != 1 {
+					t.Errorf("%s has %d attachments, want 1", todo["title"], len(attachments))
+				}
+			}
+
+			// However many todos, each relation costs one call.
+			want := map[string]int{
+				"todo.List":               1,
+				"comment.GetByTodoIDs":    1,
+				"attachment.GetByTodoIDs": 1,
+				"todo.GetByIDs":           1,
+			}
+			if got := c.reset(); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("repository calls = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCommentLimitsAreBatchedSeparately(t *testing.T) {
+	s, c := newServer(t, 3, graphqlapi.Limits{})
+
+	result := s.Execute(context.Background(), graphqlapi.Request{Query: `{
+		todos { items { first: comments(limit: 1) { body } all: comments(limit: 10) { body } } }
+	}`}, false)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Execute: %v", result.Errors)
+	}
+
+	for _, item := range result.Data.(map[string]interface{})["todos"].(map[string]interface{})["items"].([]interface{}) {
+		todo := item.(map[string]interface{})
+		if first, all := len(todo["first"].([]interface{})), len(todo["all"].([]interface{})); first != 1 || all != 2 {
+			t.Errorf("got %d and %d comments, want 1 and 2", first, all)
+		}
+	}
+	if got := c.reset()["comment.GetByTodoIDs"]; got != 2 {
+		t.Errorf("comment.GetByTodoIDs called %d times, want once per limit", got)
+	}
+}
+
+func TestLimitsRejectOversizedQueries(t *testing.T) {
+	s, c := newServer(t, 1, graphqlapi.Limits{MaxDepth: 4, MaxComplexity: 500})
+
+	for _, tc := range []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      []string
+	}{
+		{
+			name:  "within the limits",
+			query: `{ todos(limit: 10) { items { title comments(limit: 5) { body } } } }`,
+		},
+		{
+			name:  "too deep",
+			query: `{ todos { items { comments { todo { title } } } } }`,
+			want:  []string{graphqlapi.CodeQueryTooComplex},
+		},
+		{
+			name:  "too deep through a fragment",
+			query: `{ todos { items { ...withComments } } } fragment withComments on Todo { comments { todo { title } } }`,
+			want:  []string{graphqlapi.CodeQueryTooComplex},
+		},
+		{
+			name:  "too complex",
+			query: `{ todos(limit: 100) { items { title comments(limit: 100) { body } } } }`,
+			want:  []string{graphqlapi.CodeQueryTooComplex},
+		},
+		{
+			name:      "too complex through a variable",
+			query:     `query($n: Int) { todos(limit: $n) { items { title comments(limit: $n) { body } } } }`,
+			variables: map[string]interface{}{"n": float64(100)},
+			want:      []string{graphqlapi.CodeQueryTooComplex},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := s.Execute(context.Background(), graphqlapi.Request{Query: tc.query, Variables: tc.variables}, false)
+			if got := codes(result); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Fatalf("error codes = %v (%v), want %v", got, result.Errors, tc.want)
+			}
+
+			calls := c.reset()
+			if tc.want != nil && len(calls) > 0 {
+				t.Errorf("rejected query reached the repositories: %v", calls)
+			}
+		})
+	}
+}
+
+func TestBadInputIsReported(t *testing.T) {
+	s, _ := newServer(t, 1, graphqlapi.Limits{})
+
+	for _, tc := range []struct {
+		name           string
+		query          string
+		allowMutations bool
+		want           string
+	}{
+		{"comment limit below one", `{ todos { items { comments(limit: 0) { body } } } }`, false, graphqlapi.CodeBadUserInput},
+		{"comment limit above the maximum", `{ todos { items { comments(limit: 101) { body } } } }`, false, graphqlapi.CodeBadUserInput},
+		{"todo limit above the maximum", `{ todos(limit: 500) { total } }`, false, graphqlapi.CodeBadUserInput},
+		{"malformed id", `{ todo(id: "one") { title } }`, false, graphqlapi.CodeBadUserInput},
+		{"invalid todo", `mutation { createTodo(input: {title: ""}) { id } }`, true, graphqlapi.CodeBadUserInput},
+		{"mutation without POST", `mutation { deleteTodo(id: "1") }`, false, graphqlapi.CodeOperationForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := s.Execute(context.Background(), graphqlapi.Request{Query: tc.query}, tc.allowMutations)
+			if got := codes(result); len(got) != 1 || got[0] != tc.want {
+				t.Errorf("error codes = %v (%v), want [%s]", got, result.Errors, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Subprotocol is the WebSocket subprotocol spoken by ServeWebSocket, as
+// implemented by the graphql-ws client library.
+const Subprotocol = "graphql-transport-ws"
+
+const (
+	wsInitTimeout    = 10 * time.Second
+	wsWriteWait      = 10 * time.Second
+	wsMaxMessageSize = 64 << 10
+)
+
+// Close codes defined by the graphql-transport-ws protocol.
+const (
+	closeBadRequest         = 4400
+	closeUnauthorized       = 4401
+	closeInitTimeout        = 4408
+	closeSubscriberExists   = 4409
+	closeTooManyInitRequest = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// ServeWebSocket runs a graphql-transport-ws connection until it is
+// closed. Every "subscribe" message starts an operation whose results are
+// sent as "next" messages followed by "complete". Operations run with the
+// values of ctx, such as the caller's principal.
+func (s *Server) ServeWebSocket(ctx context.Context, conn *websocket.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &wsConn{conn: conn, subs: make(map[string]context.CancelFunc)}
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	var acknowledged atomic.Bool
+	initTimer := time.AfterFunc(wsInitTimeout, func() {
+		if !acknowledged.Load() {
+			c.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				c.close(closeBadRequest, "Invalid message received")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if acknowledged.Swap(true) {
+				c.close(closeTooManyInitRequest, "Too many initialisation requests")
+				return
+			}
+			c.write(wsMessage{Type: "connection_ack"})
+		case "ping":
+			c.write(wsMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !acknowledged.Load() {
+				c.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+
+			var req Request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(closeBadRequest, "Invalid subscribe message")
+				return
+			}
+
+			if !c.start(ctx, s, msg.ID, req) {
+				c.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case "complete":
+			c.stop(msg.ID)
+		default:
+			c.close(closeBadRequest, "Unknown message type: "+msg.Type)
+			return
+		}
+	}
+}
+
+// start runs an operation under id. It reports false when id is in use.
+func (c *wsConn) start(ctx context.Context, s *Server, id string, req Request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.subs[id]; exists {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.subs[id] = cancel
+	c.wg.Add(1)
+
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		failed := false
+		first := true
+		for result := range s.Subscribe(ctx, req) {
+			// Keep draining after cancellation so the executor can finish.
+			if failed || ctx.Err() != nil {
+				continue
+			}
+
+			if first && result.Data == nil && result.HasErrors() {
+				payload, _ := json.Marshal(result.Errors)
+				c.write(wsMessage{ID: id, Type: "error", Payload: payload})
+				failed = true
+				continue
+			}
+			first = false
+
+			payload, _ := json.Marshal(result)
+			c.write(wsMessage{ID: id, Type: "next", Payload: payload})
+		}
+
+		c.mu.Lock()
+		_, active := c.subs[id]
+		delete(c.subs, id)
+		c.mu.Unlock()
+
+		if active && !failed {
+			c.write(wsMessage{ID: id, Type: "complete"})
+		}
+	}()
+
+	return true
+}
+
+// stop ends the operation the client completed. No "complete" is sent back.
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.subs[id]; ok {
+		delete(c.subs, id)
+		cancel()
+	}
+}
+
+func (c *wsConn) write(msg wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.conn.Close()
+	}
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+	c.conn.Close()
+}
@@ -0,0 +1,95 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	todov1 "todo-api/api/proto/todo/v1"
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+)
+
+// methodScopes is the scope each todo RPC requires. Methods of other
+// services, such as health checking and reflection, are open.
+var methodScopes = map[string]string{
+	todov1.TodoService_ListTodos_FullMethodName:  models.ScopeTodosRead,
+	todov1.TodoService_GetTodo_FullMethodName:    models.ScopeTodosRead,
+	todov1.TodoService_Watch_FullMethodName:      models.ScopeTodosRead,
+	todov1.TodoService_CreateTodo_FullMethodName: models.ScopeTodosWrite,
+	todov1.TodoService_UpdateTodo_FullMethodName: models.ScopeTodosWrite,
+	todov1.TodoService_DeleteTodo_FullMethodName: models.ScopeTodosWrite,
+}
+
+// authenticator checks the API key or JWT sent in the "authorization"
+// metadata as "Bearer <token>", like the REST API.
+type authenticator struct {
+	authn services.Authenticator
+}
+
+func (a *authenticator) authorize(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		if strings.HasPrefix(method, "/"+todov1.TodoService_ServiceDesc.ServiceName+"/") {
+			return nil, status.Error(codes.PermissionDenied, "method has no scope assigned")
+		}
+		return ctx, nil
+	}
+
+	var secret string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		scheme, token, found := strings.Cut(values[0], " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			secret = strings.TrimSpace(token)
+		}
+	}
+	if secret == "" {
+		return nil, status.Error(codes.Unauthenticated, "send an API key as a bearer token in the authorization metadata")
+	}
+
+	principal, err := a.authn.Authenticate(ctx, secret)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if !principal.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "this method requires the %s scope", scope)
+	}
+
+	return auth.NewContext(ctx, principal), nil
+}
+
+func (a *authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream carries the principal in its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
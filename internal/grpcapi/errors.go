@@ -0,0 +1,27 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// toStatus maps a service error to a gRPC status the way the REST handlers
+// map it to an HTTP status.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidInput):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,84 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/ratelimit"
+)
+
+// limiter takes a token for each todo RPC from the same read and write
+// buckets as the REST API, so a client has one budget across both. A nil
+// limiter leaves its RPCs unlimited.
+type limiter struct {
+	reads, writes *ratelimit.Limiter
+}
+
+// allow takes a token for method from the bucket of the caller of ctx. When
+// none is left, it returns a ResourceExhausted error and the retry-after
+// trailer to send with it.
+func (l *limiter) allow(ctx context.Context, method string) (metadata.MD, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return nil, nil
+	}
+
+	limiter := l.writes
+	if scope == models.ScopeTodosRead {
+		limiter = l.reads
+	}
+	if limiter == nil {
+		return nil, nil
+	}
+
+	result := limiter.Allow(clientKey(ctx))
+	if result.Allowed {
+		return nil, nil
+	}
+
+	retryAfter := strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))
+	return metadata.Pairs("retry-after", retryAfter), status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %s seconds", retryAfter)
+}
+
+func (l *limiter) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if trailer, err := l.allow(ctx, info.FullMethod); err != nil {
+		grpc.SetTrailer(ctx, trailer)
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *limiter) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if trailer, err := l.allow(ss.Context(), info.FullMethod); err != nil {
+		ss.SetTrailer(trailer)
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// clientKey names the bucket of the caller like the REST API does: its user
+// or API key, or else its IP address.
+func clientKey(ctx context.Context) string {
+	if key := auth.LimitKey(ctx); key != "" {
+		return key
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:" + host
+}
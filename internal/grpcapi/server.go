@@ -0,0 +1,60 @@
+package grpcapi
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	todov1 "todo-api/api/proto/todo/v1"
+	"todo-api/internal/events"
+	"todo-api/internal/ratelimit"
+	"todo-api/internal/services"
+)
+
+// Server is the gRPC counterpart of the REST API. It serves the todo
+// service, the standard health checking service and server reflection.
+type Server struct {
+	*grpc.Server
+	health *health.Server
+}
+
+// NewServer builds the gRPC server. Todo RPCs require an API key or JWT with
+// the matching scope unless authn is nil, and take a token from the reads
+// or writes limiter of the caller, as REST requests do. With a TLS
+// configuration it serves TLS, verifying client certificates as that
+// configuration says.
+func NewServer(service services.TodoService, broker *events.Broker, authn services.Authenticator, reads, writes *ratelimit.Limiter, tlsConfig *tls.Config) *Server {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if authn != nil {
+		a := &authenticator{authn: authn}
+		opts = append(opts, grpc.ChainUnaryInterceptor(a.unary), grpc.ChainStreamInterceptor(a.stream))
+	}
+	// Limits follow authentication, which identifies the caller.
+	l := &limiter{reads: reads, writes: writes}
+	opts = append(opts, grpc.ChainUnaryInterceptor(l.unary), grpc.ChainStreamInterceptor(l.stream))
+
+	s := &Server{
+		Server: grpc.NewServer(opts...),
+		health: health.NewServer(),
+	}
+
+	todov1.RegisterTodoServiceServer(s.Server, NewTodoServer(service, broker))
+	healthpb.RegisterHealthServer(s.Server, s.health)
+	reflection.Register(s.Server)
+
+	s.health.SetServingStatus(todov1.TodoService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s
+}
+
+// GracefulStop reports every service as not serving, then waits for
+// in-flight calls to finish.
+func (s *Server) GracefulStop() {
+	s.health.Shutdown()
+	s.Server.GracefulStop()
+}
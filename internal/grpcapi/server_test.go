@@ -0,0 +1,303 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	todov1 "todo-api/api/proto/todo/v1"
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/grpcapi"
+	"todo-api/internal/models"
+	"todo-api/internal/ratelimit"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// todoService answers every call with err, or with a todo when err is nil.
+type todoService struct {
+	services.TodoService
+	err error
+}
+
+func (s *todoService) GetByID(_ context.Context, id int64) (*models.Todo, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.Todo{ID: id, Title: "Buy groceries"}, nil
+}
+
+func (s *todoService) Create(_ context.Context, todo *models.Todo) error {
+	todo.ID = 1
+	return s.err
+}
+
+// authenticator knows the principals of a few tokens.
+type authenticator map[string]*auth.Principal
+
+func (a authenticator) Authenticate(_ context.Context, token string) (*auth.Principal, error) {
+	if p, ok := a[token]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown token: %w", services.ErrUnauthorized)
+}
+
+// server configures startServer.
+type server struct {
+	service       services.TodoService
+	broker        *events.Broker
+	authn         services.Authenticator
+	reads, writes *ratelimit.Limiter
+}
+
+// startServer serves s over an in-memory connection and returns a client
+// connection to it.
+func startServer(t *testing.T, s server) *grpc.ClientConn {
+	t.Helper()
+
+	if s.service == nil {
+		s.service = &todoService{}
+	}
+	if s.broker == nil {
+		s.broker = events.NewBroker(10, 16)
+	}
+	t.Cleanup(s.broker.Close)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcapi.NewServer(s.service, s.broker, s.authn, s.reads, s.writes, nil)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// withToken returns a context sending token as a bearer token.
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestServiceErrorsMapToStatusCodes(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want codes.Code
+	}{
+		{nil, codes.OK},
+		{fmt.Errorf("todo with id 1 %w", repositories.ErrNotFound), codes.NotFound},
+		{fmt.Errorf("title is required: %w", services.ErrInvalidInput), codes.InvalidArgument},
+		{fmt.Errorf("edit requires the editor role: %w", services.ErrForbidden), codes.PermissionDenied},
+		{fmt.Errorf("100 todos today: %w", services.ErrQuotaExceeded), codes.ResourceExhausted},
+		{errors.New("disk I/O error"), codes.Internal},
+	} {
+		client := todov1.NewTodoServiceClient(startServer(t, server{service: &todoService{err: tc.err}}))
+
+		_, err := client.GetTodo(context.Background(), &todov1.GetTodoRequest{Id: 1})
+		if got := status.Code(err); got != tc.want {
+			t.Errorf("GetTodo failing with %v = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	todo := &models.Todo{ID: 1, Title: "Buy groceries"}
+
+	t.Run("resumes after last_seq and streams new events", func(t *testing.T) {
+		broker := events.NewBroker(10, 16)
+		client := todov1.NewTodoServiceClient(startServer(t, server{broker: broker}))
+
+		seen := broker.Publish(events.New(events.TodoCreated, todo))
+		missed := broker.Publish(events.New(events.TodoUpdated, todo))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.Watch(ctx, &todov1.WatchRequest{LastSeq: seen.Seq})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+
+		if event, err := stream.Recv(); err != nil || event.GetSeq() != missed.Seq || event.GetId() != missed.ID {
+			t.Fatalf("first event = %v, %v; want the missed %s", event, err, missed.ID)
+		}
+
+		// The subscription exists once the backlog has been sent.
+		live := broker.Publish(events.New(events.TodoCompleted, todo))
+		if event, err := stream.Recv(); err != nil || event.GetSeq() != live.Seq || event.GetTodo().GetTitle() != todo.Title {
+			t.Fatalf("live event = %v, %v; want seq %d", event, err, live.Seq)
+		}
+	})
+
+	t.Run("resets when events left the log", func(t *testing.T) {
+		broker := events.NewBroker(1, 16)
+		client := todov1.NewTodoServiceClient(startServer(t, server{broker: broker}))
+
+		first := broker.Publish(events.New(events.TodoCreated, todo))
+		broker.Publish(events.New(events.TodoUpdated, todo))
+		last := broker.Publish(events.New(events.TodoCompleted, todo))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.Watch(ctx, &todov1.WatchRequest{LastSeq: first.Seq})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+
+		if event, err := stream.Recv(); err != nil || event.GetType() != "reset" || event.GetSeq() != last.Seq {
+			t.Fatalf("first event = %v, %v; want a reset at seq %d", event, err, last.Seq)
+		}
+	})
+
+	t.Run("filters by type and todo", func(t *testing.T) {
+		broker := events.NewBroker(10, 16)
+		client := todov1.NewTodoServiceClient(startServer(t, server{broker: broker}))
+
+		start := broker.Publish(events.New(events.TodoCreated, todo))
+		broker.Publish(events.New(events.TodoUpdated, todo))
+		broker.Publish(events.New(events.TodoCompleted, &models.Todo{ID: 2}))
+		want := broker.Publish(events.New(events.TodoCompleted, todo))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := client.Watch(ctx, &todov1.WatchRequest{LastSeq: start.Seq, Types: []string{"todo.completed"}, TodoId: todo.ID})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+
+		if event, err := stream.Recv(); err != nil || event.GetSeq() != want.Seq {
+			t.Fatalf("first event = %v, %v; want seq %d", event, err, want.Seq)
+		}
+	})
+
+	t.Run("rejects unknown types", func(t *testing.T) {
+		client := todov1.NewTodoServiceClient(startServer(t, server{}))
+
+		stream, err := client.Watch(context.Background(), &todov1.WatchRequest{Types: []string{"todo.archived"}})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Watch of an unknown type: %v, want InvalidArgument", err)
+		}
+	})
+}
+
+func TestAuthentication(t *testing.T) {
+	conn := startServer(t, server{authn: authenticator{
+		"reader": {APIKeyID: 1, Scopes: []string{models.ScopeTodosRead}},
+		"writer": {APIKeyID: 2, Scopes: []string{models.ScopeTodosRead, models.ScopeTodosWrite}},
+	}})
+	client := todov1.NewTodoServiceClient(conn)
+	create := &todov1.CreateTodoRequest{Title: "Buy groceries"}
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{"without a token", context.Background(), func(ctx context.Context) error {
+			_, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: 1})
+			return err
+		}, codes.Unauthenticated},
+		{"with an unknown token", withToken("guess"), func(ctx context.Context) error {
+			_, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: 1})
+			return err
+		}, codes.Unauthenticated},
+		{"reading with the read scope", withToken("reader"), func(ctx context.Context) error {
+			_, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: 1})
+			return err
+		}, codes.OK},
+		{"writing with the read scope", withToken("reader"), func(ctx context.Context) error {
+			_, err := client.CreateTodo(ctx, create)
+			return err
+		}, codes.PermissionDenied},
+		{"writing with the write scope", withToken("writer"), func(ctx context.Context) error {
+			_, err := client.CreateTodo(ctx, create)
+			return err
+		}, codes.OK},
+		{"watching without a token", context.Background(), func(ctx context.Context) error {
+			stream, err := client.Watch(ctx, &todov1.WatchRequest{})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.Unauthenticated},
+		{"checking health without a token", context.Background(), func(ctx context.Context) error {
+			_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "todo.v1.TodoService"})
+			return err
+		}, codes.OK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := status.Code(tc.call(tc.ctx)); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	conn := startServer(t, server{
+		authn: authenticator{
+			"alice": {UserID: 1, Scopes: []string{models.ScopeTodosRead, models.ScopeTodosWrite}},
+			"bob":   {UserID: 2, Scopes: []string{models.ScopeTodosRead, models.ScopeTodosWrite}},
+		},
+		reads:  ratelimit.New(1, time.Minute),
+		writes: ratelimit.New(1, time.Minute),
+	})
+	client := todov1.NewTodoServiceClient(conn)
+	create := &todov1.CreateTodoRequest{Title: "Buy groceries"}
+
+	if _, err := client.CreateTodo(withToken("alice"), create); err != nil {
+		t.Fatalf("first CreateTodo: %v", err)
+	}
+
+	var trailer metadata.MD
+	_, err := client.CreateTodo(withToken("alice"), create, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second CreateTodo: %v, want ResourceExhausted", err)
+	}
+	if got := trailer.Get("retry-after"); len(got) != 1 || got[0] != "60" {
+		t.Errorf("retry-after trailer = %v, want 60", got)
+	}
+
+	// Reads and other users have buckets of their own.
+	if _, err := client.GetTodo(withToken("alice"), &todov1.GetTodoRequest{Id: 1}); err != nil {
+		t.Errorf("GetTodo after the writes ran out: %v", err)
+	}
+	if _, err := client.CreateTodo(withToken("bob"), create); err != nil {
+		t.Errorf("CreateTodo of another user: %v", err)
+	}
+
+	stream, err := client.Watch(withToken("alice"), &todov1.WatchRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Watch after the reads ran out: %v, want ResourceExhausted", err)
+	}
+
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("health check: %v, want it unlimited", err)
+	}
+}
@@ -0,0 +1,184 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	todov1 "todo-api/api/proto/todo/v1"
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+)
+
+type todoServer struct {
+	todov1.UnimplementedTodoServiceServer
+	service services.TodoService
+	broker  *events.Broker
+}
+
+// NewTodoServer implements the TodoService RPCs on top of the same service
+// and event broker as the REST handlers.
+func NewTodoServer(service services.TodoService, broker *events.Broker) todov1.TodoServiceServer {
+	return &todoServer{service: service, broker: broker}
+}
+
+func (s *todoServer) ListTodos(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	todos, total, err := s.service.List(ctx, models.TodoFilter{
+		Completed: req.Completed,
+		Query:     req.GetQuery(),
+		Limit:     int(req.GetLimit()),
+		Offset:    int(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &todov1.ListTodosResponse{
+		Todos: make([]*todov1.Todo, 0, len(todos)),
+		Total: total,
+	}
+	for i := range todos {
+		resp.Todos = append(resp.Todos, toProto(&todos[i]))
+	}
+
+	return resp, nil
+}
+
+func (s *todoServer) GetTodo(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.GetByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(todo), nil
+}
+
+func (s *todoServer) CreateTodo(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.Todo, error) {
+	todo := &models.Todo{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Completed:   req.GetCompleted(),
+	}
+
+	if err := s.service.Create(ctx, todo); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(todo), nil
+}
+
+func (s *todoServer) UpdateTodo(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.Todo, error) {
+	todo := &models.Todo{
+		ID:          req.GetId(),
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Completed:   req.GetCompleted(),
+	}
+
+	if err := s.service.Update(ctx, todo); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(todo), nil
+}
+
+func (s *todoServer) DeleteTodo(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
+	if err := s.service.Delete(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.DeleteTodoResponse{}, nil
+}
+
+func (s *todoServer) Watch(req *todov1.WatchRequest, stream todov1.TodoService_WatchServer) error {
+	filter, err := watchFilter(req)
+	if err != nil {
+		return err
+	}
+
+	sub, backlog, complete := s.broker.Subscribe(req.GetLastSeq(), auth.VisibleEvents(stream.Context(), filter))
+	defer s.broker.Unsubscribe(sub)
+
+	if !complete {
+		reset := &todov1.TodoEvent{Seq: s.broker.LastSeq(), Type: "reset"}
+		if err := stream.Send(reset); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range backlog {
+		if err := stream.Send(toProtoEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream closed, resume with last_seq")
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func watchFilter(req *todov1.WatchRequest) (events.Filter, error) {
+	var types map[events.Type]bool
+	if len(req.GetTypes()) > 0 {
+		types = make(map[events.Type]bool)
+		for _, name := range req.GetTypes() {
+			eventType := events.Type(name)
+			if !eventType.Valid() {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown event type: %s", name)
+			}
+			types[eventType] = true
+		}
+	}
+
+	todoID := req.GetTodoId()
+	if types == nil && todoID == 0 {
+		return nil, nil
+	}
+
+	return func(event events.Event) bool {
+		if types != nil && !types[event.Type] {
+			return false
+		}
+		return todoID == 0 || event.TodoID == todoID
+	}, nil
+}
+
+func toProto(todo *models.Todo) *todov1.Todo {
+	return &todov1.Todo{
+		Id:           todo.ID,
+		Title:        todo.Title,
+		Description:  todo.Description,
+		Completed:    todo.Completed,
+		CommentCount: todo.CommentCount,
+		CreatedAt:    timestamppb.New(todo.CreatedAt),
+		UpdatedAt:    timestamppb.New(todo.UpdatedAt),
+	}
+}
+
+func toProtoEvent(event events.Event) *todov1.TodoEvent {
+	msg := &todov1.TodoEvent{
+		Id:         event.ID,
+		Seq:        event.Seq,
+		Type:       string(event.Type),
+		TodoId:     event.TodoID,
+		OccurredAt: timestamppb.New(event.OccurredAt),
+	}
+	if event.Todo != nil {
+		msg.Todo = toProto(event.Todo)
+	}
+
+	return msg
+}
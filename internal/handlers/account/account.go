@@ -0,0 +1,26 @@
+package account
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/repositories"
+	"todo-api/pkg/utils"
+)
+
+// CredentialsRequest is the body accepted by registration and login
+type CredentialsRequest struct {
+	Email    string `json:"email" binding:"required" example:"ada@example.com"`
+	Password string `json:"password" binding:"required" example:"correct horse battery staple"`
+}
+
+func handleServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.NotFound(c, message, err.Error())
+	case errors.Is(err, repositories.ErrConflict):
+		utils.Conflict(c, message, err.Error())
+	default:
+		utils.BadRequest(c, message, err.Error())
+	}
+}
@@ -0,0 +1,37 @@
+package account
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/auth"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetMe returns the account of the caller
+// @Summary Get the current account
+// @Description Retrieves the account the bearer token belongs to
+// @Tags auth
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User "Account found"
+// @Failure 401 {object} object "Missing or invalid API key"
+// @Failure 404 {object} object "The API key does not belong to an account"
+// @Router /auth/me [get]
+func GetMe(service services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner := auth.Owner(c.Request.Context())
+		if owner == 0 {
+			utils.NotFound(c, "Account not found", "the API key does not belong to an account")
+			return
+		}
+
+		user, err := service.GetByID(owner)
+		if err != nil {
+			handleServiceError(c, "Failed to get account", err)
+			return
+		}
+
+		utils.OK(c, user)
+	}
+}
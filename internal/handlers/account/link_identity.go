@@ -0,0 +1,57 @@
+package account
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/auth"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// LinkIdentityRequest is the body accepted by LinkIdentity
+type LinkIdentityRequest struct {
+	Token string `json:"token" binding:"required" example:"eyJhbGciOiJFUzI1NiIsImtpZCI6InRlc3QifQ..."`
+}
+
+// LinkIdentity links an identity of the OpenID Connect provider to the account
+// @Summary Link an OpenID Connect identity
+// @Description Links the identity a JWT of the provider was issued to to the caller's account, so the JWT and later ones for the same subject act as the account. The caller must use a login token of the account. Identities are never linked to an account by their email alone.
+// @Tags auth
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param identity body LinkIdentityRequest true "A JWT of the identity"
+// @Success 200 {object} models.User "Identity linked to the account"
+// @Failure 400 {object} object "Invalid request body or JWT"
+// @Failure 401 {object} object "Missing or invalid API key"
+// @Failure 403 {object} object "The caller did not use a login token of an account"
+// @Failure 409 {object} object "The identity is linked to another account"
+// @Router /auth/identities [post]
+func LinkIdentity(tokens *auth.TokenVerifier, service services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LinkIdentityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		identity, err := tokens.Verify(c.Request.Context(), req.Token)
+		if err != nil {
+			utils.BadRequest(c, "Invalid identity token", err.Error())
+			return
+		}
+
+		user, err := service.LinkIdentity(c.Request.Context(), identity)
+		if err != nil {
+			if errors.Is(err, services.ErrForbidden) {
+				utils.Forbidden(c, "Failed to link identity", err.Error())
+				return
+			}
+			handleServiceError(c, "Failed to link identity", err)
+			return
+		}
+
+		utils.OK(c, user)
+	}
+}
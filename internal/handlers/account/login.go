@@ -0,0 +1,42 @@
+package account
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// Login issues a token for an account
+// @Summary Log in
+// @Description Checks the email and password and returns a bearer token limited to the account's todos, with the todos:read and todos:write scopes. The token is an API key that expires after the configured session lifetime.
+// @Tags auth
+// @Accept  json
+// @Produce json
+// @Param credentials body CredentialsRequest true "Email and password"
+// @Success 200 {object} models.Session "Logged in successfully"
+// @Failure 400 {object} object "Invalid request body"
+// @Failure 401 {object} object "Wrong email or password"
+// @Router /auth/login [post]
+func Login(service services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CredentialsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		session, err := service.Login(req.Email, req.Password)
+		if err != nil {
+			if errors.Is(err, services.ErrUnauthorized) {
+				utils.Unauthorized(c, "Invalid email or password", "check the credentials and try again")
+				return
+			}
+			utils.InternalServerError(c, "Failed to log in", err.Error())
+			return
+		}
+
+		utils.OK(c, session)
+	}
+}
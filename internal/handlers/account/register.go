@@ -0,0 +1,36 @@
+package account
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// Register creates an account
+// @Summary Register an account
+// @Description Creates an account with an email address and a password of 8 to 72 bytes. Todos created while logged in belong to the account and are invisible to other users.
+// @Tags auth
+// @Accept  json
+// @Produce json
+// @Param credentials body CredentialsRequest true "Email and password"
+// @Success 201 {object} models.User "Account created successfully"
+// @Failure 400 {object} object "Invalid request body or validation error"
+// @Failure 409 {object} object "Email is already registered"
+// @Router /auth/register [post]
+func Register(service services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CredentialsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		user, err := service.Register(req.Email, req.Password)
+		if err != nil {
+			handleServiceError(c, "Failed to register", err)
+			return
+		}
+
+		utils.Created(c, user)
+	}
+}
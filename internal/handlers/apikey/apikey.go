@@ -0,0 +1,47 @@
+package apikey
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/pkg/utils"
+)
+
+// APIKeyRequest is the body accepted when creating an API key
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required" example:"ci-pipeline"`
+	Scopes    []string   `json:"scopes" binding:"required" example:"todos:read,todos:write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2027-02-16T09:00:00Z"`
+}
+
+func (r APIKeyRequest) toModel() models.APIKey {
+	return models.APIKey{
+		Name:      r.Name,
+		Scopes:    r.Scopes,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+// parseID reads a positive integer path parameter.
+func parseID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		utils.HandleIDError(c, err)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func handleServiceError(c *gin.Context, message string, err error) {
+	if errors.Is(err, repositories.ErrNotFound) {
+		utils.NotFound(c, message, err.Error())
+		return
+	}
+
+	utils.BadRequest(c, message, err.Error())
+}
@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// CreateAPIKey issues a new API key
+// @Summary Create an API key
+// @Description Issues a bearer token with the given scopes (todos:read, todos:write, admin) and optional expiry. Only a hash is stored, so the secret is returned by this call only.
+// @Tags api-keys
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param key body APIKeyRequest true "API key data"
+// @Success 201 {object} models.APIKey "API key created successfully, including its secret"
+// @Failure 400 {object} object "Invalid request body or validation error"
+// @Failure 401 {object} object "Missing or invalid API key"
+// @Failure 403 {object} object "The admin scope is required"
+// @Router /api-keys [post]
+func CreateAPIKey(service services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req APIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		key := req.toModel()
+		if err := service.Create(&key); err != nil {
+			utils.BadRequest(c, "Failed to create API key", err.Error())
+			return
+		}
+
+		utils.Created(c, key)
+	}
+}
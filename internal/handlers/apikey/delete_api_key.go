@@ -0,0 +1,37 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// DeleteAPIKey revokes an API key
+// @Summary Revoke an API key
+// @Description Deletes an API key; requests using it are rejected from then on
+// @Tags api-keys
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} object "API key revoked successfully"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 401 {object} object "Missing or invalid API key"
+// @Failure 403 {object} object "The admin scope is required"
+// @Failure 404 {object} object "API key not found"
+// @Router /api-keys/{id} [delete]
+func DeleteAPIKey(service services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		if err := service.Delete(id); err != nil {
+			handleServiceError(c, "Failed to revoke API key", err)
+			return
+		}
+
+		utils.Message(c, "API key revoked successfully")
+	}
+}
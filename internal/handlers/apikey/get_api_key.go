@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetAPIKey returns an API key
+// @Summary Get an API key
+// @Description Retrieves an API key by ID, without its secret
+// @Tags api-keys
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKey "API key found"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 401 {object} object "Missing or invalid API key"
+// @Failure 403 {object} object "The admin scope is required"
+// @Failure 404 {object} object "API key not found"
+// @Router /api-keys/{id} [get]
+func GetAPIKey(service services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		key, err := service.GetByID(id)
+		if err != nil {
+			handleServiceError(c, "Failed to get API key", err)
+			return
+		}
+
+		utils.OK(c, key)
+	}
+}
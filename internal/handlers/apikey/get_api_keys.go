@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetAPIKeys lists API keys
+// @Summary List API keys
+// @Description Retrieves every API key with its scopes, expiry and last use. Secrets are never returned.
+// @Tags api-keys
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey "List of API keys"
+// @Failure 401 {object} object "Missing or invalid API key"
+// @Failure 403 {object} object "The admin scope is required"
+// @Failure 500 {object} object "Internal server error"
+// @Router /api-keys [get]
+func GetAPIKeys(service services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := service.GetAll()
+		if err != nil {
+			utils.InternalServerError(c, "Failed to get API keys", err.Error())
+			return
+		}
+
+		utils.OK(c, keys)
+	}
+}
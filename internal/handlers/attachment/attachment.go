@@ -0,0 +1,47 @@
+package attachment
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// parseIDs reads the todo id and, when present, the attachment id from the path.
+func parseIDs(c *gin.Context) (todoID, attachmentID int64, ok bool) {
+	todoID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.HandleIDError(c, err)
+		return 0, 0, false
+	}
+
+	if raw := c.Param("attachment_id"); raw != "" {
+		attachmentID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			utils.HandleIDError(c, err)
+			return 0, 0, false
+		}
+	}
+
+	return todoID, attachmentID, true
+}
+
+func handleServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.NotFound(c, message, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		utils.Forbidden(c, message, err.Error())
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		utils.RequestEntityTooLarge(c, message, err.Error())
+	case errors.Is(err, services.ErrUnsupportedAttachmentType):
+		utils.UnsupportedMediaType(c, message, err.Error())
+	case errors.Is(err, services.ErrAttachmentContentMissing):
+		utils.InternalServerError(c, message, err.Error())
+	default:
+		utils.BadRequest(c, message, err.Error())
+	}
+}
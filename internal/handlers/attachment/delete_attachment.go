@@ -0,0 +1,37 @@
+package attachment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// DeleteAttachment removes an attachment from a todo
+// @Summary Delete an attachment
+// @Description Removes an attachment from a todo. The stored content is garbage collected once no attachment references it.
+// @Tags attachments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Success 200 {object} object "Attachment deleted successfully"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 403 {object} object "Requires the editor role on a shared todo"
+// @Failure 404 {object} object "Todo or attachment not found"
+// @Router /todos/{id}/attachments/{attachment_id} [delete]
+func DeleteAttachment(service services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, attachmentID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		if err := service.Delete(c.Request.Context(), todoID, attachmentID); err != nil {
+			handleServiceError(c, "Failed to delete attachment", err)
+			return
+		}
+
+		utils.Message(c, "Attachment deleted successfully")
+	}
+}
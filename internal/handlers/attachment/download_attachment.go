@@ -0,0 +1,51 @@
+package attachment
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+)
+
+// DownloadAttachment streams the content of an attachment
+// @Summary Download an attachment
+// @Description Streams the stored content with its sniffed Content-Type. Supports Range and conditional requests.
+// @Tags attachments
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Param Range header string false "Byte range, e.g. bytes=0-1023"
+// @Success 200 {file} file "Attachment content"
+// @Success 206 {file} file "Partial attachment content"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Todo or attachment not found"
+// @Failure 416 {object} object "Requested range not satisfiable"
+// @Failure 500 {object} object "Attachment content missing from storage"
+// @Router /todos/{id}/attachments/{attachment_id}/content [get]
+func DownloadAttachment(service services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, attachmentID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		attachment, file, err := service.Open(c.Request.Context(), todoID, attachmentID)
+		if err != nil {
+			handleServiceError(c, "Attachment not found", err)
+			return
+		}
+		defer file.Close()
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", attachment.ContentType)
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": attachment.Filename,
+		}))
+		header.Set("ETag", `"`+attachment.SHA256+`"`)
+		header.Set("X-Content-Type-Options", "nosniff")
+
+		http.ServeContent(c.Writer, c.Request, attachment.Filename, attachment.CreatedAt, file)
+	}
+}
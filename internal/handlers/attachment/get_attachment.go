@@ -0,0 +1,37 @@
+package attachment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetAttachment retrieves the metadata of an attachment
+// @Summary Get attachment by ID
+// @Description Retrieves the metadata of a specific attachment of a todo
+// @Tags attachments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Success 200 {object} models.Attachment "Attachment details"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Todo or attachment not found"
+// @Router /todos/{id}/attachments/{attachment_id} [get]
+func GetAttachment(service services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, attachmentID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		attachment, err := service.GetByID(c.Request.Context(), todoID, attachmentID)
+		if err != nil {
+			handleServiceError(c, "Attachment not found", err)
+			return
+		}
+
+		utils.OK(c, attachment)
+	}
+}
@@ -0,0 +1,36 @@
+package attachment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetAttachments lists the attachments of a todo
+// @Summary List attachments of a todo
+// @Description Retrieves the metadata of every file attached to a todo
+// @Tags attachments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.Attachment "List of attachments"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Todo not found"
+// @Router /todos/{id}/attachments [get]
+func GetAttachments(service services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		attachments, err := service.List(c.Request.Context(), todoID)
+		if err != nil {
+			handleServiceError(c, "Failed to get attachments", err)
+			return
+		}
+
+		utils.OK(c, attachments)
+	}
+}
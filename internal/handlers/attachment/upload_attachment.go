@@ -0,0 +1,70 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// UploadAttachment attaches a file to a todo
+// @Summary Upload an attachment
+// @Description Uploads a file as multipart/form-data in the "file" field. The content type is sniffed from the content and must be one of the allowed types; identical content is stored only once.
+// @Tags attachments
+// @Accept  multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} models.Attachment "Attachment uploaded successfully"
+// @Failure 400 {object} object "Invalid ID format or multipart body"
+// @Failure 403 {object} object "Requires the editor role on a shared todo"
+// @Failure 404 {object} object "Todo not found"
+// @Failure 413 {object} object "File exceeds the maximum size"
+// @Failure 415 {object} object "File type is not allowed"
+// @Router /todos/{id}/attachments [post]
+func UploadAttachment(service services.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			utils.BadRequest(c, "Invalid multipart body", err.Error())
+			return
+		}
+
+		// Stream the file part straight into the blob store instead of
+		// letting the multipart parser buffer it.
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				utils.BadRequest(c, "Invalid multipart body", "file field is required")
+				return
+			}
+			if err != nil {
+				utils.BadRequest(c, "Invalid multipart body", err.Error())
+				return
+			}
+
+			if part.FormName() != "file" {
+				part.Close()
+				continue
+			}
+
+			attachment, err := service.Upload(c.Request.Context(), todoID, part.FileName(), part)
+			part.Close()
+			if err != nil {
+				handleServiceError(c, "Failed to upload attachment", err)
+				return
+			}
+
+			utils.Created(c, attachment)
+			return
+		}
+	}
+}
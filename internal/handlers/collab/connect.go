@@ -0,0 +1,67 @@
+package collab
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"todo-api/internal/auth"
+	"todo-api/internal/collab"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// Connect opens the collaboration WebSocket
+// @Summary Open the collaboration channel
+// @Description Upgrades to a WebSocket carrying JSON messages. Send {"type":"subscribe","topic":"todos"} or "todo:<id>" to receive change events and presence join/leave updates for that topic, and {"type":"editing","todo_id":1} to take or renew the soft editing lock of a todo, which expires unless renewed. {"type":"stop_editing","todo_id":1} releases it. Changes made through the REST API are broadcast as well. Callers logged in to an account only receive the changes and locks of todos they own or that are shared with them, may only subscribe to and lock those todos, and share presence on the todos list only with their own account. Authenticated callers are shown under their account email or API key name; the user parameter only names callers when authentication is disabled. Browsers may connect from the API's origin and the CORS allowed origins.
+// @Tags collaboration
+// @Security BearerAuth
+// @Param user query string false "Display name shown to other participants, required when authentication is disabled"
+// @Success 101 {object} collab.Message "Switching protocols"
+// @Failure 400 {object} object "Missing user or not a WebSocket request"
+// @Failure 403 {object} object "Origin not allowed"
+// @Router /collab [get]
+func Connect(hub *collab.Hub, users services.UserService, checkOrigin func(r *http.Request) bool) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     checkOrigin,
+	}
+
+	return func(c *gin.Context) {
+		user, ok := displayName(c, users)
+		if !ok {
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade has already written the error response.
+			return
+		}
+
+		hub.Serve(conn, user, auth.Owner(c.Request.Context()))
+	}
+}
+
+// displayName is the name other participants see: the account email or API
+// key name of an authenticated caller, so nobody can pose as someone else,
+// and the user query parameter only when authentication is disabled.
+func displayName(c *gin.Context, users services.UserService) (string, bool) {
+	name, ok, err := services.CallerName(c.Request.Context(), users)
+	if err != nil {
+		utils.InternalServerError(c, "Failed to load user", err.Error())
+		return "", false
+	}
+	if ok {
+		return name, true
+	}
+
+	user := strings.TrimSpace(c.Query("user"))
+	if user == "" || len(user) > 100 {
+		utils.BadRequest(c, "Invalid user", "user is required and must be less than 100 characters")
+		return "", false
+	}
+	return user, true
+}
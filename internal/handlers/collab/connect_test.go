@@ -0,0 +1,133 @@
+package collab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"todo-api/internal/auth"
+	"todo-api/internal/collab"
+	"todo-api/internal/config"
+	"todo-api/internal/events"
+	collabHandler "todo-api/internal/handlers/collab"
+	"todo-api/internal/middleware"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+)
+
+// users knows a single account.
+type users struct {
+	services.UserService
+}
+
+func (users) GetByID(id int64) (*models.User, error) {
+	return &models.User{ID: id, Email: "ada@example.com"}, nil
+}
+
+// startConnect serves Connect, authenticating requests as principal unless
+// it is nil, and returns its WebSocket URL.
+func startConnect(t *testing.T, principal *auth.Principal, cors config.CORSConfig) string {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	broker := events.NewBroker(10, 16)
+	hub := collab.NewHub(broker, time.Minute, func(context.Context, int64) ([]int64, error) {
+		return nil, nil
+	})
+	t.Cleanup(broker.Close)
+
+	r := gin.New()
+	r.GET("/collab", func(c *gin.Context) {
+		if principal != nil {
+			c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), principal))
+		}
+	}, collabHandler.Connect(hub, users{}, middleware.WebSocketOrigin(cors)))
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/collab"
+}
+
+// welcome connects with header and returns the name the hub welcomed.
+func welcome(t *testing.T, url string, header http.Header) (string, *http.Response, error) {
+	t.Helper()
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		return "", resp, err
+	}
+	defer conn.Close()
+
+	var msg collab.Message
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	return msg.User, resp, nil
+}
+
+func TestConnectNamesParticipants(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		principal *auth.Principal
+		want      string
+	}{
+		{"without authentication", nil, "mallory"},
+		{"with an account's API key", &auth.Principal{APIKeyID: 1, UserID: 7, Name: "login"}, "ada@example.com"},
+		{"with a JWT", &auth.Principal{UserID: 7, Subject: "sub", Name: "grace@example.com"}, "grace@example.com"},
+		{"with an admin key", &auth.Principal{APIKeyID: 2, Name: "deploy bot"}, "deploy bot"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			url := startConnect(t, tc.principal, config.CORSConfig{})
+
+			user, _, err := welcome(t, url+"?user=mallory", nil)
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			if user != tc.want {
+				t.Errorf("welcomed as %q, want %q", user, tc.want)
+			}
+		})
+	}
+}
+
+func TestConnectRequiresUserWithoutAuthentication(t *testing.T) {
+	url := startConnect(t, nil, config.CORSConfig{})
+
+	_, resp, err := welcome(t, url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Dial without user: %v, want 400 Bad Request", err)
+	}
+}
+
+func TestConnectChecksOrigin(t *testing.T) {
+	url := startConnect(t, nil, config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+	host := strings.TrimPrefix(url, "ws://")
+	host = host[:strings.Index(host, "/")]
+
+	for origin, allowed := range map[string]bool{
+		"":                          true,
+		"http://" + host:            true,
+		"https://app.example.com":   true,
+		"https://evil.example.com":  false,
+		"https://app.example.com.x": false,
+	} {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+
+		_, resp, err := welcome(t, url+"?user=alice", header)
+		if allowed && err != nil {
+			t.Errorf("origin %q refused: %v", origin, err)
+		}
+		if !allowed && (err == nil || resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("origin %q: %v, want 403 Forbidden", origin, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package comment
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// CommentRequest is the body accepted when writing a comment. The author is
+// the caller and cannot be chosen.
+type CommentRequest struct {
+	Body            string `json:"body" example:"Picked up **milk**, still need eggs"`
+	ParentCommentID *int64 `json:"parent_comment_id,omitempty" example:"1"`
+}
+
+// parseIDs reads the todo id and, when present, the comment id from the path.
+func parseIDs(c *gin.Context) (todoID, commentID int64, ok bool) {
+	todoID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.HandleIDError(c, err)
+		return 0, 0, false
+	}
+
+	if raw := c.Param("comment_id"); raw != "" {
+		commentID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			utils.HandleIDError(c, err)
+			return 0, 0, false
+		}
+	}
+
+	return todoID, commentID, true
+}
+
+// handleServiceError answers 404 for missing todos or comments, 403 for
+// callers lacking the role and 400 otherwise.
+func handleServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.NotFound(c, message, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		utils.Forbidden(c, message, err.Error())
+	default:
+		utils.BadRequest(c, message, err.Error())
+	}
+}
@@ -0,0 +1,46 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// CreateComment adds a comment to a todo
+// @Summary Create a comment
+// @Description Adds a markdown comment to a todo. Set parent_comment_id to reply to another comment of the same todo. The author is the account email or API key name of the caller, or "anonymous" when authentication is disabled.
+// @Tags comments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param comment body CommentRequest true "Comment data"
+// @Success 201 {object} models.Comment "Comment created successfully"
+// @Failure 400 {object} object "Invalid request body or validation error"
+// @Failure 403 {object} object "Requires the editor role on a shared todo"
+// @Failure 404 {object} object "Todo or parent comment not found"
+// @Router /todos/{id}/comments [post]
+func CreateComment(service services.CommentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		var req CommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		comment := models.Comment{TodoID: todoID, Body: req.Body, ParentCommentID: req.ParentCommentID}
+
+		if err := service.Create(c.Request.Context(), &comment); err != nil {
+			handleServiceError(c, "Failed to create comment", err)
+			return
+		}
+
+		utils.Created(c, comment)
+	}
+}
@@ -0,0 +1,37 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// DeleteComment soft-deletes a comment
+// @Summary Delete a comment
+// @Description Soft-deletes a comment. Its replies are kept and it stays in the thread with an empty body.
+// @Tags comments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param comment_id path int true "Comment ID"
+// @Success 200 {object} object "Comment deleted successfully"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 403 {object} object "Requires the editor role on a shared todo"
+// @Failure 404 {object} object "Todo or comment not found"
+// @Router /todos/{id}/comments/{comment_id} [delete]
+func DeleteComment(service services.CommentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, commentID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		if err := service.Delete(c.Request.Context(), todoID, commentID); err != nil {
+			handleServiceError(c, "Failed to delete comment", err)
+			return
+		}
+
+		utils.Message(c, "Comment deleted successfully")
+	}
+}
@@ -0,0 +1,37 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetComment retrieves a single comment
+// @Summary Get comment by ID
+// @Description Retrieves a specific comment of a todo
+// @Tags comments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param comment_id path int true "Comment ID"
+// @Success 200 {object} models.Comment "Comment details"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Todo or comment not found"
+// @Router /todos/{id}/comments/{comment_id} [get]
+func GetComment(service services.CommentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, commentID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		comment, err := service.GetByID(c.Request.Context(), todoID, commentID)
+		if err != nil {
+			handleServiceError(c, "Comment not found", err)
+			return
+		}
+
+		utils.OK(c, comment)
+	}
+}
@@ -0,0 +1,44 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetComments lists the comments of a todo
+// @Summary List comments of a todo
+// @Description Retrieves a page of comments for a todo in chronological order. Replies reference their parent through parent_comment_id; deleted comments keep their place in the thread with an empty body.
+// @Tags comments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param offset query int false "Number of comments to skip" default(0)
+// @Success 200 {object} utils.PaginatedResponse{data=[]models.Comment} "Page of comments"
+// @Failure 400 {object} object "Invalid ID or pagination parameters"
+// @Failure 404 {object} object "Todo not found"
+// @Router /todos/{id}/comments [get]
+func GetComments(service services.CommentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		limit, offset, err := utils.ParsePagination(c)
+		if err != nil {
+			utils.BadRequest(c, "Invalid pagination parameters", err.Error())
+			return
+		}
+
+		comments, total, err := service.List(c.Request.Context(), todoID, limit, offset)
+		if err != nil {
+			handleServiceError(c, "Failed to get comments", err)
+			return
+		}
+
+		utils.Paginated(c, comments, total, limit, offset)
+	}
+}
@@ -0,0 +1,47 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// UpdateComment edits the body of a comment
+// @Summary Edit a comment
+// @Description Replaces the body of a comment and sets edited_at. Deleted comments cannot be edited.
+// @Tags comments
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param comment_id path int true "Comment ID"
+// @Param comment body CommentRequest true "Updated comment body"
+// @Success 200 {object} models.Comment "Comment updated successfully"
+// @Failure 400 {object} object "Invalid ID format or request body"
+// @Failure 403 {object} object "Requires the editor role on a shared todo"
+// @Failure 404 {object} object "Todo or comment not found"
+// @Router /todos/{id}/comments/{comment_id} [put]
+func UpdateComment(service services.CommentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, commentID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		var req CommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		comment := models.Comment{ID: commentID, TodoID: todoID, Body: req.Body}
+
+		if err := service.Update(c.Request.Context(), &comment); err != nil {
+			handleServiceError(c, "Failed to update comment", err)
+			return
+		}
+
+		utils.OK(c, comment)
+	}
+}
@@ -0,0 +1,48 @@
+package grant
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// CreateGrant shares a todo with a user
+// @Summary Share a todo
+// @Description Grants the user registered with the given email the viewer, editor or admin role on a todo. Viewers read it, editors also change it, comment and attach files, admins also delete and share it. Granting again changes the role. Todos are shared with users only: a body naming a group or group_id is refused.
+// @Tags grants
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param grant body GrantRequest true "User and role"
+// @Success 201 {object} models.Grant "Todo shared successfully"
+// @Failure 400 {object} object "Invalid request body or role, or a group grant"
+// @Failure 403 {object} object "Sharing requires the admin role"
+// @Failure 404 {object} object "Todo or user not found"
+// @Router /todos/{id}/grants [post]
+func CreateGrant(service services.GrantService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		var req GrantRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+		if req.Group != nil || req.GroupID != nil {
+			utils.BadRequest(c, "Failed to share todo", "todos are shared with users only; group grants are not supported")
+			return
+		}
+
+		grant, err := service.Grant(c.Request.Context(), todoID, req.Email, req.Role)
+		if err != nil {
+			handleServiceError(c, "Failed to share todo", err)
+			return
+		}
+
+		utils.Created(c, grant)
+	}
+}
@@ -0,0 +1,37 @@
+package grant
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// DeleteGrant stops sharing a todo with a user
+// @Summary Revoke a grant
+// @Description Revokes a user's access to a todo. Requires the admin role, except for users giving up their own access.
+// @Tags grants
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} object "Grant revoked successfully"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 403 {object} object "Revoking requires the admin role"
+// @Failure 404 {object} object "Todo or grant not found"
+// @Router /todos/{id}/grants/{user_id} [delete]
+func DeleteGrant(service services.GrantService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, userID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		if err := service.Revoke(c.Request.Context(), todoID, userID); err != nil {
+			handleServiceError(c, "Failed to revoke grant", err)
+			return
+		}
+
+		utils.Message(c, "Grant revoked successfully")
+	}
+}
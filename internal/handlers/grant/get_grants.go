@@ -0,0 +1,36 @@
+package grant
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetGrants lists who a todo is shared with
+// @Summary List grants of a todo
+// @Description Retrieves the users a todo is shared with and their roles. The owner has full access without a grant.
+// @Tags grants
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.Grant "List of grants"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Todo not found"
+// @Router /todos/{id}/grants [get]
+func GetGrants(service services.GrantService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		grants, err := service.List(c.Request.Context(), todoID)
+		if err != nil {
+			handleServiceError(c, "Failed to get grants", err)
+			return
+		}
+
+		utils.OK(c, grants)
+	}
+}
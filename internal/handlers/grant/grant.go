@@ -0,0 +1,54 @@
+package grant
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GrantRequest is the body accepted when sharing a todo
+type GrantRequest struct {
+	Email string `json:"email" binding:"required" example:"grace@example.com"`
+	Role  string `json:"role" binding:"required" example:"editor"`
+	// Group and GroupID are only read to refuse them: todos are shared
+	// with users, and a group grant must not be mistaken for one.
+	Group   json.RawMessage `json:"group,omitempty" swaggerignore:"true"`
+	GroupID json.RawMessage `json:"group_id,omitempty" swaggerignore:"true"`
+}
+
+// parseIDs reads the todo id and, when present, the user id from the path.
+func parseIDs(c *gin.Context) (todoID, userID int64, ok bool) {
+	todoID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.HandleIDError(c, err)
+		return 0, 0, false
+	}
+
+	if raw := c.Param("user_id"); raw != "" {
+		userID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			utils.HandleIDError(c, err)
+			return 0, 0, false
+		}
+	}
+
+	return todoID, userID, true
+}
+
+// handleServiceError answers 404 for missing todos, users or grants, 403
+// for callers lacking the role and 400 otherwise.
+func handleServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.NotFound(c, message, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		utils.Forbidden(c, message, err.Error())
+	default:
+		utils.BadRequest(c, message, err.Error())
+	}
+}
@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"todo-api/internal/graphqlapi"
+	"todo-api/pkg/utils"
+)
+
+// Serve answers GraphQL requests at a single endpoint. POST accepts a JSON body with
+// query, operationName and variables; GET takes the same as query
+// parameters and only runs queries. A GET upgrading to a WebSocket with the
+// graphql-transport-ws subprotocol runs subscriptions; checkOrigin decides
+// which browser origins may open one.
+func Serve(server *graphqlapi.Server, checkOrigin func(r *http.Request) bool) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{graphqlapi.Subprotocol},
+		CheckOrigin:     checkOrigin,
+	}
+
+	return func(c *gin.Context) {
+		if websocket.IsWebSocketUpgrade(c.Request) {
+			conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+			if err != nil {
+				// Upgrade has already written the error response.
+				return
+			}
+
+			server.ServeWebSocket(context.WithoutCancel(c.Request.Context()), conn)
+			return
+		}
+
+		var req graphqlapi.Request
+		if c.Request.Method == "POST" {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				utils.HandleJSONError(c, err)
+				return
+			}
+		} else {
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if raw := c.Query("variables"); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+					utils.BadRequest(c, "Invalid variables", "variables must be a JSON object")
+					return
+				}
+			}
+		}
+
+		if req.Query == "" {
+			utils.BadRequest(c, "Missing query", "query is required")
+			return
+		}
+
+		c.JSON(200, server.Execute(c.Request.Context(), req, c.Request.Method == "POST"))
+	}
+}
@@ -0,0 +1,47 @@
+package sharelink
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/models"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// CreateShareLink creates a public read-only link to a todo
+// @Summary Create a share link
+// @Description Creates a link opening a read-only view of the todo without an account, with an optional expiry and password. Only a hash of the token is stored, so the token and URL are returned by this call only.
+// @Tags share-links
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param link body ShareLinkRequest false "Expiry and password"
+// @Success 201 {object} models.ShareLink "Share link created successfully, including its token"
+// @Failure 400 {object} object "Invalid request body or validation error"
+// @Failure 403 {object} object "Managing share links requires the admin role"
+// @Failure 404 {object} object "Todo not found"
+// @Router /todos/{id}/share-links [post]
+func CreateShareLink(service services.ShareLinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		var req ShareLinkRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				utils.HandleJSONError(c, err)
+				return
+			}
+		}
+
+		link := models.ShareLink{TodoID: todoID, ExpiresAt: req.ExpiresAt}
+		if err := service.Create(c.Request.Context(), &link, req.Password); err != nil {
+			handleServiceError(c, "Failed to create share link", err)
+			return
+		}
+
+		utils.Created(c, link)
+	}
+}
@@ -0,0 +1,37 @@
+package sharelink
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// DeleteShareLink revokes a share link
+// @Summary Revoke a share link
+// @Description Revokes a share link; its token stops working immediately.
+// @Tags share-links
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Param link_id path int true "Share link ID"
+// @Success 200 {object} object "Share link revoked successfully"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 403 {object} object "Managing share links requires the admin role"
+// @Failure 404 {object} object "Todo or share link not found"
+// @Router /todos/{id}/share-links/{link_id} [delete]
+func DeleteShareLink(service services.ShareLinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, linkID, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		if err := service.Revoke(c.Request.Context(), todoID, linkID); err != nil {
+			handleServiceError(c, "Failed to revoke share link", err)
+			return
+		}
+
+		utils.Message(c, "Share link revoked successfully")
+	}
+}
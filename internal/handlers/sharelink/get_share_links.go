@@ -0,0 +1,37 @@
+package sharelink
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetShareLinks lists the share links of a todo
+// @Summary List share links of a todo
+// @Description Retrieves the public links to a todo with their expiry and access count. Tokens are not returned.
+// @Tags share-links
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.ShareLink "List of share links"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 403 {object} object "Managing share links requires the admin role"
+// @Failure 404 {object} object "Todo not found"
+// @Router /todos/{id}/share-links [get]
+func GetShareLinks(service services.ShareLinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		todoID, _, ok := parseIDs(c)
+		if !ok {
+			return
+		}
+
+		links, err := service.List(c.Request.Context(), todoID)
+		if err != nil {
+			handleServiceError(c, "Failed to get share links", err)
+			return
+		}
+
+		utils.OK(c, links)
+	}
+}
@@ -0,0 +1,42 @@
+package sharelink
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetSharedTodo opens a share link
+// @Summary Open a share link
+// @Description Returns a read-only view of the shared todo and its comments, without authentication. Password-protected links need the password in the X-Share-Password header. Every successful view is counted, and requests are rate limited per client.
+// @Tags share-links
+// @Produce json
+// @Param token path string true "Share link token"
+// @Param X-Share-Password header string false "Password of a protected link"
+// @Success 200 {object} models.SharedTodo "Shared todo"
+// @Failure 403 {object} object "Missing or wrong password"
+// @Failure 404 {object} object "Unknown, revoked or expired link"
+// @Failure 429 {object} object "Too many requests"
+// @Router /shared/{token} [get]
+func GetSharedTodo(service services.ShareLinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shared, err := service.Open(c.Request.Context(), c.Param("token"), c.GetHeader(PasswordHeader))
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrNotFound):
+				utils.NotFound(c, "Share link not found", err.Error())
+			case errors.Is(err, services.ErrUnauthorized):
+				utils.Forbidden(c, "Password required", "send the link's password in the "+PasswordHeader+" header")
+			default:
+				utils.InternalServerError(c, "Failed to open share link", err.Error())
+			}
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		utils.OK(c, shared)
+	}
+}
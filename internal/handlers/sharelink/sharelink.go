@@ -0,0 +1,53 @@
+package sharelink
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// PasswordHeader carries the password of a protected share link.
+const PasswordHeader = "X-Share-Password"
+
+// ShareLinkRequest is the body accepted when creating a share link
+type ShareLinkRequest struct {
+	Password  string     `json:"password,omitempty" example:"correct horse battery staple"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-03-16T09:00:00Z"`
+}
+
+// parseIDs reads the todo id and, when present, the link id from the path.
+func parseIDs(c *gin.Context) (todoID, linkID int64, ok bool) {
+	todoID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.HandleIDError(c, err)
+		return 0, 0, false
+	}
+
+	if raw := c.Param("link_id"); raw != "" {
+		linkID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			utils.HandleIDError(c, err)
+			return 0, 0, false
+		}
+	}
+
+	return todoID, linkID, true
+}
+
+// handleServiceError answers 404 for missing todos or links, 403 for callers
+// lacking the role and 400 otherwise.
+func handleServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.NotFound(c, message, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		utils.Forbidden(c, message, err.Error())
+	default:
+		utils.BadRequest(c, message, err.Error())
+	}
+}
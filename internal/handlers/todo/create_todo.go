@@ -1,6 +1,10 @@
 package todo
 
 import (
+	"errors"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"todo-api/internal/models"
 	"todo-api/internal/services"
@@ -13,9 +17,15 @@ import (
 // @Tags todos
 // @Accept  json
 // @Produce json
+// @Security BearerAuth
 // @Param todo body models.Todo true "Todo data"
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a retry with the same key and body gets the first response again"
 // @Success 201 {object} models.Todo "Todo created successfully"
 // @Failure 400 {object} object "Invalid request body or validation error"
+// @Failure 409 {object} object "A request with the same idempotency key is still in progress"
+// @Failure 413 {object} object "Request body too large"
+// @Failure 422 {object} object "Idempotency key reused with a different request"
+// @Failure 429 {object} object "Rate limit or daily todo quota exceeded"
 // @Failure 500 {object} object "Internal server error"
 // @Router /todos [post]
 func CreateTodo(service services.TodoService) gin.HandlerFunc {
@@ -27,7 +37,14 @@ func CreateTodo(service services.TodoService) gin.HandlerFunc {
 			return
 		}
 		
-		if err := service.Create(&todo); err != nil {
+		if err := service.Create(c.Request.Context(), &todo); err != nil {
+			var quota *services.QuotaError
+			if errors.As(err, &quota) {
+				c.Header("Retry-After", strconv.Itoa(int(time.Until(quota.Reset).Seconds())+1))
+				utils.TooManyRequests(c, "Daily todo quota exceeded", err.Error())
+				return
+			}
+			
 			utils.BadRequest(c, "Failed to create todo", err.Error())
 			return
 		}
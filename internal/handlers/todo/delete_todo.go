@@ -1,6 +1,7 @@
 package todo
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -14,9 +15,11 @@ import (
 // @Tags todos
 // @Accept  json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Todo ID"
 // @Success 200 {object} models.Todo "Todo deleted successfully"
 // @Failure 400 {object} object "Invalid ID format"
+// @Failure 403 {object} object "Deleting requires the admin role"
 // @Failure 404 {object} object "Todo not found"
 // @Router /todos/{id} [delete]
 func DeleteTodo(service services.TodoService) gin.HandlerFunc {
@@ -27,7 +30,11 @@ func DeleteTodo(service services.TodoService) gin.HandlerFunc {
 			return
 		}
 		
-		if err := service.Delete(id); err != nil {
+		if err := service.Delete(c.Request.Context(), id); err != nil {
+			if errors.Is(err, services.ErrForbidden) {
+				utils.Forbidden(c, "Failed to delete todo", err.Error())
+				return
+			}
 			utils.NotFound(c, "Failed to delete todo", err.Error())
 			return
 		}
@@ -14,6 +14,7 @@ import (
 // @Tags todos
 // @Accept  json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Todo ID"
 // @Success 200 {object} models.Todo "Todo details"
 // @Failure 400 {object} object "Invalid ID format"
@@ -27,7 +28,7 @@ func GetTodo(service services.TodoService) gin.HandlerFunc {
 			return
 		}
 		
-		todo, err := service.GetByID(id)
+		todo, err := service.GetByID(c.Request.Context(), id)
 		if err != nil {
 			utils.NotFound(c, "Todo not found", err.Error())
 			return
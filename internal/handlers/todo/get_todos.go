@@ -1,28 +1,76 @@
 package todo
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+	"todo-api/internal/models"
 	"todo-api/internal/services"
 	"todo-api/pkg/utils"
 )
 
 // GetTodos retrieves all todos
 // @Summary Get all todos
-// @Description Retrieves a list of all todos from the database
+// @Description Retrieves a list of all todos from the database, newest first. Passing any of completed, q, limit or offset filters the list and pages it (20 per page unless limit is given), and the number of matching todos is returned in the X-Total-Count header.
 // @Tags todos
 // @Accept  json
 // @Produce json
+// @Security BearerAuth
+// @Param completed query bool false "Only return todos with this completed state"
+// @Param q query string false "Case-insensitive text matched against title and description"
+// @Param limit query int false "Page size (1-100)"
+// @Param offset query int false "Number of todos to skip"
 // @Success 200 {array} models.Todo "List of todos"
+// @Header 200 {integer} X-Total-Count "Number of matching todos, sent when filtering or paging"
+// @Failure 400 {object} object "Invalid filter or pagination parameters"
 // @Failure 500 {object} object "Internal server error"
 // @Router /todos [get]
 func GetTodos(service services.TodoService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		todos, err := service.GetAll()
+		if !hasListQuery(c) {
+			todos, err := service.GetAll(c.Request.Context())
+			if err != nil {
+				utils.InternalServerError(c, "Failed to get todos", err.Error())
+				return
+			}
+			
+			utils.OK(c, todos)
+			return
+		}
+		
+		limit, offset, err := utils.ParsePagination(c)
+		if err != nil {
+			utils.BadRequest(c, "Invalid pagination", err.Error())
+			return
+		}
+		
+		filter := models.TodoFilter{Query: c.Query("q"), Limit: limit, Offset: offset}
+		if raw := c.Query("completed"); raw != "" {
+			completed, err := strconv.ParseBool(raw)
+			if err != nil {
+				utils.BadRequest(c, "Invalid filter", "completed must be true or false")
+				return
+			}
+			filter.Completed = &completed
+		}
+		
+		todos, total, err := service.List(c.Request.Context(), filter)
 		if err != nil {
 			utils.InternalServerError(c, "Failed to get todos", err.Error())
 			return
 		}
 		
+		c.Header("X-Total-Count", strconv.FormatInt(total, 10))
 		utils.OK(c, todos)
 	}
 }
+
+// hasListQuery reports whether the request asks for a filtered or paged list.
+func hasListQuery(c *gin.Context) bool {
+	for _, name := range []string{"completed", "q", "limit", "offset"} {
+		if _, ok := c.GetQuery(name); ok {
+			return true
+		}
+	}
+	return false
+}
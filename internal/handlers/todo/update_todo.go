@@ -1,6 +1,7 @@
 package todo
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -15,10 +16,12 @@ import (
 // @Tags todos
 // @Accept  json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Todo ID"
 // @Param todo body models.Todo true "Updated todo data"
 // @Success 200 {object} models.Todo "Todo updated successfully"
 // @Failure 400 {object} object "Invalid ID format or request body"
+// @Failure 403 {object} object "Editing requires the editor role"
 // @Failure 500 {object} object "Internal server error"
 // @Router /todos/{id} [put]
 func UpdateTodo(service services.TodoService) gin.HandlerFunc {
@@ -37,7 +40,11 @@ func UpdateTodo(service services.TodoService) gin.HandlerFunc {
 		
 		todo.ID = id
 		
-		if err := service.Update(&todo); err != nil {
+		if err := service.Update(c.Request.Context(), &todo); err != nil {
+			if errors.Is(err, services.ErrForbidden) {
+				utils.Forbidden(c, "Failed to update todo", err.Error())
+				return
+			}
 			utils.BadRequest(c, "Failed to update todo", err.Error())
 			return
 		}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// CreateWebhook registers a webhook subscription
+// @Summary Create a webhook
+// @Description Subscribes a URL to todo events (todo.created, todo.updated, todo.deleted, todo.completed). Deliveries are signed with HMAC-SHA256 using the secret, which is generated when omitted and only returned by this call.
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body WebhookRequest true "Webhook data"
+// @Success 201 {object} models.WebhookSubscription "Webhook created successfully, including its secret"
+// @Failure 400 {object} object "Invalid request body or validation error"
+// @Router /webhooks [post]
+func CreateWebhook(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		subscription := req.toModel()
+		if err := service.Create(&subscription); err != nil {
+			utils.BadRequest(c, "Failed to create webhook", err.Error())
+			return
+		}
+
+		utils.Created(c, subscription)
+	}
+}
@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// DeleteWebhook removes a webhook subscription
+// @Summary Delete a webhook
+// @Description Removes a subscription together with its pending and dead deliveries
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} object "Webhook deleted successfully"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Webhook not found"
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		if err := service.Delete(id); err != nil {
+			handleServiceError(c, "Failed to delete webhook", err)
+			return
+		}
+
+		utils.Message(c, "Webhook deleted successfully")
+	}
+}
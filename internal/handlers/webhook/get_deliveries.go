@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetDeliveries lists deliveries of a webhook
+// @Summary List webhook deliveries
+// @Description Retrieves a page of deliveries of a subscription, newest first. Filter with status=dead to read the dead-letter list.
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Delivery status" Enums(pending, delivered, dead)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param offset query int false "Number of deliveries to skip" default(0)
+// @Success 200 {object} utils.PaginatedResponse{data=[]models.WebhookDelivery} "Page of deliveries"
+// @Failure 400 {object} object "Invalid ID, status or pagination parameters"
+// @Failure 404 {object} object "Webhook not found"
+// @Router /webhooks/{id}/deliveries [get]
+func GetDeliveries(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		limit, offset, err := utils.ParsePagination(c)
+		if err != nil {
+			utils.BadRequest(c, "Invalid pagination parameters", err.Error())
+			return
+		}
+
+		deliveries, total, err := service.GetDeliveries(id, c.Query("status"), limit, offset)
+		if err != nil {
+			handleServiceError(c, "Failed to get deliveries", err)
+			return
+		}
+
+		utils.Paginated(c, deliveries, total, limit, offset)
+	}
+}
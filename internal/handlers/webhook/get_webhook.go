@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetWebhook retrieves a webhook subscription
+// @Summary Get webhook by ID
+// @Description Retrieves a specific webhook subscription. The secret is never returned.
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookSubscription "Webhook details"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Webhook not found"
+// @Router /webhooks/{id} [get]
+func GetWebhook(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		subscription, err := service.GetByID(id)
+		if err != nil {
+			handleServiceError(c, "Webhook not found", err)
+			return
+		}
+
+		utils.OK(c, subscription)
+	}
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// GetWebhooks lists webhook subscriptions
+// @Summary List webhooks
+// @Description Retrieves every webhook subscription. Secrets are never returned.
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.WebhookSubscription "List of webhooks"
+// @Failure 500 {object} object "Internal server error"
+// @Router /webhooks [get]
+func GetWebhooks(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subscriptions, err := service.GetAll()
+		if err != nil {
+			utils.InternalServerError(c, "Failed to get webhooks", err.Error())
+			return
+		}
+
+		utils.OK(c, subscriptions)
+	}
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// ReplayDeliveries requeues every dead delivery of a webhook
+// @Summary Replay dead letters
+// @Description Moves every dead delivery of a subscription back to pending with a fresh retry budget
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} object "Number of replayed deliveries"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Webhook not found"
+// @Router /webhooks/{id}/deliveries/replay [post]
+func ReplayDeliveries(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		replayed, err := service.Replay(id, 0)
+		if err != nil {
+			handleServiceError(c, "Failed to replay deliveries", err)
+			return
+		}
+
+		utils.OK(c, gin.H{"replayed": replayed})
+	}
+}
+
+// ReplayDelivery requeues a single dead delivery
+// @Summary Replay a dead letter
+// @Description Moves one dead delivery back to pending with a fresh retry budget
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 200 {object} object "Number of replayed deliveries"
+// @Failure 400 {object} object "Invalid ID format"
+// @Failure 404 {object} object "Webhook or dead delivery not found"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func ReplayDelivery(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		deliveryID, ok := parseID(c, "delivery_id")
+		if !ok {
+			return
+		}
+
+		if deliveryID <= 0 {
+			utils.BadRequest(c, "Invalid ID format", "ID must be a positive number")
+			return
+		}
+
+		replayed, err := service.Replay(id, deliveryID)
+		if err != nil {
+			handleServiceError(c, "Failed to replay delivery", err)
+			return
+		}
+
+		utils.OK(c, gin.H{"replayed": replayed})
+	}
+}
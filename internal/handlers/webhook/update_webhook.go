@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// UpdateWebhook updates a webhook subscription
+// @Summary Update a webhook
+// @Description Replaces the URL, event types and active flag of a subscription. Omit the secret to keep the current one.
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param webhook body WebhookRequest true "Updated webhook data"
+// @Success 200 {object} models.WebhookSubscription "Webhook updated successfully"
+// @Failure 400 {object} object "Invalid ID format or request body"
+// @Failure 404 {object} object "Webhook not found"
+// @Router /webhooks/{id} [put]
+func UpdateWebhook(service services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.HandleJSONError(c, err)
+			return
+		}
+
+		subscription := req.toModel()
+		subscription.ID = id
+
+		if err := service.Update(&subscription); err != nil {
+			handleServiceError(c, "Failed to update webhook", err)
+			return
+		}
+
+		utils.OK(c, subscription)
+	}
+}
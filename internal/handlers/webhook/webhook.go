@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/pkg/utils"
+)
+
+// WebhookRequest is the body accepted when creating or updating a subscription
+type WebhookRequest struct {
+	URL        string   `json:"url" binding:"required" example:"https://example.com/hooks/todos"`
+	EventTypes []string `json:"event_types" binding:"required" example:"todo.created,todo.completed"`
+	Secret     string   `json:"secret,omitempty" example:"whsec_3f1c9a7b2e5d4c8f"`
+	Active     *bool    `json:"active,omitempty" example:"true"`
+}
+
+func (r WebhookRequest) toModel() models.WebhookSubscription {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return models.WebhookSubscription{
+		URL:        r.URL,
+		EventTypes: r.EventTypes,
+		Secret:     r.Secret,
+		Active:     active,
+	}
+}
+
+// parseID reads a positive integer path parameter.
+func parseID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		utils.HandleIDError(c, err)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func handleServiceError(c *gin.Context, message string, err error) {
+	if errors.Is(err, repositories.ErrNotFound) {
+		utils.NotFound(c, message, err.Error())
+		return
+	}
+
+	utils.BadRequest(c, message, err.Error())
+}
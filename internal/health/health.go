@@ -0,0 +1,113 @@
+// Package health runs the dependency checks behind the readiness endpoint.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check probes one dependency. It returns an optional detail for operators,
+// such as the schema version found, and an error when the dependency is
+// not usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// LatencyMs is how long the check took, in milliseconds.
+	LatencyMs float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check. Its status is ok only when every
+// check passed.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker runs its checks concurrently, each bounded by the timeout.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Run runs every check and reports them in the order they were given.
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// run runs one check, giving up when the timeout passes even if the check
+// ignores its context, as a write to a stalled disk would.
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+
+	start := time.Now()
+	go func() {
+		detail, err := check.Run(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-ctx.Done():
+		o.err = ctx.Err()
+	}
+
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    o.detail,
+	}
+	if o.err != nil {
+		result.Status = StatusUnavailable
+		result.Error = o.err.Error()
+		if errors.Is(o.err, context.DeadlineExceeded) {
+			result.Error = "timed out after " + c.timeout.String()
+		}
+	}
+	return result
+}
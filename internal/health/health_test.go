@@ -0,0 +1,74 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/health"
+)
+
+func TestCheckerReportsChecksInOrder(t *testing.T) {
+	checker := health.NewChecker(time.Second,
+		health.Check{Name: "database", Run: func(context.Context) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "", nil
+		}},
+		health.Check{Name: "migrations", Run: func(context.Context) (string, error) {
+			return "version 012", nil
+		}},
+	)
+
+	report := checker.Run(context.Background())
+	if report.Status != health.StatusOK || len(report.Checks) != 2 {
+		t.Fatalf("Run = %+v, want two passing checks", report)
+	}
+	if database := report.Checks[0]; database.Name != "database" || database.Status != health.StatusOK || database.LatencyMs < 10 {
+		t.Errorf("first check = %+v, want the database taking 10ms or more", database)
+	}
+	if migrations := report.Checks[1]; migrations.Name != "migrations" || migrations.Detail != "version 012" {
+		t.Errorf("second check = %+v, want the migrations with their detail", migrations)
+	}
+}
+
+func TestCheckerFailsOnAnyFailingCheck(t *testing.T) {
+	checker := health.NewChecker(time.Second,
+		health.Check{Name: "database", Run: func(context.Context) (string, error) {
+			return "", nil
+		}},
+		health.Check{Name: "storage", Run: func(context.Context) (string, error) {
+			return "", errors.New("read-only file system")
+		}},
+	)
+
+	report := checker.Run(context.Background())
+	if report.Status != health.StatusUnavailable {
+		t.Errorf("status = %s, want %s", report.Status, health.StatusUnavailable)
+	}
+	if storage := report.Checks[1]; storage.Status != health.StatusUnavailable || storage.Error != "read-only file system" {
+		t.Errorf("storage check = %+v, want its error", storage)
+	}
+	if database := report.Checks[0]; database.Status != health.StatusOK {
+		t.Errorf("database check = %+v, want it to pass", database)
+	}
+}
+
+func TestCheckerTimesOutChecksIgnoringTheirContext(t *testing.T) {
+	stalled := make(chan struct{})
+	defer close(stalled)
+
+	checker := health.NewChecker(50*time.Millisecond, health.Check{Name: "storage", Run: func(context.Context) (string, error) {
+		<-stalled
+		return "", nil
+	}})
+
+	start := time.Now()
+	report := checker.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %v despite the 50ms timeout", elapsed)
+	}
+	if storage := report.Checks[0]; storage.Status != health.StatusUnavailable || storage.Error != "timed out after 50ms" {
+		t.Errorf("stalled check = %+v, want a timeout", storage)
+	}
+}
@@ -0,0 +1,73 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"todo-api/internal/config"
+	"todo-api/internal/logging"
+)
+
+func TestLinesNameTheRequestAndTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(config.LogConfig{Level: "info", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := logging.WithRequestID(context.Background(), "support-ticket-42")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	logger.With("component", "test").InfoContext(ctx, "Todo created", "todo_id", 7)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %q", buf.String())
+	}
+	for key, want := range map[string]interface{}{
+		"msg":        "Todo created",
+		"level":      "INFO",
+		"component":  "test",
+		"todo_id":    float64(7),
+		"request_id": "support-ticket-42",
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+}
+
+func TestLinesBelowTheLevelAreDropped(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(config.LogConfig{Level: "warn", Format: "text"}, &buf)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	logger.Info("routine")
+	logger.Warn("unusual")
+	if out := buf.String(); strings.Contains(out, "routine") || !strings.Contains(out, "msg=unusual") {
+		t.Errorf("logged %q, want only the warning as text", out)
+	}
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug enabled at warn level")
+	}
+}
+
+func TestNewRejectsUnknownLevelsAndFormats(t *testing.T) {
+	for _, cfg := range []config.LogConfig{
+		{Level: "verbose", Format: "json"},
+		{Level: "info", Format: "xml"},
+	} {
+		if _, err := logging.New(cfg, &bytes.Buffer{}); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
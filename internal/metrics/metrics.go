@@ -0,0 +1,147 @@
+// Package metrics collects Prometheus metrics about HTTP requests,
+// repository queries, the database pool and the todos themselves, and
+// serves them in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "todo_api"
+
+// collectTimeout bounds the queries run while a scrape is in progress.
+const collectTimeout = 5 * time.Second
+
+// Metrics holds the collectors of one server. Each server has a registry of
+// its own, so several can run in one process, as the tests do.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	queries  *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests being served.",
+		}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of repository calls by repository, method and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"repository", "method", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.queries,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RequestStarted counts a request in flight until the returned function is
+// called with its outcome. route is the matched route pattern, such as
+// /api/v1/todos/:id, so that IDs do not multiply the series.
+func (m *Metrics) RequestStarted() func(method, route string, status int) {
+	start := time.Now()
+	m.inFlight.Inc()
+
+	return func(method, route string, status int) {
+		m.inFlight.Dec()
+		code := strconv.Itoa(status)
+		m.requests.WithLabelValues(method, route, code).Inc()
+		m.duration.WithLabelValues(method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveQuery records how long a repository method took.
+func (m *Metrics) ObserveQuery(repository, method string, elapsed time.Duration, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	m.queries.WithLabelValues(repository, method, outcome).Observe(elapsed.Seconds())
+}
+
+// CollectDBStats exports the connection pool statistics of db.
+func (m *Metrics) CollectDBStats(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// TodoCounter counts the open and completed todos of every owner.
+type TodoCounter func(ctx context.Context) (open, completed int64, err error)
+
+// CollectTodos exports the number of todos by state, counted on every
+// scrape.
+func (m *Metrics) CollectTodos(count TodoCounter) {
+	m.registry.MustRegister(&todoCollector{
+		count: count,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "todos"),
+			"Todos by state.",
+			[]string{"state"}, nil,
+		),
+	})
+}
+
+type todoCollector struct {
+	count TodoCounter
+	desc  *prometheus.Desc
+}
+
+func (c *todoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *todoCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	open, completed, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(open), "open")
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(completed), "completed")
+}
@@ -0,0 +1,103 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/internal/metrics"
+)
+
+// scrape returns the exposition text m serves.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", w.Code)
+	}
+
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return string(body)
+}
+
+// expectMetrics fails unless body contains every line of want.
+func expectMetrics(t *testing.T, body string, want ...string) {
+	t.Helper()
+
+	for _, line := range want {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics lack %s", line)
+		}
+	}
+	if t.Failed() {
+		t.Logf("metrics:\n%s", body)
+	}
+}
+
+func TestMetricsCountRequestsInFlightAndDone(t *testing.T) {
+	m := metrics.New()
+
+	done := m.RequestStarted()
+	m.RequestStarted()("POST", "/api/v1/todos", http.StatusCreated)
+	m.RequestStarted()("POST", "/api/v1/todos", http.StatusCreated)
+	expectMetrics(t, scrape(t, m),
+		`todo_api_http_requests_total{method="POST",route="/api/v1/todos",status="201"} 2`,
+		`todo_api_http_request_duration_seconds_count{method="POST",route="/api/v1/todos",status="201"} 2`,
+		`todo_api_http_requests_in_flight 1`,
+	)
+
+	done("GET", "/api/v1/todos/:id", http.StatusNotFound)
+	expectMetrics(t, scrape(t, m),
+		`todo_api_http_requests_total{method="GET",route="/api/v1/todos/:id",status="404"} 1`,
+		`todo_api_http_requests_in_flight 0`,
+	)
+}
+
+func TestMetricsObserveQueriesByOutcome(t *testing.T) {
+	m := metrics.New()
+
+	m.ObserveQuery("todo", "Create", time.Millisecond, nil)
+	m.ObserveQuery("todo", "Create", 2*time.Millisecond, nil)
+	m.ObserveQuery("todo", "GetByID", time.Millisecond, errors.New("database is locked"))
+
+	expectMetrics(t, scrape(t, m),
+		`todo_api_repository_query_duration_seconds_count{method="Create",outcome="ok",repository="todo"} 2`,
+		`todo_api_repository_query_duration_seconds_count{method="GetByID",outcome="error",repository="todo"} 1`,
+	)
+}
+
+func TestMetricsCountTodosOnEveryScrape(t *testing.T) {
+	m := metrics.New()
+	open := int64(3)
+	m.CollectTodos(func(context.Context) (int64, int64, error) {
+		return open, 1, nil
+	})
+
+	expectMetrics(t, scrape(t, m), `todo_api_todos{state="open"} 3`, `todo_api_todos{state="completed"} 1`)
+
+	open = 5
+	expectMetrics(t, scrape(t, m), `todo_api_todos{state="open"} 5`)
+}
+
+func TestMetricsReportFailedTodoCounts(t *testing.T) {
+	m := metrics.New()
+	m.CollectTodos(func(context.Context) (int64, int64, error) {
+		return 0, 0, errors.New("database is closed")
+	})
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "database is closed") {
+		t.Errorf("GET /metrics with a failing count: %d %s, want 500 with the error", w.Code, w.Body)
+	}
+}
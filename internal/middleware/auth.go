@@ -0,0 +1,95 @@
+// Package middleware holds the Gin middleware shared by the API routes.
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/auth"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+// Authenticate requires a valid API key or JWT on every request, given as a
+// bearer token or, for GET requests from browsers that cannot set headers on
+// EventSource and WebSocket connections, as the access_token query
+// parameter. The caller is stored as an auth.Principal in the request
+// context.
+func Authenticate(authenticator services.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := bearerToken(c.Request)
+		if secret == "" {
+			utils.Unauthorized(c, "Authentication required", "send an API key as a bearer token")
+			c.Abort()
+			return
+		}
+
+		principal, err := authenticator.Authenticate(c.Request.Context(), secret)
+		if err != nil {
+			if errors.Is(err, services.ErrUnauthorized) {
+				utils.Unauthorized(c, "Invalid token", err.Error())
+			} else {
+				utils.InternalServerError(c, "Failed to authenticate", err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		ctx := auth.NewContext(c.Request.Context(), principal)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequireScope rejects callers that were not granted scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkScope(c, scope)
+	}
+}
+
+// RequireReadWrite requires the read scope for safe methods and the write
+// scope for everything else.
+func RequireReadWrite(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isSafeMethod(c.Request.Method) {
+			checkScope(c, read)
+		} else {
+			checkScope(c, write)
+		}
+	}
+}
+
+func checkScope(c *gin.Context, scope string) {
+	p, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		utils.Unauthorized(c, "Authentication required", "send an API key as a bearer token")
+		c.Abort()
+		return
+	}
+
+	if !p.HasScope(scope) {
+		utils.Forbidden(c, "Insufficient scope", "this request requires the "+scope+" scope")
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	if r.Method == http.MethodGet {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/config"
+	"todo-api/pkg/utils"
+)
+
+// corsExposedHeaders are the response headers browsers let scripts read.
+var corsExposedHeaders = strings.Join([]string{
+	"X-Request-ID",
+	"X-Total-Count",
+	"Retry-After",
+	"RateLimit-Policy",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	IdempotentReplayedHeader,
+}, ", ")
+
+// CORS lets browser scripts from the configured origins call the API.
+// Preflight requests are answered here, with 403 Forbidden for origins that
+// are not allowed; other requests from such origins get no CORS headers, so
+// the browser withholds the response. An origin of "*" allows any origin.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(allowed) == 0 {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !allowed[origin] && !allowed["*"] {
+			if preflight {
+				utils.Forbidden(c, "Origin not allowed", "add "+origin+" to TODO_CORS_ALLOWED_ORIGINS")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowed["*"] && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", methods)
+		c.Header("Access-Control-Allow-Headers", headers)
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// WebSocketOrigin returns the CheckOrigin of a WebSocket upgrader. Browsers
+// may open sockets from the API's own origin and from the origins CORS
+// allows; clients other than browsers send no Origin and are let through.
+func WebSocketOrigin(cfg config.CORSConfig) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed[origin] || allowed["*"] {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
@@ -0,0 +1,110 @@
+package middleware_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/config"
+	"todo-api/internal/middleware"
+)
+
+// corsRouter serves GET and POST /todos behind CORS with cfg, allowing the
+// methods and headers the API allows.
+func corsRouter(cfg config.CORSConfig) *gin.Engine {
+	cfg.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	cfg.AllowedHeaders = []string{"Authorization", "Content-Type", "Idempotency-Key"}
+	cfg.MaxAge = 10 * time.Minute
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.CORS(cfg))
+	r.GET("/todos", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/todos", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r
+}
+
+func TestCORSAnswersPreflights(t *testing.T) {
+	r := corsRouter(config.CORSConfig{AllowedOrigins: []string{"https://app.example.com/"}})
+
+	w := send(r, http.MethodOptions, "/todos", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "content-type,idempotency-key",
+	})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight = %d, want 204", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "GET, POST, PUT, DELETE, OPTIONS",
+		"Access-Control-Allow-Headers":     "Authorization, Content-Type, Idempotency-Key",
+		"Access-Control-Max-Age":           "600",
+		"Access-Control-Allow-Credentials": "",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if vary := strings.Join(w.Header().Values("Vary"), ","); vary != "Origin,Access-Control-Request-Method,Access-Control-Request-Headers" {
+		t.Errorf("Vary = %q, want the origin and the requested method and headers", vary)
+	}
+
+	evil := send(r, http.MethodOptions, "/todos", map[string]string{
+		"Origin":                        "https://evil.example.com",
+		"Access-Control-Request-Method": "DELETE",
+	})
+	if evil.Code != http.StatusForbidden || evil.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight from another origin = %d %v, want 403 without CORS headers", evil.Code, evil.Header())
+	}
+}
+
+func TestCORSMarksResponsesToAllowedOrigins(t *testing.T) {
+	r := corsRouter(config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+
+	w := send(r, http.MethodGet, "/todos", map[string]string{"Origin": "https://app.example.com"})
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		!strings.Contains(w.Header().Get("Access-Control-Expose-Headers"), "X-Total-Count") ||
+		!strings.Contains(w.Header().Get("Access-Control-Expose-Headers"), middleware.IdempotentReplayedHeader) ||
+		w.Header().Get("Vary") != "Origin" {
+		t.Errorf("GET from the allowed origin = %d %v", w.Code, w.Header())
+	}
+
+	// Other origins are served without CORS headers, so browsers withhold
+	// the response.
+	w = send(r, http.MethodGet, "/todos", map[string]string{"Origin": "https://evil.example.com"})
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("GET from another origin = %d %v, want no CORS headers", w.Code, w.Header())
+	}
+
+	// Requests not sent by browsers are left alone.
+	if w := send(r, http.MethodGet, "/todos", nil); w.Header().Get("Vary") != "" {
+		t.Errorf("GET without an origin = %v, want no CORS headers", w.Header())
+	}
+}
+
+func TestCORSWithAnyOrigin(t *testing.T) {
+	headers := map[string]string{"Origin": "https://app.example.com"}
+
+	if w := send(corsRouter(config.CORSConfig{AllowedOrigins: []string{"*"}}), http.MethodGet, "/todos", headers); w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Credentials require the origin itself.
+	w := send(corsRouter(config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}), http.MethodGet, "/todos", headers)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("with credentials: %v, want the origin echoed", w.Header())
+	}
+}
+
+func TestCORSWithoutOriginsIsOff(t *testing.T) {
+	w := send(corsRouter(config.CORSConfig{}), http.MethodOptions, "/todos", map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": "POST",
+	})
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "" {
+		t.Errorf("preflight without allowed origins = %d %v, want no CORS headers", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/services"
+	"todo-api/pkg/utils"
+)
+
+const (
+	// IdempotencyKeyHeader makes a POST request safe to retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from an earlier
+	// request with the same key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Idempotency answers a POST request carrying an Idempotency-Key header
+// that was already handled with the stored response, instead of running
+// it again. A key reused with a different method, path or body gets 422
+// Unprocessable Entity, and one sent again while its first request is
+// still running 409 Conflict. Responses that invite a retry, 401, 403, 429
+// and 5xx, are not stored.
+//
+// Mount it on the routes that create resources from a JSON body, after
+// authentication and the scope checks. Multipart bodies, which may be
+// large uploads, are passed through without a key.
+func Idempotency(service services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" || strings.HasPrefix(c.ContentType(), "multipart/") {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.HandleBodyError(c, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		stored, err := service.Begin(ctx, key, fingerprint(c.Request, body))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidInput):
+				utils.BadRequest(c, "Invalid idempotency key", err.Error())
+			case errors.Is(err, services.ErrKeyReused):
+				utils.UnprocessableEntity(c, "Idempotency key reused", err.Error())
+			case errors.Is(err, services.ErrRequestInFlight):
+				utils.Conflict(c, "Request in progress", err.Error())
+			default:
+				utils.InternalServerError(c, "Failed to check idempotency key", err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		if stored != nil {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Release the key unless the response is stored, also when a
+		// handler panics, so the request can be retried.
+		kept := false
+		defer func() {
+			if kept {
+				return
+			}
+			if err := service.Release(ctx, key); err != nil {
+				slog.ErrorContext(ctx, "Failed to release idempotency key", "error", err)
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if !storable(status) {
+			return
+		}
+		if err := service.Complete(ctx, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			slog.ErrorContext(ctx, "Failed to store idempotent response", "error", err)
+			return
+		}
+		kept = true
+	}
+}
+
+// storable reports whether a response with status answers every retry of
+// its request. Those refused for missing credentials or permissions, or
+// for load, may succeed when retried.
+func storable(status int) bool {
+	switch {
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return false
+	case status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		return false
+	default:
+		return true
+	}
+}
+
+// fingerprint hashes what makes a request the same request: its method,
+// path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body while writing it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
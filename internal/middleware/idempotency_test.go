@@ -0,0 +1,284 @@
+package middleware_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/auth"
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/middleware"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// idempotentRouter serves POST /todos behind the Idempotency middleware,
+// backed by a migrated SQLite database, authenticating requests as the user
+// in the X-User header. The handler answers with the status in the X-Status
+// header, 201 without one, and the size of the body it read, and counts its
+// runs.
+type idempotentRouter struct {
+	*gin.Engine
+	db   *database.DB
+	runs atomic.Int32
+	// block, when set, holds the handler until it is closed.
+	block chan struct{}
+}
+
+func newIdempotentRouter(t *testing.T) *idempotentRouter {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todos.db")
+	cfg := &config.DatabaseConfig{
+		Path:          path,
+		DSN:           path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+		MigrationsDir: filepath.Join("..", "..", "migrations"),
+	}
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(cfg.MigrationsDir); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	ir := &idempotentRouter{Engine: gin.New(), db: db}
+	service := services.NewIdempotencyService(repositories.NewIdempotencyRepository(db), time.Hour)
+	ir.Use(func(c *gin.Context) {
+		if user, _ := strconv.ParseInt(c.GetHeader("X-User"), 10, 64); user != 0 {
+			c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), &auth.Principal{UserID: user}))
+		}
+	}, middleware.Idempotency(service))
+	ir.POST("/todos", func(c *gin.Context) {
+		run := ir.runs.Add(1)
+		if ir.block != nil {
+			<-ir.block
+		}
+		status := http.StatusCreated
+		if raw := c.GetHeader("X-Status"); raw != "" {
+			status, _ = strconv.Atoi(raw)
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(status, gin.H{"run": run, "bytes": len(body)})
+	})
+	return ir
+}
+
+func (ir *idempotentRouter) post(key, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	ir.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysStoredResponses(t *testing.T) {
+	ir := newIdempotentRouter(t)
+
+	first := ir.post("retry-1", `{"title": "buy milk"}`, nil)
+	if first.Code != http.StatusCreated || first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Fatalf("first POST = %d %v, want 201 not replayed", first.Code, first.Header())
+	}
+
+	retry := ir.post("retry-1", `{"title": "buy milk"}`, nil)
+	if retry.Code != http.StatusCreated || retry.Header().Get(middleware.IdempotentReplayedHeader) != "true" ||
+		retry.Body.String() != first.Body.String() || retry.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Fatalf("retried POST = %d %v %s, want the replayed %s", retry.Code, retry.Header(), retry.Body, first.Body)
+	}
+
+	ir.post("", `{"title": "buy milk"}`, nil)
+	ir.post("retry-2", `{"title": "buy milk"}`, nil)
+	if runs := ir.runs.Load(); runs != 3 {
+		t.Errorf("handler ran %d times, want once per key and once without", runs)
+	}
+}
+
+func TestIdempotencyRejectsKeysReusedForAnotherRequest(t *testing.T) {
+	ir := newIdempotentRouter(t)
+	ir.post("retry-1", `{"title": "buy milk"}`, nil)
+
+	if w := ir.post("retry-1", `{"title": "buy eggs"}`, nil); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("POST reusing the key with another body = %d, want 422", w.Code)
+	}
+	if w := ir.post(strings.Repeat("k", services.MaxIdempotencyKeyLength+1), `{}`, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("POST with an oversized key = %d, want 400", w.Code)
+	}
+	if runs := ir.runs.Load(); runs != 1 {
+		t.Errorf("handler ran %d times, want once", runs)
+	}
+}
+
+func TestIdempotencyAnswersConflictWhileInFlight(t *testing.T) {
+	ir := newIdempotentRouter(t)
+	ir.block = make(chan struct{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- ir.post("slow", `{"title": "in flight"}`, nil)
+	}()
+	for ir.runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if w := ir.post("slow", `{"title": "in flight"}`, nil); w.Code != http.StatusConflict {
+		t.Errorf("POST while the key is in flight = %d, want 409", w.Code)
+	}
+	close(ir.block)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("first POST = %d, want 201", w.Code)
+	}
+	if w := ir.post("slow", `{"title": "in flight"}`, nil); w.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+		t.Errorf("POST after completion = %d %v, want a replay", w.Code, w.Header())
+	}
+}
+
+func TestIdempotencyLeasesKeysInFlight(t *testing.T) {
+	ir := newIdempotentRouter(t)
+	ir.block = make(chan struct{})
+
+	// expiresWithin reports whether the key expires within d from now.
+	expiresWithin := func(d time.Duration) bool {
+		t.Helper()
+		var within bool
+		err := ir.db.QueryRow(fmt.Sprintf(`SELECT expires_at <= datetime('now', '+%d seconds') FROM idempotency_keys WHERE idempotency_key = 'stuck'`, int(d.Seconds()))).Scan(&within)
+		if err != nil {
+			t.Fatalf("SELECT expires_at: %v", err)
+		}
+		return within
+	}
+
+	done := make(chan *httptest.ResponseRecorder, 2)
+	go func() {
+		done <- ir.post("stuck", `{"title": "in flight"}`, nil)
+	}()
+	for ir.runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if !expiresWithin(time.Minute) {
+		t.Error("key in flight is held for longer than a minute")
+	}
+
+	// Once the lease runs out, a retry claims the key and runs.
+	if _, err := ir.db.Exec(`UPDATE idempotency_keys SET expires_at = datetime('now', '-1 second') WHERE idempotency_key = 'stuck'`); err != nil {
+		t.Fatalf("UPDATE: %v", err)
+	}
+	go func() {
+		done <- ir.post("stuck", `{"title": "in flight"}`, nil)
+	}()
+	for ir.runs.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(ir.block)
+	for range 2 {
+		if w := <-done; w.Code != http.StatusCreated {
+			t.Errorf("POST = %d, want 201", w.Code)
+		}
+	}
+
+	if expiresWithin(time.Minute) {
+		t.Error("stored response is kept for no longer than the lease")
+	}
+}
+
+func TestIdempotencySkipsMultipartBodies(t *testing.T) {
+	ir := newIdempotentRouter(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "notes.txt")
+	part.Write([]byte("buy milk"))
+	form.Close()
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set(middleware.IdempotencyKeyHeader, "upload")
+		w := httptest.NewRecorder()
+		ir.ServeHTTP(w, req)
+
+		want := fmt.Sprintf(`"bytes":%d`, body.Len())
+		if w.Code != http.StatusCreated || w.Header().Get(middleware.IdempotentReplayedHeader) != "" || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("multipart POST = %d %v %s, want 201 not replayed with %s", w.Code, w.Header(), w.Body, want)
+		}
+	}
+
+	var stored int
+	if err := ir.db.QueryRow(`SELECT COUNT(*) FROM idempotency_keys`).Scan(&stored); err != nil || stored != 0 {
+		t.Errorf("%d keys stored for multipart requests (%v), want none", stored, err)
+	}
+	if runs := ir.runs.Load(); runs != 2 {
+		t.Errorf("handler ran %d times, want twice", runs)
+	}
+}
+
+func TestIdempotencyRunsConcurrentDuplicatesOnce(t *testing.T) {
+	ir := newIdempotentRouter(t)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = ir.post("burst", `{"title": "only once"}`, nil).Code
+		}()
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		if code != http.StatusCreated && code != http.StatusConflict {
+			t.Errorf("concurrent POST = %d, want 201 or 409", code)
+		}
+	}
+	if runs := ir.runs.Load(); runs != 1 {
+		t.Errorf("handler ran %d times, want once", runs)
+	}
+}
+
+func TestIdempotencyForgetsFailuresAndExpiredKeys(t *testing.T) {
+	ir := newIdempotentRouter(t)
+
+	for _, status := range []string{"500", "429", "401", "403"} {
+		ir.post("flaky", `{}`, map[string]string{"X-Status": status})
+	}
+	if w := ir.post("flaky", `{}`, nil); w.Code != http.StatusCreated || ir.runs.Load() != 5 {
+		t.Errorf("POST after a 500, 429, 401 and 403 = %d after %d runs, want 201 after running again", w.Code, ir.runs.Load())
+	}
+
+	if _, err := ir.db.Exec(`UPDATE idempotency_keys SET expires_at = datetime('now', '-1 second') WHERE idempotency_key = 'flaky'`); err != nil {
+		t.Fatalf("UPDATE: %v", err)
+	}
+	if w := ir.post("flaky", `{}`, nil); w.Header().Get(middleware.IdempotentReplayedHeader) != "" || ir.runs.Load() != 6 {
+		t.Errorf("POST with an expired key was replayed, want it to run again")
+	}
+}
+
+func TestIdempotencyKeysBelongToTheCaller(t *testing.T) {
+	ir := newIdempotentRouter(t)
+
+	alice := ir.post("same-key", `{"title": "mine"}`, map[string]string{"X-User": "1"})
+	bob := ir.post("same-key", `{"title": "mine"}`, map[string]string{"X-User": "2"})
+	if alice.Code != http.StatusCreated || bob.Code != http.StatusCreated || bob.Header().Get(middleware.IdempotentReplayedHeader) != "" || ir.runs.Load() != 2 {
+		t.Errorf("alice = %d, bob = %d %v; want a run each", alice.Code, bob.Code, bob.Header())
+	}
+}
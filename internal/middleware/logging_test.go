@@ -0,0 +1,149 @@
+package middleware_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/config"
+	"todo-api/internal/logging"
+	"todo-api/internal/middleware"
+	"todo-api/pkg/utils"
+)
+
+// logBuffer collects the lines logged by the handlers under test.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the decoded JSON lines naming requestID.
+func (b *logBuffer) lines(t *testing.T, requestID string) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("log line is not JSON: %q", scanner.Text())
+		}
+		if line["request_id"] == requestID {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// captureLogs makes the default logger write debug JSON lines to a buffer
+// for the rest of the test.
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+
+	logs := &logBuffer{}
+	logger, err := logging.New(config.LogConfig{Level: "debug", Format: "json"}, logs)
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return logs
+}
+
+// loggedRouter serves a few routes behind the logging middleware.
+func loggedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.Logger(), middleware.Recovery())
+	r.POST("/todos", func(c *gin.Context) {
+		slog.InfoContext(c.Request.Context(), "Todo created", "todo_id", 1)
+		c.Status(http.StatusCreated)
+	})
+	r.GET("/todos/:id", func(c *gin.Context) {
+		utils.NotFound(c, "Todo not found", "no todo with id "+c.Param("id"))
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return r
+}
+
+func TestRequestIDsAreKeptOrGenerated(t *testing.T) {
+	captureLogs(t)
+	r := loggedRouter()
+
+	if got := send(r, http.MethodPost, "/todos", map[string]string{logging.RequestIDHeader: "support-ticket-42"}).Header().Get(logging.RequestIDHeader); got != "support-ticket-42" {
+		t.Errorf("X-Request-ID = %q, want the caller's", got)
+	}
+
+	for _, sent := range []string{"", "fake\nline", "fake" + strings.Repeat("x", 200)} {
+		w := send(r, http.MethodGet, "/todos/999", map[string]string{logging.RequestIDHeader: sent})
+
+		var body utils.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decoding %s: %v", w.Body, err)
+		}
+		if id := w.Header().Get(logging.RequestIDHeader); len(id) != 32 || body.RequestID != id {
+			t.Errorf("sent %q: X-Request-ID %q and body request_id %q, want the same generated ID", sent, id, body.RequestID)
+		}
+	}
+}
+
+func TestLoggerLevelsRequestLinesByStatus(t *testing.T) {
+	logs := captureLogs(t)
+	r := loggedRouter()
+
+	for _, tc := range []struct {
+		method, path, requestID string
+		route, level            string
+		status                  float64
+	}{
+		{http.MethodPost, "/todos", "created", "/todos", "INFO", 201},
+		{http.MethodGet, "/todos/999", "missing", "/todos/:id", "WARN", 404},
+		{http.MethodGet, "/panic", "panicked", "/panic", "ERROR", 500},
+	} {
+		send(r, tc.method, tc.path, map[string]string{logging.RequestIDHeader: tc.requestID})
+
+		messages := map[string]map[string]interface{}{}
+		for _, line := range logs.lines(t, tc.requestID) {
+			messages[line["msg"].(string)] = line
+		}
+		if line := messages["request"]; line == nil || line["status"] != tc.status || line["route"] != tc.route || line["level"] != tc.level {
+			t.Errorf("%s %s: request line = %v, want %v at %s", tc.method, tc.path, line, tc.status, tc.level)
+		}
+	}
+
+	// Lines logged while serving a request name it too.
+	if lines := logs.lines(t, "created"); len(lines) != 2 || lines[0]["msg"] != "Todo created" {
+		t.Errorf("lines of the created request = %v, want the handler's and the request line", lines)
+	}
+	if lines := logs.lines(t, "panicked"); len(lines) == 0 || lines[0]["panic"] != "boom" {
+		t.Errorf("lines of the panicking request = %v, want the panic first", lines)
+	}
+}
+
+func TestRecoveryAnswersPanicsWithJSON(t *testing.T) {
+	captureLogs(t)
+
+	w := send(loggedRouter(), http.MethodGet, "/panic", nil)
+	var body utils.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); w.Code != http.StatusInternalServerError || err != nil || body.RequestID == "" {
+		t.Errorf("panic answered %d %s, want a 500 error body with the request ID", w.Code, w.Body)
+	}
+}
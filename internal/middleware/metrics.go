@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/metrics"
+)
+
+// Metrics records the count, latency and concurrency of requests by
+// method, matched route and status. Requests matching no route are
+// recorded under the route "unmatched".
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := m.RequestStarted()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		done(c.Request.Method, route, c.Writer.Status())
+	}
+}
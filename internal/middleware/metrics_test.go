@@ -0,0 +1,36 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/metrics"
+	"todo-api/internal/middleware"
+)
+
+func TestMetricsRecordRoutePatterns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New()
+	r := gin.New()
+	r.Use(middleware.Metrics(m))
+	r.GET("/api/v1/todos/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/v1/todos/1", "/api/v1/todos/2", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`todo_api_http_requests_total{method="GET",route="/api/v1/todos/:id",status="200"} 2`,
+		`todo_api_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`todo_api_http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/auth"
+	"todo-api/internal/ratelimit"
+	"todo-api/pkg/utils"
+)
+
+// RateLimitByIP rejects clients, identified by IP, that exceed limiter with
+// 429 Too Many Requests and a Retry-After header.
+func RateLimitByIP(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit(c, limiter, c.ClientIP())
+	}
+}
+
+// RateLimit limits each client to reads for safe methods and writes for
+// everything else. Clients are identified by the user or API key of the
+// principal stored by Authenticate, or by IP without one. A nil limiter
+// leaves its requests unlimited.
+func RateLimit(reads, writes *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := writes
+		if isSafeMethod(c.Request.Method) {
+			limiter = reads
+		}
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		limit(c, limiter, clientKey(c))
+	}
+}
+
+// clientKey names the bucket of the caller, shared with its gRPC calls.
+func clientKey(c *gin.Context) string {
+	if key := auth.LimitKey(c.Request.Context()); key != "" {
+		return key
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// limit takes a token from the bucket of key, describing the bucket in the
+// RateLimit-* headers of the IETF draft, and rejects the request with 429
+// Too Many Requests and a Retry-After header when none is left.
+func limit(c *gin.Context, limiter *ratelimit.Limiter, key string) {
+	result := limiter.Allow(key)
+	c.Header("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+seconds(limiter.Period()))
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", seconds(result.Reset))
+
+	if !result.Allowed {
+		retryAfter := seconds(result.RetryAfter)
+		c.Header("Retry-After", retryAfter)
+		utils.TooManyRequests(c, "Rate limit exceeded", "retry after "+retryAfter+" seconds")
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// seconds rounds d up to whole seconds, as the headers expect.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
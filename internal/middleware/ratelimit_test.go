@@ -0,0 +1,136 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/auth"
+	"todo-api/internal/middleware"
+	"todo-api/internal/ratelimit"
+	"todo-api/pkg/utils"
+)
+
+// limitedRouter limits /todos with reads and writes, authenticating
+// requests as the user or API key in the X-User and X-Key headers.
+func limitedRouter(reads, writes *ratelimit.Limiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		user, _ := strconv.ParseInt(c.GetHeader("X-User"), 10, 64)
+		key, _ := strconv.ParseInt(c.GetHeader("X-Key"), 10, 64)
+		if user != 0 || key != 0 {
+			c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), &auth.Principal{UserID: user, APIKeyID: key}))
+		}
+	}, middleware.RateLimit(reads, writes))
+	r.GET("/todos", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/todos", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r
+}
+
+// send serves a request without a body to r and returns the response.
+func send(r http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitDescribesTheBucket(t *testing.T) {
+	r := limitedRouter(ratelimit.New(3, time.Minute), nil)
+
+	w := send(r, http.MethodGet, "/todos", nil)
+	for header, want := range map[string]string{
+		"RateLimit-Policy":    "3;w=60",
+		"RateLimit-Limit":     "3",
+		"RateLimit-Remaining": "2",
+		"RateLimit-Reset":     "20",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	send(r, http.MethodGet, "/todos", nil)
+	send(r, http.MethodGet, "/todos", nil)
+	w = send(r, http.MethodGet, "/todos", nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "20" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("fourth read = %d with headers %v, want 429 retrying after 20s", w.Code, w.Header())
+	}
+	var body utils.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error != "Rate limit exceeded" {
+		t.Errorf("429 body = %s, want the rate limit error", w.Body)
+	}
+}
+
+func TestRateLimitSeparatesReadsWritesAndClients(t *testing.T) {
+	r := limitedRouter(ratelimit.New(1, time.Minute), ratelimit.New(1, time.Minute))
+	alice := map[string]string{"X-User": "1"}
+
+	if w := send(r, http.MethodGet, "/todos", alice); w.Code != http.StatusOK {
+		t.Fatalf("alice's read = %d", w.Code)
+	}
+	if w := send(r, http.MethodPost, "/todos", alice); w.Code != http.StatusCreated {
+		t.Fatalf("alice's write after her read = %d, want a bucket of its own", w.Code)
+	}
+	if w := send(r, http.MethodGet, "/todos", alice); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("alice's second read = %d, want 429", w.Code)
+	}
+
+	// Every key of a user shares the user's bucket; keys without a user and
+	// anonymous callers have their own.
+	if w := send(r, http.MethodGet, "/todos", map[string]string{"X-User": "1", "X-Key": "9"}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("alice's read with another key = %d, want 429", w.Code)
+	}
+	for name, headers := range map[string]map[string]string{
+		"bob":       {"X-User": "2"},
+		"admin key": {"X-Key": "9"},
+		"anonymous": nil,
+	} {
+		if w := send(r, http.MethodGet, "/todos", headers); w.Code != http.StatusOK {
+			t.Errorf("%s's read = %d, want 200", name, w.Code)
+		}
+	}
+}
+
+func TestRateLimitWithoutLimiterIsUnlimited(t *testing.T) {
+	r := limitedRouter(nil, ratelimit.New(1, time.Minute))
+
+	for i := 0; i < 5; i++ {
+		if w := send(r, http.MethodGet, "/todos", nil); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("read %d = %d with headers %v, want unlimited", i+1, w.Code, w.Header())
+		}
+	}
+}
+
+func TestRateLimitByIPBelievesTrustedProxiesOnly(t *testing.T) {
+	for _, tc := range []struct {
+		proxy   string
+		allowed bool
+	}{
+		{"192.0.2.1", true},
+		{"10.0.0.1", false},
+	} {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		if err := r.SetTrustedProxies([]string{tc.proxy}); err != nil {
+			t.Fatalf("SetTrustedProxies: %v", err)
+		}
+		r.Use(middleware.RateLimitByIP(ratelimit.New(1, time.Minute)))
+		r.POST("/todos", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+		// httptest requests come from 192.0.2.1.
+		send(r, http.MethodPost, "/todos", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+		w := send(r, http.MethodPost, "/todos", map[string]string{"X-Forwarded-For": "203.0.113.8"})
+		if allowed := w.Code == http.StatusCreated; allowed != tc.allowed {
+			t.Errorf("trusting %s, a second forwarded client got %d, want allowed %v", tc.proxy, w.Code, tc.allowed)
+		}
+	}
+}
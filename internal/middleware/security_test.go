@@ -0,0 +1,117 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/middleware"
+	"todo-api/pkg/utils"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.SecurityHeaders(180*24*time.Hour, nil))
+	r.GET("/api/v1/todos", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/swagger/*any", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil))
+	for header, want := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+		"Strict-Transport-Security": "",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// The Swagger UI may run its own scripts and inline styles.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'self'") || !strings.Contains(csp, "'unsafe-inline'") {
+		t.Errorf("Swagger UI CSP = %q", csp)
+	}
+}
+
+func TestSecurityHeadersTrustForwardedProtoFromProxiesOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.SecurityHeaders(180*24*time.Hour, []string{"10.0.0.0/8", "::1"}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, tc := range []struct {
+		remoteAddr string
+		proto      string
+		want       string
+	}{
+		{"10.1.2.3:4567", "https", "max-age=15552000; includeSubDomains"},
+		{"[::1]:4567", "https", "max-age=15552000; includeSubDomains"},
+		{"10.1.2.3:4567", "http", ""},
+		{"10.1.2.3:4567", "", ""},
+		{"203.0.113.7:4567", "https", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Strict-Transport-Security"); got != tc.want {
+			t.Errorf("from %s with X-Forwarded-Proto %q: Strict-Transport-Security = %q, want %q", tc.remoteAddr, tc.proto, got, tc.want)
+		}
+	}
+}
+
+// limitedBodies serves POST routes reading their body behind BodyLimit,
+// with a larger limit for uploads.
+func limitedBodies() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.BodyLimit(1024, map[string]int64{"/todos/:id/attachments": 8192}))
+
+	read := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			utils.HandleBodyError(c, err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	}
+	r.POST("/todos", read)
+	r.POST("/todos/:id/attachments", read)
+	return r
+}
+
+func TestBodyLimitRejectsOversizedBodies(t *testing.T) {
+	r := limitedBodies()
+	big := strings.Repeat("x", 2048)
+
+	for _, tc := range []struct {
+		name string
+		path string
+		body io.Reader
+		want int
+	}{
+		{"a small body", "/todos", strings.NewReader("{}"), http.StatusCreated},
+		{"a large Content-Length", "/todos", strings.NewReader(big), http.StatusRequestEntityTooLarge},
+		// Without a Content-Length the body is cut off while it is read.
+		{"a large chunked body", "/todos", io.MultiReader(strings.NewReader(big)), http.StatusRequestEntityTooLarge},
+		{"an upload under its own limit", "/todos/1/attachments", strings.NewReader(big), http.StatusCreated},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, tc.body))
+		if w.Code != tc.want {
+			t.Errorf("POST with %s = %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+}
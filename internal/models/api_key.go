@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// API key scopes
+const (
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+	// ScopeAdmin grants every other scope as well as key and webhook management.
+	ScopeAdmin = "admin"
+)
+
+// APIKey is a bearer token granting access to the API with a set of scopes.
+// Keys issued by login belong to a user; keys created by admins do not.
+type APIKey struct {
+	ID         int64      `json:"id" db:"id" example:"1"`
+	Name       string     `json:"name" db:"name" example:"ci-pipeline"`
+	Prefix     string     `json:"prefix" db:"prefix" example:"todo_3f1c9a7b"`
+	Scopes     []string   `json:"scopes" db:"scopes" example:"todos:read,todos:write"`
+	UserID     *int64     `json:"user_id,omitempty" db:"user_id" example:"1"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at" example:"2027-02-16T09:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at" example:"2026-02-16T09:30:00Z"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+	// Secret is the token itself. Only its hash is stored, so it is only
+	// returned when the key is created.
+	Secret string `json:"secret,omitempty" db:"-" example:"todo_3f1c9a7b2e5d4c8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key grants scope. The admin scope grants
+// every scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
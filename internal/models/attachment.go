@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Attachment describes a file attached to a todo. The content lives in the
+// blob store under its SHA-256 hash.
+type Attachment struct {
+	ID          int64     `json:"id" db:"id" example:"1"`
+	TodoID      int64     `json:"todo_id" db:"todo_id" example:"1"`
+	Filename    string    `json:"filename" db:"filename" example:"receipt.pdf"`
+	ContentType string    `json:"content_type" db:"content_type" example:"application/pdf"`
+	Size        int64     `json:"size" db:"size" example:"48213"`
+	SHA256      string    `json:"sha256" db:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+}
+
+func (Attachment) TableName() string {
+	return "attachments"
+}
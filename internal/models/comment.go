@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Comment represents a markdown comment on a todo, optionally replying to another comment
+type Comment struct {
+	ID              int64      `json:"id" db:"id" example:"1"`
+	TodoID          int64      `json:"todo_id" db:"todo_id" example:"1"`
+	ParentCommentID *int64     `json:"parent_comment_id,omitempty" db:"parent_comment_id" example:"1"`
+	Author          string     `json:"author" db:"author" validate:"required,max=100" example:"alice"`
+	Body            string     `json:"body" db:"body" validate:"required,max=2000" example:"Picked up **milk**, still need eggs"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at" example:"2026-02-16T09:00:00Z"`
+	EditedAt        *time.Time `json:"edited_at,omitempty" db:"edited_at" example:"2026-02-16T09:30:00Z"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" db:"deleted_at" example:"2026-02-16T10:00:00Z"`
+}
+
+func (Comment) TableName() string {
+	return "comments"
+}
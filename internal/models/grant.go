@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Roles a todo can be shared with, from least to most privileged
+const (
+	// RoleViewer reads the todo, its comments and attachments.
+	RoleViewer = "viewer"
+	// RoleEditor also changes the todo, comments on it and attaches files.
+	RoleEditor = "editor"
+	// RoleAdmin also deletes the todo and shares it with others.
+	RoleAdmin = "admin"
+)
+
+// Grant gives a user a role on a todo owned by someone else.
+type Grant struct {
+	TodoID    int64     `json:"todo_id" db:"todo_id" example:"1"`
+	UserID    int64     `json:"user_id" db:"user_id" example:"2"`
+	Email     string    `json:"email" db:"email" example:"grace@example.com"`
+	Role      string    `json:"role" db:"role" example:"editor"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+}
+
+func (Grant) TableName() string {
+	return "todo_grants"
+}
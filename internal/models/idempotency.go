@@ -0,0 +1,20 @@
+package models
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key header. StatusCode is zero while the request is in flight.
+type IdempotencyRecord struct {
+	// Scope names the caller the key belongs to, so callers cannot replay
+	// each other's responses.
+	Scope string `db:"scope"`
+	Key   string `db:"idempotency_key"`
+	// Fingerprint is a hash of the request, telling a retry from another
+	// request reusing the key.
+	Fingerprint string `db:"fingerprint"`
+	StatusCode  int    `db:"status_code"`
+	ContentType string `db:"content_type"`
+	Body        []byte `db:"body"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ShareLink opens a read-only view of a todo to anyone holding its token,
+// without an account.
+type ShareLink struct {
+	ID             int64      `json:"id" db:"id" example:"1"`
+	TodoID         int64      `json:"todo_id" db:"todo_id" example:"1"`
+	Prefix         string     `json:"prefix" db:"prefix" example:"shr_9d2c41ab"`
+	HasPassword    bool       `json:"has_password" db:"-" example:"false"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" db:"expires_at" example:"2026-03-16T09:00:00Z"`
+	AccessCount    int64      `json:"access_count" db:"access_count" example:"3"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty" db:"last_accessed_at" example:"2026-02-17T12:00:00Z"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+	// Token and URL are only returned when the link is created, since only
+	// a hash of the token is stored.
+	Token string `json:"token,omitempty" db:"-" example:"shr_9d2c41ab7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f"`
+	URL   string `json:"url,omitempty" db:"-" example:"/api/v1/shared/shr_9d2c41ab7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f"`
+}
+
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// SharedTodo is the read-only view of a todo opened through a share link. It
+// leaves out who owns the todo.
+type SharedTodo struct {
+	Title       string     `json:"title" example:"Buy groceries"`
+	Description string     `json:"description,omitempty" example:"Milk, eggs, bread"`
+	Completed   bool       `json:"completed" example:"false"`
+	CreatedAt   time.Time  `json:"created_at" example:"2026-02-16T09:00:00Z"`
+	UpdatedAt   time.Time  `json:"updated_at" example:"2026-02-16T09:00:00Z"`
+	Comments    []Comment  `json:"comments"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2026-03-16T09:00:00Z"`
+}
@@ -4,14 +4,24 @@ import "time"
 
 // Todo represents a todo item
 type Todo struct {
-	ID          int64     `json:"id" db:"id" example:"1"`
-	Title       string    `json:"title" db:"title" validate:"required,min=3,max=100" example:"Buy groceries"`
-	Description string    `json:"description,omitempty" db:"description" example:"Milk, eggs, bread"`
-	Completed   bool      `json:"completed" db:"completed" example:"false"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at" example:"2026-02-16T09:00:00Z"`
+	ID           int64     `json:"id" db:"id" example:"1"`
+	Title        string    `json:"title" db:"title" validate:"required,min=3,max=100" example:"Buy groceries"`
+	Description  string    `json:"description,omitempty" db:"description" example:"Milk, eggs, bread"`
+	Completed    bool      `json:"completed" db:"completed" example:"false"`
+	CommentCount int64     `json:"comment_count" db:"comment_count" example:"0"`
+	OwnerID      *int64    `json:"owner_id,omitempty" db:"owner_id" example:"1"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at" example:"2026-02-16T09:00:00Z"`
 }
 
 func (Todo) TableName() string {
 	return "todos"
 }
+
+// TodoFilter narrows and pages a todo listing. Zero values do not filter.
+type TodoFilter struct {
+	Completed *bool
+	Query     string
+	Limit     int
+	Offset    int
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// User is an account that owns todos
+type User struct {
+	ID        int64     `json:"id" db:"id" example:"1"`
+	Email     string    `json:"email" db:"email" example:"ada@example.com"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// Session is the result of a login: a bearer token for the user that
+// expires at ExpiresAt.
+type Session struct {
+	Token     string    `json:"token" example:"todo_3f1c9a7b2e5d4c8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d"`
+	ExpiresAt time.Time `json:"expires_at" example:"2026-03-18T09:00:00Z"`
+	User      *User     `json:"user"`
+}
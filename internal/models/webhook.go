@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookSubscription registers a URL that receives signed todo events
+type WebhookSubscription struct {
+	ID         int64     `json:"id" db:"id" example:"1"`
+	URL        string    `json:"url" db:"url" example:"https://example.com/hooks/todos"`
+	EventTypes []string  `json:"event_types" db:"event_types" example:"todo.created,todo.completed"`
+	Secret     string    `json:"secret,omitempty" db:"secret" example:"whsec_3f1c9a7b2e5d4c8f"`
+	Active     bool      `json:"active" db:"active" example:"true"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at" example:"2026-02-16T09:00:00Z"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery is one event queued for one subscription
+type WebhookDelivery struct {
+	ID             int64           `json:"id" db:"id" example:"1"`
+	SubscriptionID int64           `json:"subscription_id" db:"subscription_id" example:"1"`
+	EventID        string          `json:"event_id" db:"event_id" example:"5f2b9c1e8a7d4e3f9b0c1d2e3f4a5b6c"`
+	EventType      string          `json:"event_type" db:"event_type" example:"todo.created"`
+	Payload        json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	Status         string          `json:"status" db:"status" example:"pending"`
+	Attempts       int             `json:"attempts" db:"attempts" example:"0"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at" example:"2026-02-16T09:00:00Z"`
+	LastStatusCode int             `json:"last_status_code,omitempty" db:"last_status_code" example:"500"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error" example:"unexpected status 500"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at" example:"2026-02-16T09:00:00Z"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at" example:"2026-02-16T09:00:01Z"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
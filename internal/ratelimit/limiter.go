@@ -0,0 +1,108 @@
+// Package ratelimit implements token buckets kept in memory, one per key,
+// such as a client IP.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled completely, and so
+// are indistinguishable from new ones, are dropped.
+const sweepInterval = time.Minute
+
+// Limiter allows each key Limit requests per Period, refilled continuously,
+// with bursts of up to Limit.
+type Limiter struct {
+	limit  int
+	period time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Result is the outcome of a request against the limiter.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of requests left right now.
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, or zero
+	// when it is allowed now.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// New returns a limiter allowing limit requests per period for each key.
+func New(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		period:  period,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Period is the time it takes an empty bucket to refill.
+func (l *Limiter) Period() time.Duration {
+	return l.period
+}
+
+// Allow takes a token from the bucket of key if one is left.
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweepLocked(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.limit), b.tokens+l.rate()*now.Sub(b.updated).Seconds())
+	b.updated = now
+
+	result := Result{Limit: l.limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = l.duration(float64(l.limit) - b.tokens)
+
+	return result
+}
+
+// rate is the number of tokens added per second.
+func (l *Limiter) rate() float64 {
+	return float64(l.limit) / l.period.Seconds()
+}
+
+// duration is how long it takes to add tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate() * float64(time.Second)))
+}
+
+func (l *Limiter) sweepLocked(now time.Time) {
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.duration(float64(l.limit)-b.tokens) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for the limiter.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(limit int, period time.Duration) (*Limiter, *clock) {
+	c := &clock{now: time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC)}
+	l := New(limit, period)
+	l.now = c.Now
+	return l, c
+}
+
+func TestLimiterAllowsBurstsUpToTheLimit(t *testing.T) {
+	l, _ := newTestLimiter(3, time.Minute)
+
+	for i, want := range []int{2, 1, 0} {
+		result := l.Allow("alice")
+		if !result.Allowed || result.Limit != 3 || result.Remaining != want || result.RetryAfter != 0 {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, result, want)
+		}
+	}
+
+	result := l.Allow("alice")
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("fourth request = %+v, want denied", result)
+	}
+	// One token takes a third of the minute to come back, the whole bucket
+	// all of it.
+	if result.RetryAfter != 20*time.Second || result.Reset != time.Minute {
+		t.Errorf("denied request retries after %v and resets after %v, want 20s and 1m", result.RetryAfter, result.Reset)
+	}
+
+	if other := l.Allow("bob"); !other.Allowed || other.Remaining != 2 {
+		t.Errorf("another key = %+v, want a full bucket of its own", other)
+	}
+}
+
+func TestLimiterRefillsContinuously(t *testing.T) {
+	l, c := newTestLimiter(3, time.Minute)
+	for i := 0; i < 3; i++ {
+		l.Allow("alice")
+	}
+
+	c.now = c.now.Add(10 * time.Second)
+	if result := l.Allow("alice"); result.Allowed || result.RetryAfter != 10*time.Second {
+		t.Fatalf("after half a token = %+v, want denied for another 10s", result)
+	}
+
+	c.now = c.now.Add(10 * time.Second)
+	if result := l.Allow("alice"); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("after a token = %+v, want allowed with none remaining", result)
+	}
+
+	// Buckets never hold more than the limit.
+	c.now = c.now.Add(time.Hour)
+	if result := l.Allow("alice"); !result.Allowed || result.Remaining != 2 || result.Reset != 20*time.Second {
+		t.Errorf("after an hour = %+v, want a full bucket less one", result)
+	}
+}
+
+func TestLimiterSweepsFullBuckets(t *testing.T) {
+	l, c := newTestLimiter(3, time.Minute)
+	l.Allow("alice")
+	c.now = c.now.Add(50 * time.Second)
+	l.Allow("bob")
+	l.Allow("bob")
+
+	// A minute on, alice's bucket has been full for 40s while bob's still
+	// lacks a token.
+	c.now = c.now.Add(sweepInterval - 50*time.Second)
+	l.Allow("carol")
+	if _, ok := l.buckets["alice"]; ok {
+		t.Error("alice's full bucket was kept")
+	}
+	for _, key := range []string{"bob", "carol"} {
+		if _, ok := l.buckets[key]; !ok {
+			t.Errorf("%s's bucket was dropped", key)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type APIKeyRepository interface {
+	GetAll() ([]models.APIKey, error)
+	GetByID(id int64) (*models.APIKey, error)
+	GetByHash(hash string) (*models.APIKey, error)
+	Create(key *models.APIKey, hash string) error
+	Delete(id int64) error
+	TouchLastUsed(id int64, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *database.DB
+}
+
+func NewAPIKeyRepository(db *database.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, user_id, expires_at, last_used_at, created_at`
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var userID sql.NullInt64
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&scopes,
+		&userID,
+		&expiresAt,
+		&lastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Split(scopes, ",")
+	if userID.Valid {
+		key.UserID = &userID.Int64
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetAll() ([]models.APIKey, error) {
+	rows, err := r.db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) GetByID(id int64) (*models.APIKey, error) {
+	row := r.db.QueryRow(r.db.Rebind(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`), id)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key with id %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query api key by id: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetByHash finds the key whose secret hashes to hash.
+func (r *apiKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	row := r.db.QueryRow(r.db.Rebind(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`), hash)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query api key by hash: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) Create(key *models.APIKey, hash string) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, user_id, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(r.db.Rebind(query),
+		key.Name,
+		key.Prefix,
+		hash,
+		strings.Join(key.Scopes, ","),
+		key.UserID,
+		key.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	key.ID = id
+	key.CreatedAt = time.Now()
+
+	return nil
+}
+
+func (r *apiKeyRepository) Delete(id int64) error {
+	result, err := r.db.Exec(r.db.Rebind(`DELETE FROM api_keys WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("api key with id %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id int64, at time.Time) error {
+	if _, err := r.db.Exec(r.db.Rebind(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`), at, id); err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type AttachmentRepository interface {
+	GetByTodoID(todoID int64) ([]models.Attachment, error)
+	GetByTodoIDs(todoIDs []int64) ([]models.Attachment, error)
+	GetByID(id int64) (*models.Attachment, error)
+	Create(attachment *models.Attachment) error
+	Delete(id int64) error
+	ReferencedSHA256() (map[string]bool, error)
+}
+
+type attachmentRepository struct {
+	db *database.DB
+}
+
+func NewAttachmentRepository(db *database.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, todo_id, filename, content_type, size, sha256, created_at`
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.TodoID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func (r *attachmentRepository) GetByTodoID(todoID int64) ([]models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE todo_id = ?
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(r.db.Rebind(query), todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// GetByTodoIDs returns the attachments of several todos in one query.
+func (r *attachmentRepository) GetByTodoIDs(todoIDs []int64) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	if len(todoIDs) == 0 {
+		return attachments, nil
+	}
+
+	in, args := inClause(todoIDs)
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE todo_id IN ` + in + `
+		ORDER BY todo_id ASC, created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return attachments, nil
+}
+
+func (r *attachmentRepository) GetByID(id int64) (*models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE id = ?
+	`
+
+	attachment, err := scanAttachment(r.db.QueryRow(r.db.Rebind(query), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("attachment with id %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query attachment by id: %w", err)
+	}
+
+	return attachment, nil
+}
+
+func (r *attachmentRepository) Create(attachment *models.Attachment) error {
+	query := `
+		INSERT INTO attachments (todo_id, filename, content_type, size, sha256)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(r.db.Rebind(query),
+		attachment.TodoID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.SHA256,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	attachment.ID = id
+	attachment.CreatedAt = time.Now()
+
+	return nil
+}
+
+func (r *attachmentRepository) Delete(id int64) error {
+	result, err := r.db.Exec(r.db.Rebind(`DELETE FROM attachments WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("attachment with id %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// ReferencedSHA256 returns the set of blob hashes that still have metadata rows.
+func (r *attachmentRepository) ReferencedSHA256() (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT sha256 FROM attachments`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachment hashes: %w", err)
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var sum string
+		if err := rows.Scan(&sum); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment hash: %w", err)
+		}
+		referenced[sum] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return referenced, nil
+}
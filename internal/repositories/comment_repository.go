@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type CommentRepository interface {
+	GetByTodoID(todoID int64, limit, offset int) ([]models.Comment, error)
+	GetByTodoIDs(todoIDs []int64, limit int) ([]models.Comment, error)
+	CountByTodoID(todoID int64) (int64, error)
+	GetByID(id int64) (*models.Comment, error)
+	Create(comment *models.Comment) error
+	Update(comment *models.Comment) error
+	SoftDelete(id int64) error
+}
+
+type commentRepository struct {
+	db *database.DB
+}
+
+func NewCommentRepository(db *database.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+const commentColumns = `id, todo_id, parent_comment_id, author, body, created_at, updated_at, edited_at, deleted_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	var comment models.Comment
+	var parentID sql.NullInt64
+	var editedAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.TodoID,
+		&parentID,
+		&comment.Author,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&editedAt,
+		&deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		comment.ParentCommentID = &parentID.Int64
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		comment.DeletedAt = &deletedAt.Time
+	}
+
+	return &comment, nil
+}
+
+// GetByTodoID returns a page of comments for a todo in chronological order.
+// Soft-deleted comments are included so that reply threads stay intact.
+func (r *commentRepository) GetByTodoID(todoID int64, limit, offset int) ([]models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE todo_id = ?
+		ORDER BY created_at ASC, id ASC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(r.db.Rebind(query), todoID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, *comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return comments, nil
+}
+
+// GetByTodoIDs returns the first limit comments of each of several todos in
+// one query, ordered by todo and then chronologically.
+func (r *commentRepository) GetByTodoIDs(todoIDs []int64, limit int) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	if len(todoIDs) == 0 {
+		return comments, nil
+	}
+
+	in, args := inClause(todoIDs)
+	query := `
+		SELECT ` + commentColumns + `
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY todo_id ORDER BY created_at ASC, id ASC) AS todo_position
+			FROM comments
+			WHERE todo_id IN ` + in + `
+		) AS ranked
+		WHERE todo_position <= ?
+		ORDER BY todo_id ASC, todo_position ASC
+	`
+
+	rows, err := r.db.Query(r.db.Rebind(query), append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, *comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return comments, nil
+}
+
+// CountByTodoID counts the comments GetByTodoID pages through, soft-deleted
+// ones included.
+func (r *commentRepository) CountByTodoID(todoID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(r.db.Rebind(`SELECT COUNT(*) FROM comments WHERE todo_id = ?`), todoID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *commentRepository) GetByID(id int64) (*models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE id = ?
+	`
+
+	comment, err := scanComment(r.db.QueryRow(r.db.Rebind(query), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("comment with id %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query comment by id: %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *commentRepository) Create(comment *models.Comment) error {
+	query := `
+		INSERT INTO comments (todo_id, parent_comment_id, author, body)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`
+
+	var parentID interface{}
+	if comment.ParentCommentID != nil {
+		parentID = *comment.ParentCommentID
+	}
+
+	var id int64
+	err := r.db.QueryRow(r.db.Rebind(query), comment.TodoID, parentID, comment.Author, comment.Body).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	comment.ID = id
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// Update replaces the body of a live comment and stamps edited_at.
+func (r *commentRepository) Update(comment *models.Comment) error {
+	query := `
+		UPDATE comments
+		SET body = ?, edited_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(r.db.Rebind(query), comment.Body, comment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("comment with id %d %w", comment.ID, ErrNotFound)
+	}
+
+	now := time.Now()
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+
+	return nil
+}
+
+func (r *commentRepository) SoftDelete(id int64) error {
+	query := `
+		UPDATE comments
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(r.db.Rebind(query), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("comment with id %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package repositories
+
+import "errors"
+
+// ErrNotFound is wrapped by repository errors when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is wrapped by repository errors when a row would duplicate a
+// unique value of an existing one.
+var ErrConflict = errors.New("already exists")
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type GrantRepository interface {
+	GetByTodoID(todoID int64) ([]models.Grant, error)
+	GetRole(todoID, userID int64) (string, error)
+	Upsert(grant *models.Grant) error
+	Delete(todoID, userID int64) error
+}
+
+type grantRepository struct {
+	db *database.DB
+}
+
+func NewGrantRepository(db *database.DB) GrantRepository {
+	return &grantRepository{db: db}
+}
+
+// GetByTodoID returns the grants on a todo with the email of each grantee,
+// oldest first.
+func (r *grantRepository) GetByTodoID(todoID int64) ([]models.Grant, error) {
+	rows, err := r.db.Query(r.db.Rebind(`
+		SELECT g.todo_id, g.user_id, u.email, g.role, g.created_at
+		FROM todo_grants g JOIN users u ON u.id = g.user_id
+		WHERE g.todo_id = ?
+		ORDER BY g.created_at ASC, g.user_id ASC
+	`), todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []models.Grant{}
+	for rows.Next() {
+		var grant models.Grant
+		if err := rows.Scan(&grant.TodoID, &grant.UserID, &grant.Email, &grant.Role, &grant.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan grant: %w", err)
+		}
+		grants = append(grants, grant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return grants, nil
+}
+
+// GetRole returns the role granted to a user on a todo.
+func (r *grantRepository) GetRole(todoID, userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRow(r.db.Rebind(`SELECT role FROM todo_grants WHERE todo_id = ? AND user_id = ?`), todoID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("grant of todo %d to user %d %w", todoID, userID, ErrNotFound)
+		}
+		return "", fmt.Errorf("failed to query grant: %w", err)
+	}
+
+	return role, nil
+}
+
+// Upsert grants a role, replacing the role of an existing grant.
+func (r *grantRepository) Upsert(grant *models.Grant) error {
+	_, err := r.db.Exec(r.db.Rebind(`
+		INSERT INTO todo_grants (todo_id, user_id, role) VALUES (?, ?, ?)
+		ON CONFLICT (todo_id, user_id) DO UPDATE SET role = excluded.role
+	`), grant.TodoID, grant.UserID, grant.Role)
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return fmt.Errorf("todo with id %d %w", grant.TodoID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to save grant: %w", err)
+	}
+
+	err = r.db.QueryRow(r.db.Rebind(`SELECT created_at FROM todo_grants WHERE todo_id = ? AND user_id = ?`), grant.TodoID, grant.UserID).Scan(&grant.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to query grant: %w", err)
+	}
+
+	return nil
+}
+
+func (r *grantRepository) Delete(todoID, userID int64) error {
+	result, err := r.db.Exec(r.db.Rebind(`DELETE FROM todo_grants WHERE todo_id = ? AND user_id = ?`), todoID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete grant: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("grant of todo %d to user %d %w", todoID, userID, ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type IdempotencyRepository interface {
+	// Reserve stores record as in flight until lease has passed, after
+	// which its key can be reserved again. When an unexpired record
+	// already holds its key, that record is returned along with
+	// ErrConflict.
+	Reserve(record *models.IdempotencyRecord, lease time.Duration) (*models.IdempotencyRecord, error)
+	// Complete stores the response of a reserved record and keeps it
+	// until ttl has passed.
+	Complete(record *models.IdempotencyRecord, ttl time.Duration) error
+	// Release deletes a record, so its key can be used again.
+	Release(scope, key string) error
+	DeleteExpired() (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *database.DB
+}
+
+func NewIdempotencyRepository(db *database.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Reserve(record *models.IdempotencyRecord, lease time.Duration) (*models.IdempotencyRecord, error) {
+	var existing *models.IdempotencyRecord
+
+	err := r.db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			r.db.Rebind(`DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ? AND expires_at <= CURRENT_TIMESTAMP`),
+			record.Scope, record.Key,
+		); err != nil {
+			return fmt.Errorf("failed to delete expired idempotency key: %w", err)
+		}
+
+		result, err := tx.Exec(
+			r.db.Rebind(`INSERT INTO idempotency_keys (scope, idempotency_key, fingerprint, expires_at) VALUES (?, ?, ?, `+secondsFromNow(r.db)+`)
+				ON CONFLICT (scope, idempotency_key) DO NOTHING`),
+			record.Scope, record.Key, record.Fingerprint, lease.Seconds(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if inserted, err := result.RowsAffected(); err != nil || inserted == 1 {
+			return err
+		}
+
+		existing = &models.IdempotencyRecord{Scope: record.Scope, Key: record.Key}
+		var statusCode sql.NullInt64
+		var contentType sql.NullString
+		err = tx.QueryRow(
+			r.db.Rebind(`SELECT fingerprint, status_code, content_type, body FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?`),
+			record.Scope, record.Key,
+		).Scan(&existing.Fingerprint, &statusCode, &contentType, &existing.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+		existing.StatusCode = int(statusCode.Int64)
+		existing.ContentType = contentType.String
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return existing, fmt.Errorf("idempotency key %q: %w", record.Key, ErrConflict)
+	}
+	return nil, nil
+}
+
+func (r *idempotencyRepository) Complete(record *models.IdempotencyRecord, ttl time.Duration) error {
+	result, err := r.db.Exec(
+		r.db.Rebind(`UPDATE idempotency_keys SET status_code = ?, content_type = ?, body = ?, expires_at = `+secondsFromNow(r.db)+`
+			WHERE scope = ? AND idempotency_key = ?`),
+		record.StatusCode, record.ContentType, record.Body, ttl.Seconds(), record.Scope, record.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return fmt.Errorf("idempotency key %q: %w", record.Key, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(scope, key string) error {
+	if _, err := r.db.Exec(r.db.Rebind(`DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?`), scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,400 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+// QueryObserver is told how long each repository method took and whether it
+// failed, such as to export the durations as metrics.
+type QueryObserver func(repository, method string, elapsed time.Duration, err error)
+
+// instrument reports calls of one repository to a QueryObserver. Methods
+// of the wrappers below name their error result and defer done with it.
+type instrument struct {
+	repository string
+	observe    QueryObserver
+}
+
+func (i instrument) done(method string, start time.Time, err *error) {
+	i.observe(i.repository, method, time.Since(start), *err)
+}
+
+// InstrumentTodoRepository wraps repo to report every call to observe.
+func InstrumentTodoRepository(repo TodoRepository, observe QueryObserver) TodoRepository {
+	return &instrumentedTodoRepository{next: repo, instrument: instrument{"todo", observe}}
+}
+
+type instrumentedTodoRepository struct {
+	next TodoRepository
+	instrument
+}
+
+func (r *instrumentedTodoRepository) GetAll(ctx context.Context) (todos []models.Todo, err error) {
+	defer r.done("GetAll", time.Now(), &err)
+	return r.next.GetAll(ctx)
+}
+
+func (r *instrumentedTodoRepository) List(ctx context.Context, filter models.TodoFilter) (todos []models.Todo, total int64, err error) {
+	defer r.done("List", time.Now(), &err)
+	return r.next.List(ctx, filter)
+}
+
+func (r *instrumentedTodoRepository) GetByID(ctx context.Context, id int64) (todo *models.Todo, err error) {
+	defer r.done("GetByID", time.Now(), &err)
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *instrumentedTodoRepository) GetByIDs(ctx context.Context, ids []int64) (todos []models.Todo, err error) {
+	defer r.done("GetByIDs", time.Now(), &err)
+	return r.next.GetByIDs(ctx, ids)
+}
+
+func (r *instrumentedTodoRepository) Create(ctx context.Context, todo *models.Todo) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(ctx, todo)
+}
+
+func (r *instrumentedTodoRepository) Update(ctx context.Context, todo *models.Todo) (err error) {
+	defer r.done("Update", time.Now(), &err)
+	return r.next.Update(ctx, todo)
+}
+
+func (r *instrumentedTodoRepository) Delete(ctx context.Context, id int64) (err error) {
+	defer r.done("Delete", time.Now(), &err)
+	return r.next.Delete(ctx, id)
+}
+
+func (r *instrumentedTodoRepository) CountCreatedSince(ctx context.Context, owner int64, since time.Time) (count int64, err error) {
+	defer r.done("CountCreatedSince", time.Now(), &err)
+	return r.next.CountCreatedSince(ctx, owner, since)
+}
+
+// InstrumentCommentRepository wraps repo to report every call to observe.
+func InstrumentCommentRepository(repo CommentRepository, observe QueryObserver) CommentRepository {
+	return &instrumentedCommentRepository{next: repo, instrument: instrument{"comment", observe}}
+}
+
+type instrumentedCommentRepository struct {
+	next CommentRepository
+	instrument
+}
+
+func (r *instrumentedCommentRepository) GetByTodoID(todoID int64, limit, offset int) (comments []models.Comment, err error) {
+	defer r.done("GetByTodoID", time.Now(), &err)
+	return r.next.GetByTodoID(todoID, limit, offset)
+}
+
+func (r *instrumentedCommentRepository) GetByTodoIDs(todoIDs []int64, limit int) (comments []models.Comment, err error) {
+	defer r.done("GetByTodoIDs", time.Now(), &err)
+	return r.next.GetByTodoIDs(todoIDs, limit)
+}
+
+func (r *instrumentedCommentRepository) CountByTodoID(todoID int64) (count int64, err error) {
+	defer r.done("CountByTodoID", time.Now(), &err)
+	return r.next.CountByTodoID(todoID)
+}
+
+func (r *instrumentedCommentRepository) GetByID(id int64) (comment *models.Comment, err error) {
+	defer r.done("GetByID", time.Now(), &err)
+	return r.next.GetByID(id)
+}
+
+func (r *instrumentedCommentRepository) Create(comment *models.Comment) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(comment)
+}
+
+func (r *instrumentedCommentRepository) Update(comment *models.Comment) (err error) {
+	defer r.done("Update", time.Now(), &err)
+	return r.next.Update(comment)
+}
+
+func (r *instrumentedCommentRepository) SoftDelete(id int64) (err error) {
+	defer r.done("SoftDelete", time.Now(), &err)
+	return r.next.SoftDelete(id)
+}
+
+// InstrumentAttachmentRepository wraps repo to report every call to observe.
+func InstrumentAttachmentRepository(repo AttachmentRepository, observe QueryObserver) AttachmentRepository {
+	return &instrumentedAttachmentRepository{next: repo, instrument: instrument{"attachment", observe}}
+}
+
+type instrumentedAttachmentRepository struct {
+	next AttachmentRepository
+	instrument
+}
+
+func (r *instrumentedAttachmentRepository) GetByTodoID(todoID int64) (attachments []models.Attachment, err error) {
+	defer r.done("GetByTodoID", time.Now(), &err)
+	return r.next.GetByTodoID(todoID)
+}
+
+func (r *instrumentedAttachmentRepository) GetByTodoIDs(todoIDs []int64) (attachments []models.Attachment, err error) {
+	defer r.done("GetByTodoIDs", time.Now(), &err)
+	return r.next.GetByTodoIDs(todoIDs)
+}
+
+func (r *instrumentedAttachmentRepository) GetByID(id int64) (attachment *models.Attachment, err error) {
+	defer r.done("GetByID", time.Now(), &err)
+	return r.next.GetByID(id)
+}
+
+func (r *instrumentedAttachmentRepository) Create(attachment *models.Attachment) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(attachment)
+}
+
+func (r *instrumentedAttachmentRepository) Delete(id int64) (err error) {
+	defer r.done("Delete", time.Now(), &err)
+	return r.next.Delete(id)
+}
+
+func (r *instrumentedAttachmentRepository) ReferencedSHA256() (referenced map[string]bool, err error) {
+	defer r.done("ReferencedSHA256", time.Now(), &err)
+	return r.next.ReferencedSHA256()
+}
+
+// InstrumentGrantRepository wraps repo to report every call to observe.
+func InstrumentGrantRepository(repo GrantRepository, observe QueryObserver) GrantRepository {
+	return &instrumentedGrantRepository{next: repo, instrument: instrument{"grant", observe}}
+}
+
+type instrumentedGrantRepository struct {
+	next GrantRepository
+	instrument
+}
+
+func (r *instrumentedGrantRepository) GetByTodoID(todoID int64) (grants []models.Grant, err error) {
+	defer r.done("GetByTodoID", time.Now(), &err)
+	return r.next.GetByTodoID(todoID)
+}
+
+func (r *instrumentedGrantRepository) GetRole(todoID, userID int64) (role string, err error) {
+	defer r.done("GetRole", time.Now(), &err)
+	return r.next.GetRole(todoID, userID)
+}
+
+func (r *instrumentedGrantRepository) Upsert(grant *models.Grant) (err error) {
+	defer r.done("Upsert", time.Now(), &err)
+	return r.next.Upsert(grant)
+}
+
+func (r *instrumentedGrantRepository) Delete(todoID, userID int64) (err error) {
+	defer r.done("Delete", time.Now(), &err)
+	return r.next.Delete(todoID, userID)
+}
+
+// InstrumentShareLinkRepository wraps repo to report every call to observe.
+func InstrumentShareLinkRepository(repo ShareLinkRepository, observe QueryObserver) ShareLinkRepository {
+	return &instrumentedShareLinkRepository{next: repo, instrument: instrument{"share_link", observe}}
+}
+
+type instrumentedShareLinkRepository struct {
+	next ShareLinkRepository
+	instrument
+}
+
+func (r *instrumentedShareLinkRepository) GetByTodoID(todoID int64) (links []models.ShareLink, err error) {
+	defer r.done("GetByTodoID", time.Now(), &err)
+	return r.next.GetByTodoID(todoID)
+}
+
+func (r *instrumentedShareLinkRepository) GetByHash(hash string) (link *models.ShareLink, passwordHash string, err error) {
+	defer r.done("GetByHash", time.Now(), &err)
+	return r.next.GetByHash(hash)
+}
+
+func (r *instrumentedShareLinkRepository) Create(link *models.ShareLink, tokenHash, passwordHash string) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(link, tokenHash, passwordHash)
+}
+
+func (r *instrumentedShareLinkRepository) Delete(todoID, id int64) (err error) {
+	defer r.done("Delete", time.Now(), &err)
+	return r.next.Delete(todoID, id)
+}
+
+func (r *instrumentedShareLinkRepository) RecordAccess(id int64, at time.Time) (err error) {
+	defer r.done("RecordAccess", time.Now(), &err)
+	return r.next.RecordAccess(id, at)
+}
+
+// InstrumentUserRepository wraps repo to report every call to observe.
+func InstrumentUserRepository(repo UserRepository, observe QueryObserver) UserRepository {
+	return &instrumentedUserRepository{next: repo, instrument: instrument{"user", observe}}
+}
+
+type instrumentedUserRepository struct {
+	next UserRepository
+	instrument
+}
+
+func (r *instrumentedUserRepository) GetByID(id int64) (user *models.User, err error) {
+	defer r.done("GetByID", time.Now(), &err)
+	return r.next.GetByID(id)
+}
+
+func (r *instrumentedUserRepository) GetByEmail(email string) (user *models.User, passwordHash string, err error) {
+	defer r.done("GetByEmail", time.Now(), &err)
+	return r.next.GetByEmail(email)
+}
+
+func (r *instrumentedUserRepository) Create(user *models.User, passwordHash string) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(user, passwordHash)
+}
+
+func (r *instrumentedUserRepository) GetByIdentity(issuer, subject string) (user *models.User, err error) {
+	defer r.done("GetByIdentity", time.Now(), &err)
+	return r.next.GetByIdentity(issuer, subject)
+}
+
+func (r *instrumentedUserRepository) CreateWithIdentity(user *models.User, issuer, subject string) (err error) {
+	defer r.done("CreateWithIdentity", time.Now(), &err)
+	return r.next.CreateWithIdentity(user, issuer, subject)
+}
+
+func (r *instrumentedUserRepository) LinkIdentity(userID int64, issuer, subject string) (err error) {
+	defer r.done("LinkIdentity", time.Now(), &err)
+	return r.next.LinkIdentity(userID, issuer, subject)
+}
+
+// InstrumentAPIKeyRepository wraps repo to report every call to observe.
+func InstrumentAPIKeyRepository(repo APIKeyRepository, observe QueryObserver) APIKeyRepository {
+	return &instrumentedAPIKeyRepository{next: repo, instrument: instrument{"api_key", observe}}
+}
+
+type instrumentedAPIKeyRepository struct {
+	next APIKeyRepository
+	instrument
+}
+
+func (r *instrumentedAPIKeyRepository) GetAll() (keys []models.APIKey, err error) {
+	defer r.done("GetAll", time.Now(), &err)
+	return r.next.GetAll()
+}
+
+func (r *instrumentedAPIKeyRepository) GetByID(id int64) (key *models.APIKey, err error) {
+	defer r.done("GetByID", time.Now(), &err)
+	return r.next.GetByID(id)
+}
+
+func (r *instrumentedAPIKeyRepository) GetByHash(hash string) (key *models.APIKey, err error) {
+	defer r.done("GetByHash", time.Now(), &err)
+	return r.next.GetByHash(hash)
+}
+
+func (r *instrumentedAPIKeyRepository) Create(key *models.APIKey, hash string) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(key, hash)
+}
+
+func (r *instrumentedAPIKeyRepository) Delete(id int64) (err error) {
+	defer r.done("Delete", time.Now(), &err)
+	return r.next.Delete(id)
+}
+
+func (r *instrumentedAPIKeyRepository) TouchLastUsed(id int64, at time.Time) (err error) {
+	defer r.done("TouchLastUsed", time.Now(), &err)
+	return r.next.TouchLastUsed(id, at)
+}
+
+// InstrumentWebhookRepository wraps repo to report every call to observe.
+func InstrumentWebhookRepository(repo WebhookRepository, observe QueryObserver) WebhookRepository {
+	return &instrumentedWebhookRepository{next: repo, instrument: instrument{"webhook", observe}}
+}
+
+type instrumentedWebhookRepository struct {
+	next WebhookRepository
+	instrument
+}
+
+func (r *instrumentedWebhookRepository) GetAll() (subscriptions []models.WebhookSubscription, err error) {
+	defer r.done("GetAll", time.Now(), &err)
+	return r.next.GetAll()
+}
+
+func (r *instrumentedWebhookRepository) GetByID(id int64) (subscription *models.WebhookSubscription, err error) {
+	defer r.done("GetByID", time.Now(), &err)
+	return r.next.GetByID(id)
+}
+
+func (r *instrumentedWebhookRepository) Create(subscription *models.WebhookSubscription) (err error) {
+	defer r.done("Create", time.Now(), &err)
+	return r.next.Create(subscription)
+}
+
+func (r *instrumentedWebhookRepository) Update(subscription *models.WebhookSubscription) (err error) {
+	defer r.done("Update", time.Now(), &err)
+	return r.next.Update(subscription)
+}
+
+func (r *instrumentedWebhookRepository) Delete(id int64) (err error) {
+	defer r.done("Delete", time.Now(), &err)
+	return r.next.Delete(id)
+}
+
+func (r *instrumentedWebhookRepository) Enqueue(tx *sql.Tx, event events.Event) (err error) {
+	defer r.done("Enqueue", time.Now(), &err)
+	return r.next.Enqueue(tx, event)
+}
+
+func (r *instrumentedWebhookRepository) DueDeliveries(limit int) (deliveries []DueDelivery, err error) {
+	defer r.done("DueDeliveries", time.Now(), &err)
+	return r.next.DueDeliveries(limit)
+}
+
+func (r *instrumentedWebhookRepository) MarkDelivered(id int64, statusCode int) (err error) {
+	defer r.done("MarkDelivered", time.Now(), &err)
+	return r.next.MarkDelivered(id, statusCode)
+}
+
+func (r *instrumentedWebhookRepository) MarkFailed(id int64, statusCode int, lastError string, retryIn time.Duration, dead bool) (err error) {
+	defer r.done("MarkFailed", time.Now(), &err)
+	return r.next.MarkFailed(id, statusCode, lastError, retryIn, dead)
+}
+
+func (r *instrumentedWebhookRepository) GetDeliveries(subscriptionID int64, status string, limit, offset int) (deliveries []models.WebhookDelivery, total int64, err error) {
+	defer r.done("GetDeliveries", time.Now(), &err)
+	return r.next.GetDeliveries(subscriptionID, status, limit, offset)
+}
+
+func (r *instrumentedWebhookRepository) Replay(subscriptionID, deliveryID int64) (replayed int64, err error) {
+	defer r.done("Replay", time.Now(), &err)
+	return r.next.Replay(subscriptionID, deliveryID)
+}
+
+// InstrumentIdempotencyRepository wraps repo to report every call to observe.
+func InstrumentIdempotencyRepository(repo IdempotencyRepository, observe QueryObserver) IdempotencyRepository {
+	return &instrumentedIdempotencyRepository{next: repo, instrument: instrument{"idempotency", observe}}
+}
+
+type instrumentedIdempotencyRepository struct {
+	next IdempotencyRepository
+	instrument
+}
+
+func (r *instrumentedIdempotencyRepository) Reserve(record *models.IdempotencyRecord, lease time.Duration) (existing *models.IdempotencyRecord, err error) {
+	defer r.done("Reserve", time.Now(), &err)
+	return r.next.Reserve(record, lease)
+}
+
+func (r *instrumentedIdempotencyRepository) Complete(record *models.IdempotencyRecord, ttl time.Duration) (err error) {
+	defer r.done("Complete", time.Now(), &err)
+	return r.next.Complete(record, ttl)
+}
+
+func (r *instrumentedIdempotencyRepository) Release(scope, key string) (err error) {
+	defer r.done("Release", time.Now(), &err)
+	return r.next.Release(scope, key)
+}
+
+func (r *instrumentedIdempotencyRepository) DeleteExpired() (deleted int64, err error) {
+	defer r.done("DeleteExpired", time.Now(), &err)
+	return r.next.DeleteExpired()
+}
@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+// memoryTodoRepository keeps todos in a map, for tests and for running the
+// services without a database. It is safe for concurrent use. Todos have
+// no comments, so their comment count is always zero, and writes run no
+// TxHooks, though they record their events like the SQL repositories.
+type memoryTodoRepository struct {
+	grants GrantRepository
+
+	mu     sync.RWMutex
+	todos  map[int64]models.Todo
+	nextID int64
+}
+
+// NewMemoryTodoRepository returns an empty in-memory TodoRepository. Users
+// see the todos they own and those grants gives them a role on; with nil
+// grants they only see their own.
+func NewMemoryTodoRepository(grants GrantRepository) TodoRepository {
+	return &memoryTodoRepository{
+		grants: grants,
+		todos:  make(map[int64]models.Todo),
+		nextID: 1,
+	}
+}
+
+func (r *memoryTodoRepository) GetAll(ctx context.Context) ([]models.Todo, error) {
+	return r.visible(ctx, func(models.Todo) bool { return true })
+}
+
+// List returns the todos matching filter, newest first, along with the number
+// of matches before limit and offset are applied.
+func (r *memoryTodoRepository) List(ctx context.Context, filter models.TodoFilter) ([]models.Todo, int64, error) {
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+	todos, err := r.visible(ctx, func(todo models.Todo) bool {
+		if filter.Completed != nil && todo.Completed != *filter.Completed {
+			return false
+		}
+		// Matches the case-insensitive LIKE of the SQL repositories.
+		return query == "" ||
+			strings.Contains(strings.ToLower(todo.Title), query) ||
+			strings.Contains(strings.ToLower(todo.Description), query)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(len(todos))
+	start := min(max(filter.Offset, 0), len(todos))
+	end := min(start+max(filter.Limit, 0), len(todos))
+
+	return todos[start:end], total, nil
+}
+
+func (r *memoryTodoRepository) GetByID(ctx context.Context, id int64) (*models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, err := r.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// GetByIDs loads several todos at once. Unknown ids are skipped.
+func (r *memoryTodoRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := []models.Todo{}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		todo, err := r.get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, nil
+}
+
+func (r *memoryTodoRepository) CountCreatedSince(ctx context.Context, owner int64, since time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, todo := range r.todos {
+		if todo.OwnerID != nil && *todo.OwnerID == owner && !todo.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo.OwnerID = nil
+	if owner := auth.Owner(ctx); owner != 0 {
+		todo.OwnerID = &owner
+	}
+
+	now := time.Now()
+	todo.ID = r.nextID
+	todo.CreatedAt = now
+	todo.UpdatedAt = now
+	todo.CommentCount = 0
+	r.nextID++
+
+	r.todos[todo.ID] = cloneTodo(*todo)
+	events.Record(ctx, events.New(events.TodoCreated, todo))
+	return nil
+}
+
+func (r *memoryTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, err := r.get(ctx, todo.ID)
+	if err != nil {
+		return err
+	}
+
+	todo.OwnerID = previous.OwnerID
+	todo.CreatedAt = previous.CreatedAt
+	todo.UpdatedAt = time.Now()
+	todo.CommentCount = previous.CommentCount
+
+	r.todos[todo.ID] = cloneTodo(*todo)
+	events.Record(ctx, events.New(events.TodoUpdated, todo))
+	if todo.Completed && !previous.Completed {
+		events.Record(ctx, events.New(events.TodoCompleted, todo))
+	}
+	return nil
+}
+
+func (r *memoryTodoRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, err := r.get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	delete(r.todos, id)
+	events.Record(ctx, events.New(events.TodoDeleted, &previous))
+	return nil
+}
+
+// get returns a copy of the todo with id if the caller of ctx may access
+// it. The caller must hold mu.
+func (r *memoryTodoRepository) get(ctx context.Context, id int64) (models.Todo, error) {
+	todo, ok := r.todos[id]
+	if ok {
+		allowed, err := r.allowed(ctx, todo)
+		if err != nil {
+			return models.Todo{}, err
+		}
+		ok = allowed
+	}
+	if !ok {
+		return models.Todo{}, fmt.Errorf("todo with id %d %w", id, ErrNotFound)
+	}
+
+	return cloneTodo(todo), nil
+}
+
+// visible returns copies of the todos the caller of ctx may access that
+// match keep, newest first.
+func (r *memoryTodoRepository) visible(ctx context.Context, keep func(models.Todo) bool) ([]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := []models.Todo{}
+	for _, todo := range r.todos {
+		if !keep(todo) {
+			continue
+		}
+		allowed, err := r.allowed(ctx, todo)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			todos = append(todos, cloneTodo(todo))
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].CreatedAt.Equal(todos[j].CreatedAt) {
+			return todos[i].CreatedAt.After(todos[j].CreatedAt)
+		}
+		return todos[i].ID > todos[j].ID
+	})
+	return todos, nil
+}
+
+// allowed reports whether the caller of ctx owns todo or was granted a role
+// on it. Callers not tied to a user may access every todo.
+func (r *memoryTodoRepository) allowed(ctx context.Context, todo models.Todo) (bool, error) {
+	user := auth.Owner(ctx)
+	if user == 0 || (todo.OwnerID != nil && *todo.OwnerID == user) {
+		return true, nil
+	}
+	if r.grants == nil {
+		return false, nil
+	}
+
+	_, err := r.grants.GetRole(todo.ID, user)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cloneTodo copies todo so that callers cannot change the stored owner
+// through the pointer.
+func cloneTodo(todo models.Todo) models.Todo {
+	if todo.OwnerID != nil {
+		owner := *todo.OwnerID
+		todo.OwnerID = &owner
+	}
+	return todo
+}
@@ -0,0 +1,344 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+// postgresTodoColumns are the columns scanned by scanTodo, for PostgreSQL.
+const postgresTodoColumns = `id, title, description, completed, owner_id, created_at, updated_at,
+	(SELECT COUNT(*) FROM comments c WHERE c.todo_id = todos.id AND c.deleted_at IS NULL) AS comment_count`
+
+// postgresTodoRepository is the TodoRepository for PostgreSQL, used when the
+// database DSN is a postgres:// URL. Its schema lives in
+// migrations/postgres. Ids and timestamps come back from the statements
+// writing them, with RETURNING.
+type postgresTodoRepository struct {
+	db    *database.DB
+	hooks []TxHook
+}
+
+func (r *postgresTodoRepository) GetAll(ctx context.Context) ([]models.Todo, error) {
+	var args pgArgs
+	query := `SELECT ` + postgresTodoColumns + `
+		FROM todos
+		WHERE ` + postgresAccessScope(ctx, &args) + `
+		ORDER BY created_at DESC`
+
+	return r.query(ctx, "todos.get_all", query, args...)
+}
+
+// List returns the todos matching filter, newest first, along with the number
+// of matches before limit and offset are applied.
+func (r *postgresTodoRepository) List(ctx context.Context, filter models.TodoFilter) ([]models.Todo, int64, error) {
+	var args pgArgs
+	conditions := []string{postgresAccessScope(ctx, &args)}
+
+	if filter.Completed != nil {
+		conditions = append(conditions, "completed = "+args.add(*filter.Completed))
+	}
+
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		// ILIKE keeps searches case-insensitive, as LIKE is on SQLite.
+		pattern := args.add("%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%")
+		conditions = append(conditions, `(title ILIKE `+pattern+` ESCAPE '\' OR description ILIKE `+pattern+` ESCAPE '\')`)
+	}
+
+	where := "WHERE " + strings.Join(conditions, " AND ")
+
+	var total int64
+	countQuery := `SELECT COUNT(*) FROM todos ` + where
+	countCtx, countSpan := startPostgresQuery(ctx, "todos", "todos.list.count", "SELECT", countQuery)
+	err := r.db.QueryRowContext(countCtx, countQuery, args...).Scan(&total)
+	if err != nil {
+		countSpan.fail(err)
+	}
+	countSpan.End()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count todos: %w", err)
+	}
+
+	query := `SELECT ` + postgresTodoColumns + `
+		FROM todos
+		` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ` + args.add(filter.Limit) + ` OFFSET ` + args.add(filter.Offset)
+
+	todos, err := r.query(ctx, "todos.list", query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	if todos == nil {
+		todos = []models.Todo{}
+	}
+
+	return todos, total, nil
+}
+
+func (r *postgresTodoRepository) GetByID(ctx context.Context, id int64) (*models.Todo, error) {
+	return r.getByID(ctx, r.db, id, "")
+}
+
+// GetByIDs loads several todos in one query. Unknown ids are skipped.
+func (r *postgresTodoRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Todo, error) {
+	if len(ids) == 0 {
+		return []models.Todo{}, nil
+	}
+
+	var args pgArgs
+	query := `SELECT ` + postgresTodoColumns + `
+		FROM todos
+		WHERE id = ANY(` + args.add(ids) + `) AND ` + postgresAccessScope(ctx, &args)
+
+	todos, err := r.query(ctx, "todos.get_by_ids", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if todos == nil {
+		todos = []models.Todo{}
+	}
+
+	return todos, nil
+}
+
+func (r *postgresTodoRepository) CountCreatedSince(ctx context.Context, owner int64, since time.Time) (int64, error) {
+	query := `SELECT COUNT(*) FROM todos WHERE owner_id = $1 AND created_at >= $2`
+
+	ctx, span := startPostgresQuery(ctx, "todos", "todos.count_created", "SELECT", query)
+	defer span.End()
+
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, owner, since).Scan(&count); err != nil {
+		return 0, span.fail(fmt.Errorf("failed to count created todos: %w", err))
+	}
+
+	return count, nil
+}
+
+func (r *postgresTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	query := `
+		INSERT INTO todos (title, description, completed, owner_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	todo.OwnerID = nil
+	if owner := auth.Owner(ctx); owner != 0 {
+		todo.OwnerID = &owner
+	}
+
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		ctx, span := startPostgresQuery(ctx, "todos", "todos.insert", "INSERT", query)
+		defer span.End()
+
+		err := tx.QueryRowContext(ctx, query, todo.Title, nullString(todo.Description), todo.Completed, todo.OwnerID).
+			Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
+			return span.fail(fmt.Errorf("failed to create todo: %w", err))
+		}
+		span.rows(1)
+
+		return r.emit(ctx, tx, events.New(events.TodoCreated, todo))
+	})
+}
+
+func (r *postgresTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	query := `
+		UPDATE todos
+		SET title = $1, description = $2, completed = $3
+		WHERE id = $4
+		RETURNING owner_id, created_at, updated_at`
+
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		// The row stays locked until commit, so concurrent updates see
+		// each other's changes and complete a todo only once.
+		previous, err := r.getByID(ctx, tx, todo.ID, "FOR UPDATE OF todos")
+		if err != nil {
+			return err
+		}
+
+		ctx, span := startPostgresQuery(ctx, "todos", "todos.update", "UPDATE", query)
+		var ownerID sql.NullInt64
+		err = tx.QueryRowContext(ctx, query, todo.Title, nullString(todo.Description), todo.Completed, todo.ID).
+			Scan(&ownerID, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
+			span.fail(err)
+			span.End()
+			return fmt.Errorf("failed to update todo: %w", err)
+		}
+		span.rows(1)
+		span.End()
+
+		todo.OwnerID = nil
+		if ownerID.Valid {
+			todo.OwnerID = &ownerID.Int64
+		}
+
+		if err := r.emit(ctx, tx, events.New(events.TodoUpdated, todo)); err != nil {
+			return err
+		}
+
+		if todo.Completed && !previous.Completed {
+			return r.emit(ctx, tx, events.New(events.TodoCompleted, todo))
+		}
+
+		return nil
+	})
+}
+
+func (r *postgresTodoRepository) Delete(ctx context.Context, id int64) error {
+	var args pgArgs
+	// RETURNING sees the comments deleted by the cascade, which run after
+	// the statement, so the event carries the count the todo had.
+	query := `DELETE FROM todos
+		WHERE id = ` + args.add(id) + ` AND ` + postgresAccessScope(ctx, &args) + `
+		RETURNING ` + postgresTodoColumns
+
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		ctx, span := startPostgresQuery(ctx, "todos", "todos.delete", "DELETE", query)
+		defer span.End()
+
+		previous, err := scanTodo(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			span.rows(0)
+			return fmt.Errorf("todo with id %d %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return span.fail(fmt.Errorf("failed to delete todo: %w", err))
+		}
+		span.rows(1)
+
+		return r.emit(ctx, tx, events.New(events.TodoDeleted, previous))
+	})
+}
+
+// getByID loads a todo the caller of ctx may access, appending lock, such
+// as "FOR UPDATE", to the query.
+func (r *postgresTodoRepository) getByID(ctx context.Context, q queryRower, id int64, lock string) (*models.Todo, error) {
+	var args pgArgs
+	query := `SELECT ` + postgresTodoColumns + `
+		FROM todos
+		WHERE id = ` + args.add(id) + ` AND ` + postgresAccessScope(ctx, &args) + `
+		` + lock
+
+	ctx, span := startPostgresQuery(ctx, "todos", "todos.get_by_id", "SELECT", query)
+	defer span.End()
+
+	todo, err := scanTodo(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		span.rows(0)
+		return nil, fmt.Errorf("todo with id %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, span.fail(fmt.Errorf("failed to query todo by id: %w", err))
+	}
+
+	span.rows(1)
+	return todo, nil
+}
+
+// query runs a query selecting postgresTodoColumns, traced under name.
+func (r *postgresTodoRepository) query(ctx context.Context, name, query string, args ...interface{}) ([]models.Todo, error) {
+	ctx, span := startPostgresQuery(ctx, "todos", name, "SELECT", query)
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, span.fail(fmt.Errorf("failed to query todos: %w", err))
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, span.fail(fmt.Errorf("failed to scan todo: %w", err))
+		}
+		todos = append(todos, *todo)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, span.fail(fmt.Errorf("rows iteration error: %w", err))
+	}
+
+	span.rows(len(todos))
+	return todos, nil
+}
+
+func (r *postgresTodoRepository) emit(ctx context.Context, tx *sql.Tx, event events.Event) error {
+	for _, hook := range r.hooks {
+		if err := hook(tx, event); err != nil {
+			return err
+		}
+	}
+	events.Record(ctx, event)
+
+	return nil
+}
+
+// scanTodo reads a row of postgresTodoColumns.
+func scanTodo(row interface{ Scan(...interface{}) error }) (*models.Todo, error) {
+	var todo models.Todo
+	var description sql.NullString
+	var ownerID sql.NullInt64
+
+	err := row.Scan(
+		&todo.ID,
+		&todo.Title,
+		&description,
+		&todo.Completed,
+		&ownerID,
+		&todo.CreatedAt,
+		&todo.UpdatedAt,
+		&todo.CommentCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	todo.Description = description.String
+	if ownerID.Valid {
+		todo.OwnerID = &ownerID.Int64
+	}
+
+	return &todo, nil
+}
+
+// postgresAccessScope is accessScope for PostgreSQL, adding its arguments
+// to args.
+func postgresAccessScope(ctx context.Context, args *pgArgs) string {
+	if user := auth.Owner(ctx); user != 0 {
+		p := args.add(user)
+		return "(owner_id = " + p + " OR id IN (SELECT todo_id FROM todo_grants WHERE user_id = " + p + "))"
+	}
+
+	return "TRUE"
+}
+
+// pgArgs collects the arguments of a PostgreSQL query, numbering their
+// placeholders as they are added.
+type pgArgs []interface{}
+
+// add appends value and returns its placeholder, such as "$3".
+func (a *pgArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// nullString stores an empty description as NULL, as the SQLite
+// repository does.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
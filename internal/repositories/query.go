@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"strings"
+
+	"todo-api/internal/database"
+)
+
+// inClause returns the "(?, ?, ...)" list and arguments for an IN condition
+// over ids. ids must not be empty.
+func inClause(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")", args
+}
+
+// secondsFromNow returns the expression for the current time plus the
+// number of seconds bound to its placeholder, in the dialect of db.
+func secondsFromNow(db *database.DB) string {
+	if db.Dialect == database.Postgres {
+		return "CURRENT_TIMESTAMP + make_interval(secs => ?)"
+	}
+	return "datetime('now', '+' || ? || ' seconds')"
+}
@@ -0,0 +1,386 @@
+package repositories_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	testRepositories(t, func(t *testing.T) *database.DB {
+		path := filepath.Join(t.TempDir(), "todos.db")
+		return migratedDB(t, &config.DatabaseConfig{
+			Path:          path,
+			DSN:           path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+			MigrationsDir: filepath.Join("..", "..", "migrations"),
+		})
+	})
+}
+
+func TestPostgresRepositories(t *testing.T) {
+	dsn := os.Getenv(postgresURLEnv)
+	if dsn == "" {
+		t.Skip("set " + postgresURLEnv + " to a PostgreSQL URL to run")
+	}
+
+	testRepositories(t, func(t *testing.T) *database.DB {
+		return migratedDB(t, &config.DatabaseConfig{
+			DSN:           postgresSchema(t, dsn),
+			MigrationsDir: filepath.Join("..", "..", "migrations", "postgres"),
+		})
+	})
+}
+
+// testRepositories checks the repositories other than todos on an empty,
+// migrated database from newDB, which every dialect passes alike.
+func testRepositories(t *testing.T, newDB func(t *testing.T) *database.DB) {
+	ctx := context.Background()
+
+	// newTodo stores a todo without an owner in db.
+	newTodo := func(t *testing.T, db *database.DB, hooks ...repositories.TxHook) *models.Todo {
+		t.Helper()
+
+		todo := &models.Todo{Title: "Shared todo"}
+		if err := repositories.NewTodoRepository(db, hooks...).Create(ctx, todo); err != nil {
+			t.Fatalf("Create todo: %v", err)
+		}
+		return todo
+	}
+
+	t.Run("users", func(t *testing.T) {
+		users := repositories.NewUserRepository(newDB(t))
+
+		ada := &models.User{Email: "ada@example.com"}
+		if err := users.Create(ada, "hash"); err != nil || ada.ID == 0 {
+			t.Fatalf("Create = %v with id %d", err, ada.ID)
+		}
+		if err := users.Create(&models.User{Email: "ada@example.com"}, "hash"); !errors.Is(err, repositories.ErrConflict) {
+			t.Errorf("Create of a taken email: %v, want ErrConflict", err)
+		}
+		if got, hash, err := users.GetByEmail("ada@example.com"); err != nil || got.ID != ada.ID || hash != "hash" {
+			t.Errorf("GetByEmail = %+v, %q, %v", got, hash, err)
+		}
+		if _, err := users.GetByID(ada.ID + 1000); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("GetByID of an unknown id: %v, want ErrNotFound", err)
+		}
+
+		grace := &models.User{Email: "grace@example.com"}
+		if err := users.CreateWithIdentity(grace, "https://issuer.example.com", "sub-1"); err != nil {
+			t.Fatalf("CreateWithIdentity: %v", err)
+		}
+		if err := users.LinkIdentity(ada.ID, "https://issuer.example.com", "sub-2"); err != nil {
+			t.Fatalf("LinkIdentity: %v", err)
+		}
+		if got, err := users.GetByIdentity("https://issuer.example.com", "sub-2"); err != nil || got.ID != ada.ID {
+			t.Errorf("GetByIdentity = %+v, %v, want ada", got, err)
+		}
+		if err := users.LinkIdentity(ada.ID, "https://issuer.example.com", "sub-1"); !errors.Is(err, repositories.ErrConflict) {
+			t.Errorf("LinkIdentity of a linked subject: %v, want ErrConflict", err)
+		}
+		if err := users.LinkIdentity(grace.ID+1000, "https://issuer.example.com", "sub-3"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("LinkIdentity to an unknown user: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("grants", func(t *testing.T) {
+		db := newDB(t)
+		todo := newTodo(t, db)
+		user := &models.User{Email: "bob@example.com"}
+		if err := repositories.NewUserRepository(db).Create(user, "hash"); err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		grants := repositories.NewGrantRepository(db)
+
+		grant := &models.Grant{TodoID: todo.ID, UserID: user.ID, Role: models.RoleViewer}
+		if err := grants.Upsert(grant); err != nil || grant.CreatedAt.IsZero() {
+			t.Fatalf("Upsert = %v with created_at %v", err, grant.CreatedAt)
+		}
+		if err := grants.Upsert(&models.Grant{TodoID: todo.ID, UserID: user.ID, Role: models.RoleEditor}); err != nil {
+			t.Fatalf("Upsert of another role: %v", err)
+		}
+		if role, err := grants.GetRole(todo.ID, user.ID); err != nil || role != models.RoleEditor {
+			t.Errorf("GetRole = %q, %v, want editor", role, err)
+		}
+		if list, err := grants.GetByTodoID(todo.ID); err != nil || len(list) != 1 || list[0].Email != user.Email {
+			t.Errorf("GetByTodoID = %+v, %v", list, err)
+		}
+		if err := grants.Upsert(&models.Grant{TodoID: todo.ID + 1000, UserID: user.ID, Role: models.RoleViewer}); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Upsert on an unknown todo: %v, want ErrNotFound", err)
+		}
+
+		if err := grants.Delete(todo.ID, user.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := grants.Delete(todo.ID, user.ID); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("second Delete: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		db := newDB(t)
+		first, second := newTodo(t, db), newTodo(t, db)
+		comments := repositories.NewCommentRepository(db)
+
+		var ids []int64
+		for _, todo := range []*models.Todo{first, first, first, second} {
+			comment := &models.Comment{TodoID: todo.ID, Author: "ada", Body: "Looks good"}
+			if len(ids) > 0 && todo == first {
+				comment.ParentCommentID = &ids[0]
+			}
+			if err := comments.Create(comment); err != nil || comment.ID == 0 {
+				t.Fatalf("Create = %v with id %d", err, comment.ID)
+			}
+			ids = append(ids, comment.ID)
+		}
+
+		if page, err := comments.GetByTodoID(first.ID, 2, 1); err != nil || len(page) != 2 || page[0].ID != ids[1] || *page[0].ParentCommentID != ids[0] {
+			t.Errorf("GetByTodoID = %+v, %v, want the two replies", page, err)
+		}
+		if batch, err := comments.GetByTodoIDs([]int64{first.ID, second.ID}, 2); err != nil || len(batch) != 3 || batch[2].TodoID != second.ID {
+			t.Errorf("GetByTodoIDs = %+v, %v, want two comments of the first todo and one of the second", batch, err)
+		}
+
+		if err := comments.Update(&models.Comment{ID: ids[1], Body: "Edited"}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err := comments.GetByID(ids[1]); err != nil || got.Body != "Edited" || got.EditedAt == nil {
+			t.Errorf("GetByID after Update = %+v, %v", got, err)
+		}
+
+		if err := comments.SoftDelete(ids[2]); err != nil {
+			t.Fatalf("SoftDelete: %v", err)
+		}
+		if err := comments.SoftDelete(ids[2]); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("second SoftDelete: %v, want ErrNotFound", err)
+		}
+		page, err := comments.GetByTodoID(first.ID, 10, 0)
+		if err != nil || len(page) != 3 || page[2].DeletedAt == nil {
+			t.Errorf("GetByTodoID after SoftDelete = %+v, %v, want the deleted comment in place", page, err)
+		}
+		if count, err := comments.CountByTodoID(first.ID); err != nil || count != int64(len(page)) {
+			t.Errorf("CountByTodoID = %d, %v, want %d like the page", count, err, len(page))
+		}
+	})
+
+	t.Run("attachments", func(t *testing.T) {
+		db := newDB(t)
+		todo := newTodo(t, db)
+		attachments := repositories.NewAttachmentRepository(db)
+
+		attachment := &models.Attachment{TodoID: todo.ID, Filename: "notes.txt", ContentType: "text/plain", Size: 5, SHA256: "abc123"}
+		if err := attachments.Create(attachment); err != nil || attachment.ID == 0 {
+			t.Fatalf("Create = %v with id %d", err, attachment.ID)
+		}
+		if got, err := attachments.GetByID(attachment.ID); err != nil || got.Filename != "notes.txt" || got.Size != 5 {
+			t.Errorf("GetByID = %+v, %v", got, err)
+		}
+		if list, err := attachments.GetByTodoIDs([]int64{todo.ID, todo.ID + 1000}); err != nil || len(list) != 1 {
+			t.Errorf("GetByTodoIDs = %+v, %v", list, err)
+		}
+		if referenced, err := attachments.ReferencedSHA256(); err != nil || !referenced["abc123"] {
+			t.Errorf("ReferencedSHA256 = %v, %v", referenced, err)
+		}
+
+		if err := attachments.Delete(attachment.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := attachments.Delete(attachment.ID); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("second Delete: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		db := newDB(t)
+		webhooks := repositories.NewWebhookRepository(db)
+
+		subscription := &models.WebhookSubscription{
+			URL:        "https://hooks.example.com",
+			EventTypes: []string{"todo.created", "todo.deleted"},
+			Secret:     "secret",
+			Active:     true,
+		}
+		if err := webhooks.Create(subscription); err != nil || subscription.ID == 0 {
+			t.Fatalf("Create = %v with id %d", err, subscription.ID)
+		}
+		ignored := &models.WebhookSubscription{URL: "https://other.example.com", EventTypes: []string{"todo.updated"}, Secret: "secret", Active: true}
+		if err := webhooks.Create(ignored); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		todo := newTodo(t, db, webhooks.Enqueue)
+		due, err := webhooks.DueDeliveries(10)
+		if err != nil || len(due) != 1 || due[0].SubscriptionID != subscription.ID || due[0].URL != subscription.URL {
+			t.Fatalf("DueDeliveries = %+v, %v, want one delivery to %s", due, err, subscription.URL)
+		}
+		var payload struct {
+			TodoID int64 `json:"todo_id"`
+		}
+		if err := json.Unmarshal(due[0].Payload, &payload); err != nil || payload.TodoID != todo.ID {
+			t.Errorf("payload %s, want the creation of todo %d", due[0].Payload, todo.ID)
+		}
+
+		id := due[0].ID
+		if err := webhooks.MarkFailed(id, 500, "server error", time.Hour, false); err != nil {
+			t.Fatalf("MarkFailed: %v", err)
+		}
+		if due, err := webhooks.DueDeliveries(10); err != nil || len(due) != 0 {
+			t.Errorf("DueDeliveries before the retry = %+v, %v, want none", due, err)
+		}
+		if err := webhooks.MarkFailed(id, 0, "connection refused", 0, true); err != nil {
+			t.Fatalf("MarkFailed: %v", err)
+		}
+		if dead, total, err := webhooks.GetDeliveries(subscription.ID, models.DeliveryDead, 10, 0); err != nil || total != 1 || dead[0].Attempts != 2 || dead[0].LastError != "connection refused" {
+			t.Errorf("GetDeliveries of dead = %+v, %d, %v", dead, total, err)
+		}
+
+		if replayed, err := webhooks.Replay(subscription.ID, 0); err != nil || replayed != 1 {
+			t.Fatalf("Replay = %d, %v, want 1", replayed, err)
+		}
+		if err := webhooks.MarkDelivered(id, 204); err != nil {
+			t.Fatalf("MarkDelivered: %v", err)
+		}
+		if delivered, _, err := webhooks.GetDeliveries(subscription.ID, models.DeliveryDelivered, 10, 0); err != nil || len(delivered) != 1 || delivered[0].DeliveredAt == nil || delivered[0].LastStatusCode != 204 {
+			t.Errorf("GetDeliveries of delivered = %+v, %v", delivered, err)
+		}
+		if _, err := webhooks.Replay(subscription.ID, id); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Replay of a delivered delivery: %v, want ErrNotFound", err)
+		}
+
+		subscription.Active = false
+		if err := webhooks.Update(subscription); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err := webhooks.GetByID(subscription.ID); err != nil || got.Active || len(got.EventTypes) != 2 {
+			t.Errorf("GetByID after Update = %+v, %v", got, err)
+		}
+		if err := webhooks.Delete(subscription.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if all, err := webhooks.GetAll(); err != nil || len(all) != 1 || all[0].ID != ignored.ID {
+			t.Errorf("GetAll after Delete = %+v, %v", all, err)
+		}
+	})
+
+	t.Run("api keys", func(t *testing.T) {
+		db := newDB(t)
+		user := &models.User{Email: "ada@example.com"}
+		if err := repositories.NewUserRepository(db).Create(user, "hash"); err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		keys := repositories.NewAPIKeyRepository(db)
+
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		key := &models.APIKey{Name: "login", Prefix: "todo_abc", Scopes: []string{"todos:read", "todos:write"}, UserID: &user.ID, ExpiresAt: &expires}
+		if err := keys.Create(key, "hash-1"); err != nil || key.ID == 0 {
+			t.Fatalf("Create = %v with id %d", err, key.ID)
+		}
+		got, err := keys.GetByHash("hash-1")
+		if err != nil || got.ID != key.ID || len(got.Scopes) != 2 || *got.UserID != user.ID || !got.ExpiresAt.Equal(expires) {
+			t.Fatalf("GetByHash = %+v, %v", got, err)
+		}
+
+		if err := keys.TouchLastUsed(key.ID, time.Now()); err != nil {
+			t.Fatalf("TouchLastUsed: %v", err)
+		}
+		if got, err := keys.GetByID(key.ID); err != nil || got.LastUsedAt == nil {
+			t.Errorf("GetByID after TouchLastUsed = %+v, %v", got, err)
+		}
+
+		if err := keys.Delete(key.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if all, err := keys.GetAll(); err != nil || len(all) != 0 {
+			t.Errorf("GetAll after Delete = %+v, %v", all, err)
+		}
+	})
+
+	t.Run("share links", func(t *testing.T) {
+		db := newDB(t)
+		todo := newTodo(t, db)
+		links := repositories.NewShareLinkRepository(db)
+
+		link := &models.ShareLink{TodoID: todo.ID, Prefix: "share_abc"}
+		if err := links.Create(link, "token-hash", "password-hash"); err != nil || link.ID == 0 {
+			t.Fatalf("Create = %v with id %d", err, link.ID)
+		}
+		if err := links.RecordAccess(link.ID, time.Now()); err != nil {
+			t.Fatalf("RecordAccess: %v", err)
+		}
+		got, password, err := links.GetByHash("token-hash")
+		if err != nil || got.ID != link.ID || !got.HasPassword || password != "password-hash" || got.AccessCount != 1 || got.LastAccessedAt == nil {
+			t.Fatalf("GetByHash = %+v, %q, %v", got, password, err)
+		}
+
+		if err := links.Delete(todo.ID+1000, link.ID); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Delete through another todo: %v, want ErrNotFound", err)
+		}
+		if err := links.Delete(todo.ID, link.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if list, err := links.GetByTodoID(todo.ID); err != nil || len(list) != 0 {
+			t.Errorf("GetByTodoID after Delete = %+v, %v", list, err)
+		}
+	})
+
+	t.Run("idempotency keys", func(t *testing.T) {
+		keys := repositories.NewIdempotencyRepository(newDB(t))
+
+		record := &models.IdempotencyRecord{Scope: "key:1", Key: "retry-1", Fingerprint: "post /todos"}
+		if existing, err := keys.Reserve(record, time.Hour); err != nil || existing != nil {
+			t.Fatalf("Reserve = %+v, %v", existing, err)
+		}
+		if existing, err := keys.Reserve(record, time.Hour); !errors.Is(err, repositories.ErrConflict) || existing.StatusCode != 0 {
+			t.Errorf("Reserve in flight = %+v, %v, want ErrConflict without a response", existing, err)
+		}
+
+		record.StatusCode, record.ContentType, record.Body = 201, "application/json", []byte(`{"id":1}`)
+		if err := keys.Complete(record, time.Hour); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		existing, err := keys.Reserve(record, time.Hour)
+		if !errors.Is(err, repositories.ErrConflict) || existing.StatusCode != 201 || string(existing.Body) != `{"id":1}` || existing.Fingerprint != "post /todos" {
+			t.Errorf("Reserve after Complete = %+v, %v, want the stored response", existing, err)
+		}
+
+		if err := keys.Release(record.Scope, record.Key); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		if err := keys.Complete(record, time.Hour); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Complete after Release: %v, want ErrNotFound", err)
+		}
+
+		// A record whose lease ran out can be reserved again, and one
+		// completed is kept for the lifetime given to Complete.
+		leased := &models.IdempotencyRecord{Scope: "key:1", Key: "retry-2", Fingerprint: "post /todos"}
+		if _, err := keys.Reserve(leased, 0); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		if existing, err := keys.Reserve(leased, 0); err != nil || existing != nil {
+			t.Errorf("Reserve after the lease ran out = %+v, %v, want it reserved again", existing, err)
+		}
+		leased.StatusCode = 201
+		if err := keys.Complete(leased, time.Hour); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if deleted, err := keys.DeleteExpired(); err != nil || deleted != 0 {
+			t.Errorf("DeleteExpired after Complete = %d, %v, want 0", deleted, err)
+		}
+
+		// A record without a lifetime expires at once.
+		if _, err := keys.Reserve(&models.IdempotencyRecord{Scope: "key:1", Key: "retry-3", Fingerprint: "post /todos"}, 0); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		if deleted, err := keys.DeleteExpired(); err != nil || deleted != 1 {
+			t.Errorf("DeleteExpired = %d, %v, want 1", deleted, err)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type ShareLinkRepository interface {
+	GetByTodoID(todoID int64) ([]models.ShareLink, error)
+	GetByHash(hash string) (*models.ShareLink, string, error)
+	Create(link *models.ShareLink, tokenHash, passwordHash string) error
+	Delete(todoID, id int64) error
+	RecordAccess(id int64, at time.Time) error
+}
+
+type shareLinkRepository struct {
+	db *database.DB
+}
+
+func NewShareLinkRepository(db *database.DB) ShareLinkRepository {
+	return &shareLinkRepository{db: db}
+}
+
+const shareLinkColumns = `id, todo_id, prefix, password_hash, expires_at, access_count, last_accessed_at, created_at`
+
+// scanShareLink reads a share link and the hash of its password, which is
+// empty for links without one.
+func scanShareLink(row rowScanner) (*models.ShareLink, string, error) {
+	var link models.ShareLink
+	var passwordHash sql.NullString
+	var expiresAt, lastAccessedAt sql.NullTime
+
+	err := row.Scan(
+		&link.ID,
+		&link.TodoID,
+		&link.Prefix,
+		&passwordHash,
+		&expiresAt,
+		&link.AccessCount,
+		&lastAccessedAt,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	link.HasPassword = passwordHash.Valid
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if lastAccessedAt.Valid {
+		link.LastAccessedAt = &lastAccessedAt.Time
+	}
+
+	return &link, passwordHash.String, nil
+}
+
+func (r *shareLinkRepository) GetByTodoID(todoID int64) ([]models.ShareLink, error) {
+	rows, err := r.db.Query(r.db.Rebind(`SELECT `+shareLinkColumns+` FROM share_links WHERE todo_id = ? ORDER BY id ASC`), todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		link, _, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, *link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return links, nil
+}
+
+// GetByHash returns the link whose token hashes to hash and the hash of its
+// password.
+func (r *shareLinkRepository) GetByHash(hash string) (*models.ShareLink, string, error) {
+	link, passwordHash, err := scanShareLink(r.db.QueryRow(r.db.Rebind(`SELECT `+shareLinkColumns+` FROM share_links WHERE token_hash = ?`), hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("share link %w", ErrNotFound)
+		}
+		return nil, "", fmt.Errorf("failed to query share link: %w", err)
+	}
+
+	return link, passwordHash, nil
+}
+
+func (r *shareLinkRepository) Create(link *models.ShareLink, tokenHash, passwordHash string) error {
+	var password interface{}
+	if passwordHash != "" {
+		password = passwordHash
+	}
+
+	var id int64
+	err := r.db.QueryRow(
+		r.db.Rebind(`INSERT INTO share_links (todo_id, prefix, token_hash, password_hash, expires_at) VALUES (?, ?, ?, ?, ?) RETURNING id`),
+		link.TodoID, link.Prefix, tokenHash, password, link.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	link.ID = id
+	link.HasPassword = passwordHash != ""
+	link.AccessCount = 0
+	link.LastAccessedAt = nil
+	link.CreatedAt = time.Now()
+
+	return nil
+}
+
+// Delete revokes a link of the given todo.
+func (r *shareLinkRepository) Delete(todoID, id int64) error {
+	result, err := r.db.Exec(r.db.Rebind(`DELETE FROM share_links WHERE id = ? AND todo_id = ?`), id, todoID)
+	if err != nil {
+		return fmt.Errorf("failed to delete share link: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("share link with id %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// RecordAccess counts a view of the link.
+func (r *shareLinkRepository) RecordAccess(id int64, at time.Time) error {
+	_, err := r.db.Exec(r.db.Rebind(`UPDATE share_links SET access_count = access_count + 1, last_accessed_at = ? WHERE id = ?`), at, id)
+	if err != nil {
+		return fmt.Errorf("failed to record share link access: %w", err)
+	}
+
+	return nil
+}
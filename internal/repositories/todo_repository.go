@@ -1,40 +1,71 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"todo-api/internal/auth"
 	"todo-api/internal/database"
+	"todo-api/internal/events"
 	"todo-api/internal/models"
 )
 
+// TodoRepository stores todos. Every method is limited to the todos the
+// user authenticated in ctx owns or was granted a role on, so a user can
+// never reach another user's todos unless they were shared; callers not
+// tied to a user see every todo. Which role a change needs is checked by the
+// services.
 type TodoRepository interface {
-	GetAll() ([]models.Todo, error)
-	GetByID(id int64) (*models.Todo, error)
-	Create(todo *models.Todo) error
-	Update(todo *models.Todo) error
-	Delete(id int64) error
+	GetAll(ctx context.Context) ([]models.Todo, error)
+	List(ctx context.Context, filter models.TodoFilter) ([]models.Todo, int64, error)
+	GetByID(ctx context.Context, id int64) (*models.Todo, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]models.Todo, error)
+	Create(ctx context.Context, todo *models.Todo) error
+	Update(ctx context.Context, todo *models.Todo) error
+	Delete(ctx context.Context, id int64) error
+	// CountCreatedSince counts the todos owner created at or after since,
+	// regardless of the caller of ctx.
+	CountCreatedSince(ctx context.Context, owner int64, since time.Time) (int64, error)
 }
 
+// TxHook is called for every event produced by a todo write, inside the
+// same transaction, so whatever it stores commits or rolls back with the write.
+// The write then records the event in the events.Recorder of its context.
+type TxHook func(tx *sql.Tx, event events.Event) error
+
 type todoRepository struct {
-	db *database.DB
+	db    *database.DB
+	hooks []TxHook
 }
 
-func NewTodoRepository(db *database.DB) TodoRepository {
-	return &todoRepository{db: db}
+// NewTodoRepository returns the repository for the dialect of db, SQLite or
+// PostgreSQL.
+func NewTodoRepository(db *database.DB, hooks ...TxHook) TodoRepository {
+	if db.Dialect == database.Postgres {
+		return &postgresTodoRepository{db: db, hooks: hooks}
+	}
+	return &todoRepository{db: db, hooks: hooks}
 }
 
-func (r *todoRepository) GetAll() ([]models.Todo, error) {
+func (r *todoRepository) GetAll(ctx context.Context) ([]models.Todo, error) {
+	owner, ownerArgs := accessScope(ctx)
 	query := `
-		SELECT id, title, description, completed, created_at, updated_at 
+		SELECT id, title, description, completed, owner_id, created_at, updated_at, 
+			(SELECT COUNT(*) FROM comments c WHERE c.todo_id = todos.id AND c.deleted_at IS NULL) AS comment_count
 		FROM todos 
+		WHERE ` + owner + `
 		ORDER BY created_at DESC
 	`
 	
-	rows, err := r.db.Query(query)
+	ctx, span := startQuery(ctx, "todos", "todos.get_all", "SELECT", query)
+	defer span.End()
+	
+	rows, err := r.db.QueryContext(ctx, query, ownerArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query todos: %w", err)
+		return nil, span.fail(fmt.Errorf("failed to query todos: %w", err))
 	}
 	defer rows.Close()
 	
@@ -42,70 +73,260 @@ func (r *todoRepository) GetAll() ([]models.Todo, error) {
 	for rows.Next() {
 		var todo models.Todo
 		var description sql.NullString
+		var ownerID sql.NullInt64
 		
 		err := rows.Scan(
 			&todo.ID,
 			&todo.Title,
 			&description,
 			&todo.Completed,
+			&ownerID,
 			&todo.CreatedAt,
 			&todo.UpdatedAt,
+			&todo.CommentCount,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan todo: %w", err)
+			return nil, span.fail(fmt.Errorf("failed to scan todo: %w", err))
 		}
 		
 		if description.Valid {
 			todo.Description = description.String
 		}
+		if ownerID.Valid {
+			todo.OwnerID = &ownerID.Int64
+		}
 		
 		todos = append(todos, todo)
 	}
 	
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+		return nil, span.fail(fmt.Errorf("rows iteration error: %w", err))
 	}
 	
+	span.rows(len(todos))
 	return todos, nil
 }
 
-func (r *todoRepository) GetByID(id int64) (*models.Todo, error) {
+// List returns the todos matching filter, newest first, along with the number
+// of matches before limit and offset are applied.
+func (r *todoRepository) List(ctx context.Context, filter models.TodoFilter) ([]models.Todo, int64, error) {
+	owner, args := accessScope(ctx)
+	conditions := []string{owner}
+	
+	if filter.Completed != nil {
+		conditions = append(conditions, "completed = ?")
+		args = append(args, *filter.Completed)
+	}
+	
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+		conditions = append(conditions, `(title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	
+	where := "WHERE " + strings.Join(conditions, " AND ")
+	
+	var total int64
+	countQuery := `SELECT COUNT(*) FROM todos ` + where
+	countCtx, countSpan := startQuery(ctx, "todos", "todos.list.count", "SELECT", countQuery)
+	err := r.db.QueryRowContext(countCtx, countQuery, args...).Scan(&total)
+	if err != nil {
+		countSpan.fail(err)
+	}
+	countSpan.End()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count todos: %w", err)
+	}
+	
 	query := `
-		SELECT id, title, description, completed, created_at, updated_at 
+		SELECT id, title, description, completed, owner_id, created_at, updated_at, 
+			(SELECT COUNT(*) FROM comments c WHERE c.todo_id = todos.id AND c.deleted_at IS NULL) AS comment_count
 		FROM todos 
-		WHERE id = ?
+		` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
 	`
 	
+	ctx, span := startQuery(ctx, "todos", "todos.list", "SELECT", query)
+	defer span.End()
+	
+	rows, err := r.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, span.fail(fmt.Errorf("failed to query todos: %w", err))
+	}
+	defer rows.Close()
+	
+	todos := []models.Todo{}
+	for rows.Next() {
+		var todo models.Todo
+		var description sql.NullString
+		var ownerID sql.NullInt64
+		
+		err := rows.Scan(
+			&todo.ID,
+			&todo.Title,
+			&description,
+			&todo.Completed,
+			&ownerID,
+			&todo.CreatedAt,
+			&todo.UpdatedAt,
+			&todo.CommentCount,
+		)
+		if err != nil {
+			return nil, 0, span.fail(fmt.Errorf("failed to scan todo: %w", err))
+		}
+		
+		if description.Valid {
+			todo.Description = description.String
+		}
+		if ownerID.Valid {
+			todo.OwnerID = &ownerID.Int64
+		}
+		
+		todos = append(todos, todo)
+	}
+	
+	if err = rows.Err(); err != nil {
+		return nil, 0, span.fail(fmt.Errorf("rows iteration error: %w", err))
+	}
+	
+	span.rows(len(todos))
+	return todos, total, nil
+}
+
+func (r *todoRepository) GetByID(ctx context.Context, id int64) (*models.Todo, error) {
+	return r.getByID(ctx, r.db, id)
+}
+
+func (r *todoRepository) CountCreatedSince(ctx context.Context, owner int64, since time.Time) (int64, error) {
+	query := `SELECT COUNT(*) FROM todos WHERE owner_id = ? AND created_at >= ?`
+	
+	ctx, span := startQuery(ctx, "todos", "todos.count_created", "SELECT", query)
+	defer span.End()
+	
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, owner, since.UTC().Format(time.DateTime)).Scan(&count); err != nil {
+		return 0, span.fail(fmt.Errorf("failed to count created todos: %w", err))
+	}
+	
+	return count, nil
+}
+
+// GetByIDs loads several todos in one query. Unknown ids are skipped.
+func (r *todoRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Todo, error) {
+	todos := []models.Todo{}
+	if len(ids) == 0 {
+		return todos, nil
+	}
+	
+	in, args := inClause(ids)
+	owner, ownerArgs := accessScope(ctx)
+	query := `
+		SELECT id, title, description, completed, owner_id, created_at, updated_at, 
+			(SELECT COUNT(*) FROM comments c WHERE c.todo_id = todos.id AND c.deleted_at IS NULL) AS comment_count
+		FROM todos 
+		WHERE id IN ` + in + ` AND ` + owner
+	
+	ctx, span := startQuery(ctx, "todos", "todos.get_by_ids", "SELECT", query)
+	defer span.End()
+	
+	rows, err := r.db.QueryContext(ctx, query, append(args, ownerArgs...)...)
+	if err != nil {
+		return nil, span.fail(fmt.Errorf("failed to query todos by ids: %w", err))
+	}
+	defer rows.Close()
+	
+	for rows.Next() {
+		var todo models.Todo
+		var description sql.NullString
+		var ownerID sql.NullInt64
+		
+		err := rows.Scan(
+			&todo.ID,
+			&todo.Title,
+			&description,
+			&todo.Completed,
+			&ownerID,
+			&todo.CreatedAt,
+			&todo.UpdatedAt,
+			&todo.CommentCount,
+		)
+		if err != nil {
+			return nil, span.fail(fmt.Errorf("failed to scan todo: %w", err))
+		}
+		
+		if description.Valid {
+			todo.Description = description.String
+		}
+		if ownerID.Valid {
+			todo.OwnerID = &ownerID.Int64
+		}
+		
+		todos = append(todos, todo)
+	}
+	
+	if err = rows.Err(); err != nil {
+		return nil, span.fail(fmt.Errorf("rows iteration error: %w", err))
+	}
+	
+	span.rows(len(todos))
+	return todos, nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *todoRepository) getByID(ctx context.Context, q queryRower, id int64) (*models.Todo, error) {
+	owner, ownerArgs := accessScope(ctx)
+	query := `
+		SELECT id, title, description, completed, owner_id, created_at, updated_at, 
+			(SELECT COUNT(*) FROM comments c WHERE c.todo_id = todos.id AND c.deleted_at IS NULL) AS comment_count
+		FROM todos 
+		WHERE id = ? AND ` + owner + `
+	`
+	
+	ctx, span := startQuery(ctx, "todos", "todos.get_by_id", "SELECT", query)
+	defer span.End()
+	
 	var todo models.Todo
 	var description sql.NullString
+	var ownerID sql.NullInt64
 	
-	err := r.db.QueryRow(query, id).Scan(
+	err := q.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&todo.ID,
 		&todo.Title,
 		&description,
 		&todo.Completed,
+		&ownerID,
 		&todo.CreatedAt,
 		&todo.UpdatedAt,
+		&todo.CommentCount,
 	)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("todo with id %d not found", id)
+			span.rows(0)
+			return nil, fmt.Errorf("todo with id %d %w", id, ErrNotFound)
 		}
-		return nil, fmt.Errorf("failed to query todo by id: %w", err)
+		return nil, span.fail(fmt.Errorf("failed to query todo by id: %w", err))
 	}
 	
 	if description.Valid {
 		todo.Description = description.String
 	}
+	if ownerID.Valid {
+		todo.OwnerID = &ownerID.Int64
+	}
 	
+	span.rows(1)
 	return &todo, nil
 }
 
-func (r *todoRepository) Create(todo *models.Todo) error {
+func (r *todoRepository) Create(ctx context.Context, todo *models.Todo) error {
 	query := `
-		INSERT INTO todos (title, description, completed) 
-		VALUES (?, ?, ?)
+		INSERT INTO todos (title, description, completed, owner_id) 
+		VALUES (?, ?, ?, ?)
 	`
 	
 	var description interface{}
@@ -113,28 +334,40 @@ func (r *todoRepository) Create(todo *models.Todo) error {
 		description = todo.Description
 	}
 	
-	result, err := r.db.Exec(query, todo.Title, description, todo.Completed)
-	if err != nil {
-		return fmt.Errorf("failed to create todo: %w", err)
+	todo.OwnerID = nil
+	if owner := auth.Owner(ctx); owner != 0 {
+		todo.OwnerID = &owner
 	}
 	
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-	
-	todo.ID = id
-	todo.CreatedAt = time.Now()
-	todo.UpdatedAt = time.Now()
-	
-	return nil
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		ctx, span := startQuery(ctx, "todos", "todos.insert", "INSERT", query)
+		defer span.End()
+		
+		result, err := tx.ExecContext(ctx, query, todo.Title, description, todo.Completed, todo.OwnerID)
+		if err != nil {
+			return span.fail(fmt.Errorf("failed to create todo: %w", err))
+		}
+		
+		id, err := result.LastInsertId()
+		if err != nil {
+			return span.fail(fmt.Errorf("failed to get last insert id: %w", err))
+		}
+		span.rows(1)
+		
+		todo.ID = id
+		todo.CreatedAt = time.Now()
+		todo.UpdatedAt = time.Now()
+		
+		return r.emit(ctx, tx, events.New(events.TodoCreated, todo))
+	})
 }
 
-func (r *todoRepository) Update(todo *models.Todo) error {
+func (r *todoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	owner, ownerArgs := accessScope(ctx)
 	query := `
 		UPDATE todos 
 		SET title = ?, description = ?, completed = ? 
-		WHERE id = ?
+		WHERE id = ? AND ` + owner + `
 	`
 	
 	var description interface{}
@@ -142,41 +375,84 @@ func (r *todoRepository) Update(todo *models.Todo) error {
 		description = todo.Description
 	}
 	
-	result, err := r.db.Exec(query, todo.Title, description, todo.Completed, todo.ID)
-	if err != nil {
-		return fmt.Errorf("failed to update todo: %w", err)
-	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		previous, err := r.getByID(ctx, tx, todo.ID)
+		if err != nil {
+			return err
+		}
+		
+		if err := r.exec(ctx, tx, "todos.update", "UPDATE", query, append([]interface{}{todo.Title, description, todo.Completed, todo.ID}, ownerArgs...)...); err != nil {
+			return fmt.Errorf("failed to update todo: %w", err)
+		}
+		
+		todo.OwnerID = previous.OwnerID
+		todo.CreatedAt = previous.CreatedAt
+		todo.UpdatedAt = time.Now()
+		
+		if err := r.emit(ctx, tx, events.New(events.TodoUpdated, todo)); err != nil {
+			return err
+		}
+		
+		if todo.Completed && !previous.Completed {
+			return r.emit(ctx, tx, events.New(events.TodoCompleted, todo))
+		}
+		
+		return nil
+	})
+}
+
+func (r *todoRepository) Delete(ctx context.Context, id int64) error {
+	owner, ownerArgs := accessScope(ctx)
+	query := `DELETE FROM todos WHERE id = ? AND ` + owner
 	
-	if rowsAffected == 0 {
-		return fmt.Errorf("todo with id %d not found", todo.ID)
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		previous, err := r.getByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		
+		if err := r.exec(ctx, tx, "todos.delete", "DELETE", query, append([]interface{}{id}, ownerArgs...)...); err != nil {
+			return fmt.Errorf("failed to delete todo: %w", err)
+		}
+		
+		return r.emit(ctx, tx, events.New(events.TodoDeleted, previous))
+	})
+}
+
+// accessScope returns the condition limiting a query to the todos the user
+// authenticated in ctx owns or was granted a role on, with its arguments.
+// Callers not tied to a user are not limited.
+func accessScope(ctx context.Context) (string, []interface{}) {
+	if user := auth.Owner(ctx); user != 0 {
+		return "(owner_id = ? OR id IN (SELECT todo_id FROM todo_grants WHERE user_id = ?))", []interface{}{user, user}
 	}
 	
-	todo.UpdatedAt = time.Now()
-	
-	return nil
+	return "1 = 1", nil
 }
 
-func (r *todoRepository) Delete(id int64) error {
-	query := `DELETE FROM todos WHERE id = ?`
+// exec runs a statement changing todos inside tx, traced under name.
+func (r *todoRepository) exec(ctx context.Context, tx *sql.Tx, name, operation, query string, args ...interface{}) error {
+	ctx, span := startQuery(ctx, "todos", name, operation, query)
+	defer span.End()
 	
-	result, err := r.db.Exec(query, id)
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to delete todo: %w", err)
+		return span.fail(err)
 	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	if affected, err := result.RowsAffected(); err == nil {
+		span.rows(int(affected))
 	}
 	
-	if rowsAffected == 0 {
-		return fmt.Errorf("todo with id %d not found", id)
+	return nil
+}
+
+func (r *todoRepository) emit(ctx context.Context, tx *sql.Tx, event events.Event) error {
+	for _, hook := range r.hooks {
+		if err := hook(tx, event); err != nil {
+			return err
+		}
 	}
+	events.Record(ctx, event)
 	
 	return nil
 }
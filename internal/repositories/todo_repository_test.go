@@ -0,0 +1,568 @@
+package repositories_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// postgresURLEnv names a PostgreSQL database to run the conformance suite
+// against. Each run works in a schema of its own, dropped afterwards.
+const postgresURLEnv = "TODO_TEST_POSTGRES_URL"
+
+// todoBackend is a TodoRepository under test, with the means to set up the
+// users and grants its access checks read.
+type todoBackend struct {
+	repo    repositories.TodoRepository
+	addUser func(t *testing.T) int64
+	grant   func(t *testing.T, todoID, userID int64)
+	// nullDescription reports whether the todo's description is stored as
+	// NULL. It is nil for stores without NULLs.
+	nullDescription func(t *testing.T, id int64) bool
+}
+
+func TestMemoryTodoRepository(t *testing.T) {
+	testTodoRepository(t, func(t *testing.T) todoBackend {
+		grants := &memoryGrants{roles: make(map[[2]int64]string)}
+		var users int64
+		return todoBackend{
+			repo: repositories.NewMemoryTodoRepository(grants),
+			addUser: func(*testing.T) int64 {
+				users++
+				return users
+			},
+			grant: func(_ *testing.T, todoID, userID int64) {
+				grants.roles[[2]int64{todoID, userID}] = models.RoleViewer
+			},
+		}
+	})
+}
+
+func TestSQLiteTodoRepository(t *testing.T) {
+	testTodoRepository(t, func(t *testing.T) todoBackend {
+		path := filepath.Join(t.TempDir(), "todos.db")
+		return sqlBackend(t, &config.DatabaseConfig{
+			Path:          path,
+			DSN:           path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+			MigrationsDir: filepath.Join("..", "..", "migrations"),
+		})
+	})
+}
+
+func TestPostgresTodoRepository(t *testing.T) {
+	dsn := os.Getenv(postgresURLEnv)
+	if dsn == "" {
+		t.Skip("set " + postgresURLEnv + " to a PostgreSQL URL to run")
+	}
+
+	testTodoRepository(t, func(t *testing.T) todoBackend {
+		return sqlBackend(t, &config.DatabaseConfig{
+			DSN:           postgresSchema(t, dsn),
+			MigrationsDir: filepath.Join("..", "..", "migrations", "postgres"),
+		})
+	})
+}
+
+// testTodoRepository is the conformance suite every TodoRepository passes.
+// newBackend returns an empty repository for each subtest.
+func testTodoRepository(t *testing.T, newBackend func(t *testing.T) todoBackend) {
+	ctx := context.Background()
+
+	t.Run("creates and reads todos", func(t *testing.T) {
+		b := newBackend(t)
+
+		todo := &models.Todo{Title: "Buy groceries", Description: "Milk, eggs"}
+		if err := b.repo.Create(ctx, todo); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if todo.ID == 0 || todo.CreatedAt.IsZero() || todo.UpdatedAt.IsZero() {
+			t.Fatalf("Create left ID %d, created_at %v, updated_at %v unset", todo.ID, todo.CreatedAt, todo.UpdatedAt)
+		}
+		if todo.OwnerID != nil {
+			t.Errorf("owner %d for a caller without a user, want none", *todo.OwnerID)
+		}
+
+		got, err := b.repo.GetByID(ctx, todo.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Title != todo.Title || got.Description != todo.Description || got.Completed {
+			t.Errorf("GetByID = %+v, want %+v", got, todo)
+		}
+
+		if _, err := b.repo.GetByID(ctx, todo.ID+1000); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("GetByID of an unknown id: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("reports unknown todos as not found", func(t *testing.T) {
+		b := newBackend(t)
+		todo := createTodo(t, b, ctx, "Existing todo")
+		unknown := todo.ID + 1000
+
+		if _, err := b.repo.GetByID(ctx, unknown); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("GetByID: %v, want ErrNotFound", err)
+		}
+		if err := b.repo.Update(ctx, &models.Todo{ID: unknown, Title: "Unknown todo"}); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Update: %v, want ErrNotFound", err)
+		}
+		if err := b.repo.Delete(ctx, unknown); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Delete: %v, want ErrNotFound", err)
+		}
+
+		if _, err := b.repo.GetByID(ctx, unknown); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("GetByID after updating an unknown todo: %v, want ErrNotFound", err)
+		}
+		if _, total, err := b.repo.List(ctx, models.TodoFilter{Limit: 10}); err != nil || total != 1 {
+			t.Errorf("List after failed writes = %d todos, %v; want 1", total, err)
+		}
+	})
+
+	t.Run("moves updated_at on update only", func(t *testing.T) {
+		b := newBackend(t)
+		todo := createTodo(t, b, ctx, "Water the plants")
+		other := createTodo(t, b, ctx, "Call the plumber")
+		stored, err := b.repo.GetByID(ctx, todo.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		otherStored, err := b.repo.GetByID(ctx, other.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if stored.UpdatedAt.Before(stored.CreatedAt) {
+			t.Errorf("updated_at %v before created_at %v", stored.UpdatedAt, stored.CreatedAt)
+		}
+
+		waitNextSecond(stored.UpdatedAt)
+		todo.Completed = true
+		if err := b.repo.Update(ctx, todo); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		got, err := b.repo.GetByID(ctx, todo.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !got.UpdatedAt.After(stored.UpdatedAt) {
+			t.Errorf("updated_at %v after Update, want later than %v", got.UpdatedAt, stored.UpdatedAt)
+		}
+		if !got.CreatedAt.Equal(stored.CreatedAt) {
+			t.Errorf("created_at %v after Update, want %v", got.CreatedAt, stored.CreatedAt)
+		}
+
+		untouched, err := b.repo.GetByID(ctx, other.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !untouched.UpdatedAt.Equal(otherStored.UpdatedAt) {
+			t.Errorf("updated_at of another todo moved from %v to %v", otherStored.UpdatedAt, untouched.UpdatedAt)
+		}
+	})
+
+	t.Run("lists newest first", func(t *testing.T) {
+		b := newBackend(t)
+		var ids []int64
+		for _, title := range []string{"First todo", "Second todo", "Third todo", "Fourth todo"} {
+			ids = append(ids, createTodo(t, b, ctx, title).ID)
+		}
+
+		var listed []int64
+		for offset := 0; offset < len(ids); offset += 3 {
+			page, _, err := b.repo.List(ctx, models.TodoFilter{Limit: 3, Offset: offset})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			for _, todo := range page {
+				listed = append(listed, todo.ID)
+			}
+		}
+		want := []int64{ids[3], ids[2], ids[1], ids[0]}
+		if fmt.Sprint(listed) != fmt.Sprint(want) {
+			t.Errorf("List pages = %v, want %v", listed, want)
+		}
+
+		all, err := b.repo.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		for i := 1; i < len(all); i++ {
+			if all[i].CreatedAt.After(all[i-1].CreatedAt) {
+				t.Errorf("GetAll lists %d, created %v, after %d, created %v", all[i-1].ID, all[i-1].CreatedAt, all[i].ID, all[i].CreatedAt)
+			}
+		}
+	})
+
+	t.Run("stores empty descriptions as NULL", func(t *testing.T) {
+		b := newBackend(t)
+		todo := createTodo(t, b, ctx, "Book dentist appointment")
+		checkDescription(t, b, todo.ID, "")
+
+		todo.Description = "Ask about the crown"
+		if err := b.repo.Update(ctx, todo); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		checkDescription(t, b, todo.ID, "Ask about the crown")
+
+		todos, _, err := b.repo.List(ctx, models.TodoFilter{Query: "crown", Limit: 10})
+		if err != nil || len(todos) != 1 {
+			t.Errorf("List by description = %v, %v; want the todo", todos, err)
+		}
+
+		todo.Description = ""
+		if err := b.repo.Update(ctx, todo); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		checkDescription(t, b, todo.ID, "")
+
+		todos, _, err = b.repo.List(ctx, models.TodoFilter{Query: "dentist", Limit: 10})
+		if err != nil || len(todos) != 1 {
+			t.Errorf("List by title of a todo without description = %v, %v; want the todo", todos, err)
+		}
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		b := newBackend(t)
+
+		const writers = 8
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*3)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				todo := &models.Todo{Title: fmt.Sprintf("Concurrent todo %d", i)}
+				if err := b.repo.Create(ctx, todo); err != nil {
+					errs <- err
+					return
+				}
+				todo.Completed = true
+				if err := b.repo.Update(ctx, todo); err != nil {
+					errs <- err
+				}
+				if _, _, err := b.repo.List(ctx, models.TodoFilter{Limit: writers}); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent write: %v", err)
+		}
+
+		completed := true
+		todos, total, err := b.repo.List(ctx, models.TodoFilter{Completed: &completed, Limit: writers})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		seen := make(map[int64]bool)
+		for _, todo := range todos {
+			seen[todo.ID] = true
+		}
+		if total != writers || len(seen) != writers {
+			t.Errorf("List after concurrent writes: %d distinct of %d completed todos, want %d", len(seen), total, writers)
+		}
+	})
+
+	t.Run("updates and deletes todos", func(t *testing.T) {
+		b := newBackend(t)
+		todo := createTodo(t, b, ctx, "Write report")
+		stored, err := b.repo.GetByID(ctx, todo.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+
+		update := &models.Todo{ID: todo.ID, Title: "Write quarterly report", Completed: true}
+		if err := b.repo.Update(ctx, update); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if !update.CreatedAt.Equal(stored.CreatedAt) {
+			t.Errorf("Update changed created_at from %v to %v", stored.CreatedAt, update.CreatedAt)
+		}
+
+		got, err := b.repo.GetByID(ctx, todo.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Title != "Write quarterly report" || !got.Completed {
+			t.Errorf("GetByID after Update = %+v", got)
+		}
+
+		if err := b.repo.Delete(ctx, todo.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := b.repo.GetByID(ctx, todo.ID); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("GetByID after Delete: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("filters and pages listings", func(t *testing.T) {
+		b := newBackend(t)
+		for _, title := range []string{"Buy milk", "Buy BREAD", "Call 100% of clients", "Walk the dog"} {
+			createTodo(t, b, ctx, title)
+		}
+		done := createTodo(t, b, ctx, "Pay the bill")
+		done.Completed = true
+		if err := b.repo.Update(ctx, done); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		completed := true
+		tests := []struct {
+			name   string
+			filter models.TodoFilter
+			count  int
+			total  int64
+		}{
+			{"everything", models.TodoFilter{Limit: 10}, 5, 5},
+			{"completed", models.TodoFilter{Completed: &completed, Limit: 10}, 1, 1},
+			{"query ignoring case", models.TodoFilter{Query: "buy bread", Limit: 10}, 1, 1},
+			{"query with wildcards", models.TodoFilter{Query: "100%", Limit: 10}, 1, 1},
+			{"page", models.TodoFilter{Limit: 2, Offset: 4}, 1, 5},
+		}
+		for _, tt := range tests {
+			todos, total, err := b.repo.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List %s: %v", tt.name, err)
+			}
+			if len(todos) != tt.count || total != tt.total {
+				t.Errorf("List %s: %d todos of %d, want %d of %d", tt.name, len(todos), total, tt.count, tt.total)
+			}
+		}
+
+		todos, total, err := b.repo.List(ctx, models.TodoFilter{Query: "nothing matches", Limit: 10})
+		if err != nil || todos == nil || len(todos) != 0 || total != 0 {
+			t.Errorf("List without matches = %v, %d, %v; want an empty list", todos, total, err)
+		}
+	})
+
+	t.Run("loads todos by ids", func(t *testing.T) {
+		b := newBackend(t)
+		first := createTodo(t, b, ctx, "First todo")
+		second := createTodo(t, b, ctx, "Second todo")
+
+		todos, err := b.repo.GetByIDs(ctx, []int64{first.ID, second.ID, second.ID + 1000})
+		if err != nil {
+			t.Fatalf("GetByIDs: %v", err)
+		}
+		if len(todos) != 2 {
+			t.Errorf("GetByIDs returned %d todos, want 2", len(todos))
+		}
+
+		todos, err = b.repo.GetByIDs(ctx, nil)
+		if err != nil || todos == nil || len(todos) != 0 {
+			t.Errorf("GetByIDs(nil) = %v, %v; want an empty list", todos, err)
+		}
+	})
+
+	t.Run("limits users to their own and shared todos", func(t *testing.T) {
+		b := newBackend(t)
+		alice, bob := b.addUser(t), b.addUser(t)
+		asAlice := auth.NewContext(ctx, &auth.Principal{UserID: alice})
+		asBob := auth.NewContext(ctx, &auth.Principal{UserID: bob})
+
+		mine := createTodo(t, b, asAlice, "Alice's todo")
+		if mine.OwnerID == nil || *mine.OwnerID != alice {
+			t.Fatalf("owner %v, want %d", mine.OwnerID, alice)
+		}
+		shared := createTodo(t, b, asAlice, "Shared todo")
+		b.grant(t, shared.ID, bob)
+
+		if _, err := b.repo.GetByID(asBob, mine.ID); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("GetByID of another user's todo: %v, want ErrNotFound", err)
+		}
+		if err := b.repo.Delete(asBob, mine.ID); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("Delete of another user's todo: %v, want ErrNotFound", err)
+		}
+		if _, err := b.repo.GetByID(asBob, shared.ID); err != nil {
+			t.Errorf("GetByID of a shared todo: %v", err)
+		}
+
+		todos, total, err := b.repo.List(asBob, models.TodoFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if total != 1 || len(todos) != 1 || todos[0].ID != shared.ID {
+			t.Errorf("List for the grantee = %v of %d, want only the shared todo", todos, total)
+		}
+
+		all, err := b.repo.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if len(all) != 2 {
+			t.Errorf("GetAll for a caller without a user returned %d todos, want 2", len(all))
+		}
+	})
+
+	t.Run("counts todos created since a time", func(t *testing.T) {
+		b := newBackend(t)
+		alice, bob := b.addUser(t), b.addUser(t)
+		asAlice := auth.NewContext(ctx, &auth.Principal{UserID: alice})
+		createTodo(t, b, asAlice, "First todo")
+		createTodo(t, b, asAlice, "Second todo")
+
+		since := time.Now().Add(-time.Minute)
+		if count, err := b.repo.CountCreatedSince(ctx, alice, since); err != nil || count != 2 {
+			t.Errorf("CountCreatedSince = %d, %v; want 2", count, err)
+		}
+		if count, err := b.repo.CountCreatedSince(ctx, bob, since); err != nil || count != 0 {
+			t.Errorf("CountCreatedSince for another user = %d, %v; want 0", count, err)
+		}
+		if count, err := b.repo.CountCreatedSince(ctx, alice, time.Now().Add(time.Minute)); err != nil || count != 0 {
+			t.Errorf("CountCreatedSince in the future = %d, %v; want 0", count, err)
+		}
+	})
+}
+
+// checkDescription checks the description read back for a todo, and that
+// an empty one is stored as NULL.
+func checkDescription(t *testing.T, b todoBackend, id int64, want string) {
+	t.Helper()
+
+	got, err := b.repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Description != want {
+		t.Errorf("description %q, want %q", got.Description, want)
+	}
+	if b.nullDescription != nil && b.nullDescription(t, id) != (want == "") {
+		t.Errorf("description %q stored as NULL: %v", want, b.nullDescription(t, id))
+	}
+}
+
+// waitNextSecond waits until the clock has passed the second of t, as
+// SQLite stores timestamps to the second.
+func waitNextSecond(t time.Time) {
+	time.Sleep(time.Until(t.Truncate(time.Second).Add(time.Second)))
+}
+
+func createTodo(t *testing.T, b todoBackend, ctx context.Context, title string) *models.Todo {
+	t.Helper()
+
+	todo := &models.Todo{Title: title}
+	if err := b.repo.Create(ctx, todo); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return todo
+}
+
+// migratedDB opens and migrates the database cfg names, closing it when
+// the test ends.
+func migratedDB(t *testing.T, cfg *config.DatabaseConfig) *database.DB {
+	t.Helper()
+
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Migrate(cfg.MigrationsDir); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return db
+}
+
+// sqlBackend migrates the database cfg names and returns its repository.
+func sqlBackend(t *testing.T, cfg *config.DatabaseConfig) todoBackend {
+	t.Helper()
+
+	db := migratedDB(t, cfg)
+	users := 0
+	return todoBackend{
+		repo: repositories.NewTodoRepository(db),
+		addUser: func(t *testing.T) int64 {
+			t.Helper()
+
+			users++
+			var id int64
+			err := db.QueryRow(db.Rebind(`INSERT INTO users (email, password_hash) VALUES (?, ?) RETURNING id`),
+				fmt.Sprintf("user%d@example.com", users), "not a hash").Scan(&id)
+			if err != nil {
+				t.Fatalf("insert user: %v", err)
+			}
+			return id
+		},
+		grant: func(t *testing.T, todoID, userID int64) {
+			t.Helper()
+
+			if _, err := db.Exec(db.Rebind(`INSERT INTO todo_grants (todo_id, user_id, role) VALUES (?, ?, 'viewer')`), todoID, userID); err != nil {
+				t.Fatalf("insert grant: %v", err)
+			}
+		},
+		nullDescription: func(t *testing.T, id int64) bool {
+			t.Helper()
+
+			var null bool
+			if err := db.QueryRow(db.Rebind(`SELECT description IS NULL FROM todos WHERE id = ?`), id).Scan(&null); err != nil {
+				t.Fatalf("query description: %v", err)
+			}
+			return null
+		},
+	}
+}
+
+// memoryGrants is the part of a GrantRepository the in-memory todo
+// repository reads.
+type memoryGrants struct {
+	repositories.GrantRepository
+	roles map[[2]int64]string
+}
+
+func (g *memoryGrants) GetRole(todoID, userID int64) (string, error) {
+	role, ok := g.roles[[2]int64{todoID, userID}]
+	if !ok {
+		return "", fmt.Errorf("grant of todo %d to user %d %w", todoID, userID, repositories.ErrNotFound)
+	}
+	return role, nil
+}
+
+// postgresSchema creates a schema of its own for the test in the database
+// at dsn, dropped when the test ends, and returns a URL working in it.
+func postgresSchema(t *testing.T, dsn string) string {
+	t.Helper()
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "todo_test_" + hex.EncodeToString(suffix)
+
+	admin, err := database.NewConnection(&config.DatabaseConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse %s: %v", postgresURLEnv, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "todo-api/internal/repositories"
+
+// querySpan traces one SQL statement and logs it at debug level when it
+// ends.
+type querySpan struct {
+	trace.Span
+	ctx   context.Context
+	name  string
+	start time.Time
+	count int
+	err   error
+}
+
+// startQuery starts a span for a statement on table, named after what the
+// statement does, such as "todos.list.count". The statement text is
+// recorded with its placeholders, never with argument values.
+func startQuery(ctx context.Context, table, name, operation, statement string) (context.Context, *querySpan) {
+	return startSystemQuery(ctx, semconv.DBSystemNameSQLite, table, name, operation, statement)
+}
+
+// startPostgresQuery is startQuery for statements run on PostgreSQL.
+func startPostgresQuery(ctx context.Context, table, name, operation, statement string) (context.Context, *querySpan) {
+	return startSystemQuery(ctx, semconv.DBSystemNamePostgreSQL, table, name, operation, statement)
+}
+
+func startSystemQuery(ctx context.Context, system attribute.KeyValue, table, name, operation, statement string) (context.Context, *querySpan) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			system,
+			semconv.DBCollectionName(table),
+			semconv.DBOperationName(operation),
+			semconv.DBQuerySummary(name),
+			semconv.DBQueryText(statement),
+		),
+	)
+	return ctx, &querySpan{Span: span, ctx: ctx, name: name, start: time.Now()}
+}
+
+// rows records how many rows the statement returned or changed.
+func (s *querySpan) rows(n int) {
+	s.count = n
+	s.SetAttributes(semconv.DBResponseReturnedRows(n))
+}
+
+// fail marks the span failed and returns err.
+func (s *querySpan) fail(err error) error {
+	s.err = err
+	s.RecordError(err)
+	s.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+func (s *querySpan) End(options ...trace.SpanEndOption) {
+	attrs := []slog.Attr{
+		slog.String("statement", s.name),
+		slog.Int("rows", s.count),
+		slog.Duration("duration", time.Since(s.start)),
+	}
+	if s.err != nil {
+		attrs = append(attrs, slog.String("error", s.err.Error()))
+	}
+	slog.LogAttrs(s.ctx, slog.LevelDebug, "query", attrs...)
+
+	s.Span.End(options...)
+}
@@ -0,0 +1,57 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"todo-api/internal/config"
+	"todo-api/internal/models"
+)
+
+func TestSQLiteQueriesAreTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "todos.db")
+	b := sqlBackend(t, &config.DatabaseConfig{
+		Path:          path,
+		DSN:           path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+		MigrationsDir: filepath.Join("..", "..", "migrations"),
+	})
+	todo := &models.Todo{Title: "Traced todo"}
+	if err := b.repo.Create(context.Background(), todo); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	ctx, parent := provider.Tracer("tracing_test").Start(context.Background(), "TodoService.GetByID")
+	if _, err := b.repo.GetByID(ctx, todo.ID); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	parent.End()
+
+	for _, span := range recorder.Ended() {
+		if span.Name() != "todos.get_by_id" {
+			continue
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("query span has parent %s, want the caller's span", span.Parent().SpanID())
+		}
+
+		attributes := map[string]any{}
+		for _, attr := range span.Attributes() {
+			attributes[string(attr.Key)] = attr.Value.AsInterface()
+		}
+		if attributes["db.system.name"] != "sqlite" || attributes["db.response.returned_rows"] != int64(1) {
+			t.Errorf("query span attributes %v, want sqlite returning 1 row", attributes)
+		}
+		return
+	}
+	t.Fatal("no todos.get_by_id span was recorded")
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/models"
+)
+
+type UserRepository interface {
+	GetByID(id int64) (*models.User, error)
+	GetByEmail(email string) (*models.User, string, error)
+	Create(user *models.User, passwordHash string) error
+	GetByIdentity(issuer, subject string) (*models.User, error)
+	CreateWithIdentity(user *models.User, issuer, subject string) error
+	LinkIdentity(userID int64, issuer, subject string) error
+}
+
+type userRepository struct {
+	db *database.DB
+}
+
+func NewUserRepository(db *database.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) GetByID(id int64) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(r.db.Rebind(`SELECT id, email, created_at FROM users WHERE id = ?`), id).Scan(
+		&user.ID,
+		&user.Email,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user with id %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query user by id: %w", err)
+	}
+
+	return &user, nil
+}
+
+// GetByEmail returns the user registered with email, ignoring case, and the
+// hash of their password.
+func (r *userRepository) GetByEmail(email string) (*models.User, string, error) {
+	var user models.User
+	var passwordHash string
+	err := r.db.QueryRow(r.db.Rebind(`SELECT id, email, created_at, password_hash FROM users WHERE email = ?`), email).Scan(
+		&user.ID,
+		&user.Email,
+		&user.CreatedAt,
+		&passwordHash,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("user %w", ErrNotFound)
+		}
+		return nil, "", fmt.Errorf("failed to query user by email: %w", err)
+	}
+
+	return &user, passwordHash, nil
+}
+
+func (r *userRepository) Create(user *models.User, passwordHash string) error {
+	var id int64
+	err := r.db.QueryRow(r.db.Rebind(`INSERT INTO users (email, password_hash) VALUES (?, ?) RETURNING id`), user.Email, passwordHash).Scan(&id)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("user with email %s %w", user.Email, ErrConflict)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	user.ID = id
+	user.CreatedAt = time.Now()
+
+	return nil
+}
+
+// GetByIdentity returns the user linked to the subject of an external
+// identity provider.
+func (r *userRepository) GetByIdentity(issuer, subject string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(r.db.Rebind(`
+		SELECT u.id, u.email, u.created_at
+		FROM user_identities i JOIN users u ON u.id = i.user_id
+		WHERE i.issuer = ? AND i.subject = ?
+	`), issuer, subject).Scan(
+		&user.ID,
+		&user.Email,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user with identity %s %w", subject, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query user by identity: %w", err)
+	}
+
+	return &user, nil
+}
+
+// CreateWithIdentity creates a user linked to an external identity. The
+// user has no password and can only sign in through the identity provider.
+func (r *userRepository) CreateWithIdentity(user *models.User, issuer, subject string) error {
+	return r.db.WithTx(func(tx *sql.Tx) error {
+		var id int64
+		err := tx.QueryRow(r.db.Rebind(`INSERT INTO users (email, password_hash) VALUES (?, '') RETURNING id`), user.Email).Scan(&id)
+		if err != nil {
+			if database.IsUniqueViolation(err) {
+				return fmt.Errorf("user with email %s %w", user.Email, ErrConflict)
+			}
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		if err := r.linkIdentity(tx, id, issuer, subject); err != nil {
+			return err
+		}
+
+		user.ID = id
+		user.CreatedAt = time.Now()
+		return nil
+	})
+}
+
+func (r *userRepository) LinkIdentity(userID int64, issuer, subject string) error {
+	return r.linkIdentity(r.db, userID, issuer, subject)
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *userRepository) linkIdentity(db execer, userID int64, issuer, subject string) error {
+	_, err := db.Exec(r.db.Rebind(`INSERT INTO user_identities (issuer, subject, user_id) VALUES (?, ?, ?)`), issuer, subject, userID)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("identity %s %w", subject, ErrConflict)
+		}
+		if database.IsForeignKeyViolation(err) {
+			return fmt.Errorf("user with id %d %w", userID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+)
+
+type WebhookRepository interface {
+	GetAll() ([]models.WebhookSubscription, error)
+	GetByID(id int64) (*models.WebhookSubscription, error)
+	Create(subscription *models.WebhookSubscription) error
+	Update(subscription *models.WebhookSubscription) error
+	Delete(id int64) error
+
+	Enqueue(tx *sql.Tx, event events.Event) error
+	DueDeliveries(limit int) ([]DueDelivery, error)
+	MarkDelivered(id int64, statusCode int) error
+	MarkFailed(id int64, statusCode int, lastError string, retryIn time.Duration, dead bool) error
+	GetDeliveries(subscriptionID int64, status string, limit, offset int) ([]models.WebhookDelivery, int64, error)
+	Replay(subscriptionID, deliveryID int64) (int64, error)
+}
+
+// DueDelivery is a pending delivery together with the target of its subscription.
+type DueDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+type webhookRepository struct {
+	db *database.DB
+}
+
+func NewWebhookRepository(db *database.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookColumns = `id, url, event_types, secret, active, created_at, updated_at`
+
+func scanWebhook(row rowScanner) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	var eventTypes string
+
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&eventTypes,
+		&subscription.Secret,
+		&subscription.Active,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.EventTypes = strings.Split(eventTypes, ",")
+	return &subscription, nil
+}
+
+func (r *webhookRepository) GetAll() ([]models.WebhookSubscription, error) {
+	rows, err := r.db.Query(`SELECT ` + webhookColumns + ` FROM webhook_subscriptions ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) GetByID(id int64) (*models.WebhookSubscription, error) {
+	row := r.db.QueryRow(r.db.Rebind(`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id = ?`), id)
+
+	subscription, err := scanWebhook(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook with id %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to query webhook by id: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (r *webhookRepository) Create(subscription *models.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (url, event_types, secret, active)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(r.db.Rebind(query),
+		subscription.URL,
+		strings.Join(subscription.EventTypes, ","),
+		subscription.Secret,
+		subscription.Active,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	subscription.ID = id
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (r *webhookRepository) Update(subscription *models.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = ?, event_types = ?, secret = ?, active = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.Exec(r.db.Rebind(query),
+		subscription.URL,
+		strings.Join(subscription.EventTypes, ","),
+		subscription.Secret,
+		subscription.Active,
+		subscription.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook with id %d %w", subscription.ID, ErrNotFound)
+	}
+
+	subscription.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (r *webhookRepository) Delete(id int64) error {
+	result, err := r.db.Exec(r.db.Rebind(`DELETE FROM webhook_subscriptions WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook with id %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// Enqueue writes one pending delivery per active subscription to the event
+// type. It is meant to run as a TxHook of the todo repository.
+func (r *webhookRepository) Enqueue(tx *sql.Tx, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, ?, ?, ?
+		FROM webhook_subscriptions
+		WHERE active AND (',' || event_types || ',') LIKE ('%,' || ? || ',%')
+	`
+
+	if _, err := tx.Exec(r.db.Rebind(query), event.ID, string(event.Type), string(payload), string(event.Type)); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row rowScanner, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload string
+	var statusCode sql.NullInt64
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+
+	dest := []interface{}{
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&statusCode,
+		&lastError,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = json.RawMessage(payload)
+	delivery.LastStatusCode = int(statusCode.Int64)
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return &delivery, nil
+}
+
+// DueDeliveries returns pending deliveries of active subscriptions whose
+// next attempt is due, oldest first.
+func (r *webhookRepository) DueDeliveries(limit int) ([]DueDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = 'pending' AND s.active AND d.next_attempt_at <= CURRENT_TIMESTAMP
+		ORDER BY d.next_attempt_at ASC, d.id ASC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(r.db.Rebind(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var due []DueDelivery
+	for rows.Next() {
+		var item DueDelivery
+		delivery, err := scanDelivery(rows, &item.URL, &item.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		item.WebhookDelivery = *delivery
+		due = append(due, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return due, nil
+}
+
+func (r *webhookRepository) MarkDelivered(id int64, statusCode int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_status_code = ?,
+			last_error = NULL, delivered_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(r.db.Rebind(query), statusCode, id); err != nil {
+		return fmt.Errorf("failed to mark delivery %d delivered: %w", id, err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt and either schedules the next one
+// after retryIn or moves the delivery to the dead-letter list.
+func (r *webhookRepository) MarkFailed(id int64, statusCode int, lastError string, retryIn time.Duration, dead bool) error {
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+
+	var code interface{}
+	if statusCode > 0 {
+		code = statusCode
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = ?,
+			next_attempt_at = ` + secondsFromNow(r.db) + `
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(r.db.Rebind(query), status, code, lastError, retryIn.Seconds(), id); err != nil {
+		return fmt.Errorf("failed to mark delivery %d failed: %w", id, err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) GetDeliveries(subscriptionID int64, status string, limit, offset int) ([]models.WebhookDelivery, int64, error) {
+	filter := `d.subscription_id = ?`
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		filter += ` AND d.status = ?`
+		args = append(args, status)
+	}
+
+	var total int64
+	if err := r.db.QueryRow(r.db.Rebind(`SELECT COUNT(*) FROM webhook_deliveries d WHERE `+filter), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count deliveries: %w", err)
+	}
+
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		WHERE ` + filter + `
+		ORDER BY d.id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(r.db.Rebind(query), append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// Replay moves dead deliveries of a subscription back to pending with a
+// fresh attempt budget. A deliveryID of 0 replays every dead delivery.
+func (r *webhookRepository) Replay(subscriptionID, deliveryID int64) (int64, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, last_error = NULL, next_attempt_at = CURRENT_TIMESTAMP
+		WHERE subscription_id = ? AND status = 'dead'
+	`
+	args := []interface{}{subscriptionID}
+	if deliveryID > 0 {
+		query += ` AND id = ?`
+		args = append(args, deliveryID)
+	}
+
+	result, err := r.db.Exec(r.db.Rebind(query), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay deliveries: %w", err)
+	}
+
+	replayed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if deliveryID > 0 && replayed == 0 {
+		return 0, fmt.Errorf("dead delivery with id %d %w", deliveryID, ErrNotFound)
+	}
+
+	return replayed, nil
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/database"
+	"todo-api/internal/health"
+	"todo-api/internal/storage"
+)
+
+// readinessChecks are the dependencies a server needs to take traffic.
+func (s *Server) readinessChecks(db *database.DB, blobs *storage.BlobStore) []health.Check {
+	return []health.Check{
+		{Name: "database", Run: func(ctx context.Context) (string, error) {
+			return "", db.Check(ctx)
+		}},
+		{Name: "storage", Run: func(context.Context) (string, error) {
+			return "", blobs.CheckWritable()
+		}},
+		{Name: "migrations", Run: func(context.Context) (string, error) {
+			return migrationVersion(db, s.config.Database.MigrationsDir)
+		}},
+		{Name: "shutdown", Run: func(context.Context) (string, error) {
+			if s.draining.Load() {
+				return "", errors.New("server is shutting down")
+			}
+			return "", nil
+		}},
+	}
+}
+
+// migrationVersion reports the newest applied migration, failing when a
+// migration in the directory has not been applied.
+func migrationVersion(db *database.DB, dir string) (string, error) {
+	migrations, err := db.MigrationStatus(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var version string
+	var pending []string
+	for _, migration := range migrations {
+		if migration.Applied {
+			version = migration.Version
+		} else {
+			pending = append(pending, migration.Version)
+		}
+	}
+
+	detail := "version " + version
+	if len(pending) > 0 {
+		return detail, fmt.Errorf("%d pending migrations: %s", len(pending), strings.Join(pending, ", "))
+	}
+	return detail, nil
+}
+
+// livez reports that the process is up and serving requests. It runs no
+// checks, so a struggling dependency does not get the server restarted.
+func livez(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// readyz runs the readiness checks, answering 503 while any fails so load
+// balancers stop sending traffic. The verbose query parameter adds each
+// check's detail and error for operators.
+func (s *Server) readyz(c *gin.Context) {
+	report := s.health.Run(c.Request.Context())
+
+	if _, verbose := c.GetQuery("verbose"); !verbose {
+		for i := range report.Checks {
+			report.Checks[i].Detail = ""
+			report.Checks[i].Error = ""
+		}
+	}
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
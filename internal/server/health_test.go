@@ -0,0 +1,150 @@
+package server_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"todo-api/internal/health"
+	"todo-api/internal/server"
+
+	_ "modernc.org/sqlite"
+)
+
+// getReadiness fetches /readyz?verbose and returns its status code and
+// checks by name.
+func getReadiness(t *testing.T, url string) (int, map[string]health.Result) {
+	t.Helper()
+
+	resp, err := http.Get(url + "/readyz?verbose")
+	if err != nil {
+		t.Fatalf("GET /readyz: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report health.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decode /readyz: %v", err)
+	}
+
+	checks := make(map[string]health.Result)
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+	if (report.Status == health.StatusOK) != (resp.StatusCode == http.StatusOK) {
+		t.Fatalf("/readyz status %q with code %d", report.Status, resp.StatusCode)
+	}
+	return resp.StatusCode, checks
+}
+
+func TestReadinessChecksDependencies(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "todos.db")
+	blobDir := filepath.Join(dir, "blobs")
+	migrationsDir := filepath.Join(dir, "migrations")
+	if err := os.CopyFS(migrationsDir, os.DirFS(filepath.Join("..", "..", "migrations"))); err != nil {
+		t.Fatalf("CopyFS: %v", err)
+	}
+	t.Setenv("TODO_DB_PATH", dbPath)
+	t.Setenv("TODO_BLOB_DIR", blobDir)
+	t.Setenv("TODO_MIGRATIONS_DIR", migrationsDir)
+	t.Setenv("TODO_AUTH_ENABLED", "false")
+	t.Setenv("TODO_HEALTH_TIMEOUT", "300ms")
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	code, checks := getReadiness(t, ts.URL)
+	if code != http.StatusOK || len(checks) != 4 {
+		t.Fatalf("/readyz = %d %v, want 200 with four checks", code, checks)
+	}
+	for _, name := range []string{"database", "storage", "migrations", "shutdown"} {
+		if check := checks[name]; check.Status != health.StatusOK || check.LatencyMs < 0 {
+			t.Errorf("%s check = %+v", name, check)
+		}
+	}
+	if detail := checks["migrations"].Detail; !strings.HasPrefix(detail, "version 0") {
+		t.Errorf("migrations detail = %q, want the applied version", detail)
+	}
+
+	// Details and errors are only shown to operators asking for them.
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz: %v", err)
+	}
+	var brief health.Report
+	json.NewDecoder(resp.Body).Decode(&brief)
+	resp.Body.Close()
+	if brief.Status != health.StatusOK || len(brief.Checks) != 4 || brief.Checks[2].Detail != "" {
+		t.Fatalf("brief /readyz = %+v", brief)
+	}
+
+	// Another process holding the database locked times the check out.
+	lock, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer lock.Close()
+	conn, err := lock.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), `BEGIN EXCLUSIVE`); err != nil {
+		t.Fatalf("BEGIN EXCLUSIVE: %v", err)
+	}
+	if code, checks := getReadiness(t, ts.URL); code != http.StatusServiceUnavailable || checks["database"].Error == "" {
+		t.Fatalf("/readyz with a locked database = %d %+v", code, checks["database"])
+	}
+	conn.ExecContext(context.Background(), `ROLLBACK`)
+	conn.Close()
+	lock.Close()
+
+	// Storage that cannot be written to fails readiness.
+	if err := os.RemoveAll(filepath.Join(blobDir, "tmp")); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if code, checks := getReadiness(t, ts.URL); code != http.StatusServiceUnavailable || checks["storage"].Status != health.StatusUnavailable || checks["database"].Status != health.StatusOK {
+		t.Fatalf("/readyz with unwritable storage = %d %v", code, checks)
+	}
+	if err := os.Mkdir(filepath.Join(blobDir, "tmp"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	// A deployed migration that has not been applied yet.
+	if err := os.WriteFile(filepath.Join(migrationsDir, "999_pending.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if code, checks := getReadiness(t, ts.URL); code != http.StatusServiceUnavailable || !strings.Contains(checks["migrations"].Error, "999_pending") {
+		t.Fatalf("/readyz with a pending migration = %d %+v", code, checks["migrations"])
+	}
+	if err := os.Remove(filepath.Join(migrationsDir, "999_pending.sql")); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	// Once shutdown begins, the server is alive but no longer ready.
+	srv.Shutdown()
+	if code, checks := getReadiness(t, ts.URL); code != http.StatusServiceUnavailable || checks["shutdown"].Status != health.StatusUnavailable {
+		t.Fatalf("/readyz while shutting down = %d %v", code, checks)
+	}
+	resp, err = http.Get(ts.URL + "/livez")
+	if err != nil {
+		t.Fatalf("GET /livez: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("/livez while shutting down = %d, want 200", resp.StatusCode)
+	}
+}
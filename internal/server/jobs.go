@@ -0,0 +1,66 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+)
+
+// runJob runs fn in the background; Close waits for it to return after
+// closing s.stop.
+func (s *Server) runJob(fn func()) {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		fn()
+	}()
+}
+
+// collectBlobs periodically removes attachment blobs that are no longer
+// referenced, until the server is closed.
+func (s *Server) collectBlobs(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			removed, err := s.attachments.CollectGarbage(interval)
+			if err != nil {
+				slog.Error("Blob garbage collection failed", "error", err)
+				continue
+			}
+			if removed > 0 {
+				slog.Info("Blob garbage collection removed blobs", "removed", removed)
+			}
+		}
+	}
+}
+
+// expireIdempotencyKeys periodically deletes stored responses whose TTL has
+// passed, until the server is closed.
+func (s *Server) expireIdempotencyKeys(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			deleted, err := s.idempotency.DeleteExpired()
+			if err != nil {
+				slog.Error("Failed to delete expired idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Expired idempotency keys deleted", "deleted", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"todo-api/internal/metrics"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// countTodos counts the todos of every owner by state. The context carries
+// no principal, so the repository does not scope the counts.
+func countTodos(repo repositories.TodoRepository) metrics.TodoCounter {
+	return func(ctx context.Context) (open, completed int64, err error) {
+		count := func(done bool) (int64, error) {
+			_, total, err := repo.List(ctx, models.TodoFilter{Completed: &done})
+			return total, err
+		}
+
+		if open, err = count(false); err != nil {
+			return 0, 0, err
+		}
+		if completed, err = count(true); err != nil {
+			return 0, 0, err
+		}
+		return open, completed, nil
+	}
+}
+
+// traced reports whether a request gets a span; scrapes and health checks
+// would only add noise.
+func traced(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/metrics", "/health", "/livez", "/readyz":
+		return false
+	}
+	return true
+}
@@ -1,96 +1,572 @@
 package server
 
 import (
-	"io/ioutil"
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"todo-api/internal/auth"
+	"todo-api/internal/certs"
+	"todo-api/internal/collab"
 	"todo-api/internal/config"
 	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/graphqlapi"
+	"todo-api/internal/grpcapi"
+	"todo-api/internal/health"
+	"todo-api/internal/handlers/account"
+	"todo-api/internal/handlers/apikey"
+	"todo-api/internal/handlers/attachment"
+	collabHandler "todo-api/internal/handlers/collab"
+	"todo-api/internal/handlers/comment"
+	"todo-api/internal/handlers/grant"
+	"todo-api/internal/handlers/sharelink"
+	graphqlHandler "todo-api/internal/handlers/graphql"
 	"todo-api/internal/handlers/todo"
+	"todo-api/internal/handlers/webhook"
+	"todo-api/internal/metrics"
+	"todo-api/internal/middleware"
+	"todo-api/internal/models"
+	"todo-api/internal/ratelimit"
 	"todo-api/internal/repositories"
 	"todo-api/internal/services"
+	"todo-api/internal/storage"
+	"todo-api/internal/tracing"
+	"todo-api/internal/webhooks"
 )
 
 
 type Server struct {
-	config  *config.Config
-	db      *database.DB
-	service services.TodoService
-	router  *gin.Engine
+	config      *config.Config
+	db          *database.DB
+	service     services.TodoService
+	comments    services.CommentService
+	attachments services.AttachmentService
+	grants      services.GrantService
+	shareLinks  services.ShareLinkService
+	webhooks    services.WebhookService
+	apiKeys     services.APIKeyService
+	users       services.UserService
+	idempotency services.IdempotencyService
+	auth        services.Authenticator
+	// tokens verifies JWTs; nil when OpenID Connect is off.
+	tokens      *auth.TokenVerifier
+	metrics     *metrics.Metrics
+	http        *http.Server
+	admin       *http.Server
+	// tls serves the current certificate; nil when TLS is off.
+	tls         *certs.Reloader
+	tracing     func(context.Context) error
+	broker      *events.Broker
+	hub         *collab.Hub
+	router      *gin.Engine
+	grpc        *grpcapi.Server
+	graphql     *graphqlapi.Server
+	health      *health.Checker
+	reads       *ratelimit.Limiter
+	writes      *ratelimit.Limiter
+	// draining is set once shutdown begins, failing readiness.
+	draining    atomic.Bool
+	stop        chan struct{}
+	closeOnce   sync.Once
+	closeErr    error
+	jobs        sync.WaitGroup
 }
 
 func NewServer() (*Server, error) {
 	cfg := config.NewConfig()
 
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	
 	db, err := database.NewConnection(&cfg.Database)
 	if err != nil {
 		return nil, err
 	}
 	
-	if err := runMigrations(db); err != nil {
+	if err := runMigrations(db, cfg.Database.MigrationsDir); err != nil {
+		return nil, err
+	}
+	
+	blobs, err := storage.NewBlobStore(cfg.Storage.BlobDir, cfg.Storage.MaxAttachmentSize)
+	if err != nil {
 		return nil, err
 	}
 	
-	repo := repositories.NewTodoRepository(db)
-	service := services.NewTodoService(repo)
+	m := metrics.New()
+	m.CollectDBStats(db.DB, "todos")
+	
+	webhookRepo := repositories.InstrumentWebhookRepository(repositories.NewWebhookRepository(db), m.ObserveQuery)
+	baseRepo := repositories.NewTodoRepository(db, webhookRepo.Enqueue)
+	m.CollectTodos(countTodos(baseRepo))
+	repo := repositories.InstrumentTodoRepository(baseRepo, m.ObserveQuery)
+	broker := events.NewBroker(cfg.Stream.LogSize, cfg.Stream.BufferSize)
+	grantRepo := repositories.InstrumentGrantRepository(repositories.NewGrantRepository(db), m.ObserveQuery)
+	userRepo := repositories.InstrumentUserRepository(repositories.NewUserRepository(db), m.ObserveQuery)
+	service := services.TraceTodoService(services.NewTodoService(repo, grantRepo, broker, cfg.Limits.DailyTodos))
+	commentRepo := repositories.InstrumentCommentRepository(repositories.NewCommentRepository(db), m.ObserveQuery)
+	commentService := services.NewCommentService(commentRepo, repo, userRepo, grantRepo)
+	attachmentService := services.NewAttachmentService(
+		repositories.InstrumentAttachmentRepository(repositories.NewAttachmentRepository(db), m.ObserveQuery),
+		repo,
+		grantRepo,
+		blobs,
+		cfg.Storage.AllowedAttachmentTypes,
+	)
+	
+	graphqlServer, err := graphqlapi.NewServer(service, commentService, attachmentService, broker, graphqlapi.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	})
+	if err != nil {
+		return nil, err
+	}
 	
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
-	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
-	r.SetTrustedProxies([]string{"127.0.0.1", "::1"})
+	r.Use(middleware.RequestID())
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithGinFilter(traced)))
+	r.Use(middleware.Metrics(m))
+	r.Use(middleware.Logger())
+	r.Use(middleware.Recovery())
+	r.Use(middleware.SecurityHeaders(cfg.HTTP.HSTSMaxAge, cfg.HTTP.TrustedProxies))
+	r.Use(middleware.CORS(cfg.HTTP.CORS))
+	// Uploads are streamed into the blob store, which enforces the
+	// attachment size; the extra megabyte leaves room for multipart headers.
+	r.Use(middleware.BodyLimit(cfg.HTTP.MaxBodySize, map[string]int64{
+		"/api/v1/todos/:id/attachments": cfg.Storage.MaxAttachmentSize + 1<<20,
+	}))
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		return nil, err
+	}
+	if err := checkCORS(cfg.HTTP.CORS); err != nil {
+		return nil, err
+	}
+	if cfg.Share.RateLimit <= 0 {
+		return nil, errors.New("TODO_SHARE_RATE_LIMIT must be at least 1, since share links are opened without an account")
+	}
+	
+	apiKeyService := services.NewAPIKeyService(repositories.InstrumentAPIKeyRepository(repositories.NewAPIKeyRepository(db), m.ObserveQuery))
+	userService := services.NewUserService(userRepo, apiKeyService, cfg.Auth.SessionTTL)
+	tokens, err := newTokenVerifier(cfg.Auth.OIDC)
+	if err != nil {
+		return nil, err
+	}
+	authenticator := services.NewAuthenticator(apiKeyService, userService, tokens, services.TokenPolicy{
+		DefaultScopes: cfg.Auth.OIDC.DefaultScopes,
+		AdminRole:     cfg.Auth.OIDC.AdminRole,
+	})
+	var grpcAuth services.Authenticator
+	if cfg.Auth.Enabled {
+		grpcAuth = authenticator
+	}
+	
+	var reloader *certs.Reloader
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		if reloader, err = certs.NewReloader(cfg.TLS); err != nil {
+			return nil, err
+		}
+		tlsConfig = reloader.Config()
+	}
 	
-	setupRoutes(r, service)
+	grantService := services.NewGrantService(grantRepo, repo, userRepo)
+	reads, writes := perMinute(cfg.Limits.ReadsPerMinute), perMinute(cfg.Limits.WritesPerMinute)
 	
-	return &Server{
-		config:  cfg,
-		db:      db,
-		service: service,
-		router:  r,
-	}, nil
+	s := &Server{
+		config:      cfg,
+		db:          db,
+		service:     service,
+		comments:    commentService,
+		attachments: attachmentService,
+		grants:      grantService,
+		shareLinks:  services.NewShareLinkService(repositories.InstrumentShareLinkRepository(repositories.NewShareLinkRepository(db), m.ObserveQuery), repo, commentRepo, grantRepo),
+		webhooks:    services.NewWebhookService(webhookRepo),
+		apiKeys:     apiKeyService,
+		users:       userService,
+		idempotency: services.NewIdempotencyService(repositories.InstrumentIdempotencyRepository(repositories.NewIdempotencyRepository(db), m.ObserveQuery), cfg.HTTP.IdempotencyTTL),
+		auth:        authenticator,
+		tokens:      tokens,
+		metrics:     m,
+		http:        &http.Server{Handler: r, TLSConfig: tlsConfig, ReadHeaderTimeout: 10 * time.Second},
+		tls:         reloader,
+		tracing:     shutdownTracing,
+		broker:      broker,
+		hub:         collab.NewHub(broker, cfg.Collab.LockTTL, grantService.Viewers),
+		router:      r,
+		grpc:        grpcapi.NewServer(service, broker, grpcAuth, reads, writes, tlsConfig),
+		graphql:     graphqlServer,
+		reads:       reads,
+		writes:      writes,
+		stop:        make(chan struct{}),
+	}
+	
+	s.health = health.NewChecker(cfg.Health.Timeout, s.readinessChecks(db, blobs)...)
+	
+	s.setupRoutes()
+	s.runJob(func() { s.collectBlobs(cfg.Storage.GCInterval) })
+	s.runJob(func() { s.expireIdempotencyKeys(time.Hour) })
+	s.runJob(func() { webhooks.NewDispatcher(webhookRepo, cfg.Webhooks).Run(s.stop) })
+	s.runJob(func() { s.hub.Run(s.stop) })
+	if reloader != nil {
+		s.runJob(func() { reloader.Watch(s.stop, cfg.TLS.ReloadInterval) })
+	}
+	
+	return s, nil
 }
 
 func (s *Server) Start(port string) error {
-	log.Printf("TODO API listening on %s", port)
-	return s.router.Run(port)
+	if addr := s.config.GRPC.Addr; addr != "off" {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		
+		slog.Info("TODO gRPC API listening", "addr", addr)
+		go func() {
+			if err := s.grpc.Serve(lis); err != nil {
+				slog.Error("gRPC server stopped", "error", err)
+			}
+		}()
+	}
+	
+	if addr := s.config.Metrics.Addr; addr != "" {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.metrics.Handler())
+		s.admin = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		
+		slog.Info("TODO metrics listening", "addr", addr)
+		go func() {
+			if err := s.admin.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Metrics server stopped", "error", err)
+			}
+		}()
+	}
+	
+	lis, err := net.Listen("tcp", port)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
 }
 
+// Serve serves the REST API on lis, over TLS when it is configured, until
+// Close. HTTP/2 is negotiated with TLS clients that support it.
+func (s *Server) Serve(lis net.Listener) error {
+	var err error
+	if s.tls != nil {
+		slog.Info("TODO API listening", "addr", lis.Addr().String(), "tls", true)
+		err = s.http.ServeTLS(lis, "", "")
+	} else {
+		slog.Info("TODO API listening", "addr", lis.Addr().String(), "tls", false)
+		err = s.http.Serve(lis)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// ReloadTLS reads the certificate files again, as the server does on its
+// own when they change. It does nothing when TLS is off.
+func (s *Server) ReloadTLS() error {
+	if s.tls == nil {
+		return nil
+	}
+	return s.tls.Reload()
+}
+
+// Handler returns the HTTP router, for serving it with a custom listener
+// such as httptest.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Shutdown starts failing readiness and keeps serving for the configured
+// delay, so load balancers stop routing to the server before Close.
+func (s *Server) Shutdown() {
+	s.draining.Store(true)
+	time.Sleep(s.config.Health.ShutdownDelay)
+}
+
+// Close stops the background jobs and listeners and closes the database.
+// Requests in flight get the shutdown timeout to finish before their
+// connections are closed. Calling it again returns the first call's result.
 func (s *Server) Close() error {
-	return s.db.Close()
+	s.closeOnce.Do(func() {
+		s.draining.Store(true)
+		close(s.stop)
+		s.broker.Close()
+	
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.Health.ShutdownTimeout)
+		defer cancel()
+		stopGRPC(ctx, s.grpc)
+		shutdownHTTP(ctx, s.http)
+		if s.admin != nil {
+			shutdownHTTP(ctx, s.admin)
+		}
+		s.jobs.Wait()
+	
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFlush()
+		if err := s.tracing(flushCtx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	
+		s.closeErr = s.db.Close()
+	})
+	return s.closeErr
 }
 
-func runMigrations(db *database.DB) error {
-	migrationSQL, err := ioutil.ReadFile("migrations/001_create_todos_table.sql")
-	if err != nil {
-		return err
+// shutdownHTTP stops srv gracefully, closing the connections still active
+// when ctx expires.
+func shutdownHTTP(ctx context.Context, srv *http.Server) {
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("Closing connections still active after the shutdown timeout", "error", err)
+		srv.Close()
 	}
+}
+
+// stopGRPC stops srv gracefully, cancelling the calls still running when
+// ctx expires.
+func stopGRPC(ctx context.Context, srv *grpcapi.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
 	
-	return db.RunMigration(string(migrationSQL))
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("Cancelling gRPC calls still running after the shutdown timeout")
+		srv.Stop()
+		<-stopped
+	}
+}
+
+// authenticate returns the middleware resolving the caller's API key or
+// JWT, or none when authentication is disabled.
+func (s *Server) authenticate() []gin.HandlerFunc {
+	if !s.config.Auth.Enabled {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.Authenticate(s.auth)}
+}
+
+// rateLimit returns the middleware limiting each client's reads and
+// writes. It follows authenticate, which identifies the client.
+func (s *Server) rateLimit() gin.HandlerFunc {
+	return middleware.RateLimit(s.reads, s.writes)
+}
+
+// perMinute returns a limiter allowing limit requests a minute, or nil when
+// limit is zero.
+func perMinute(limit int) *ratelimit.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return ratelimit.New(limit, time.Minute)
+}
+
+func (s *Server) requireScope(scope string) []gin.HandlerFunc {
+	if !s.config.Auth.Enabled {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.RequireScope(scope)}
+}
+
+func (s *Server) requireReadWrite(read, write string) []gin.HandlerFunc {
+	if !s.config.Auth.Enabled {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.RequireReadWrite(read, write)}
 }
 
-func setupRoutes(r *gin.Engine, service services.TodoService) {
+// newTokenVerifier returns the verifier of JWTs from the OpenID Connect
+// provider, or nil when none is configured.
+func newTokenVerifier(cfg config.OIDCConfig) (*auth.TokenVerifier, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("TODO_OIDC_ISSUER and TODO_OIDC_AUDIENCE are required with a JWKS")
+	}
+
+	keys := auth.NewKeySet(cfg.JWKS, cfg.JWKSRefresh)
+	return auth.NewTokenVerifier(keys, auth.TokenOptions{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		RolesClaim: cfg.RolesClaim,
+		Leeway:     cfg.Leeway,
+	}), nil
+}
+
+// checkCORS rejects a configuration browsers would refuse anyway.
+func checkCORS(cfg config.CORSConfig) error {
+	if !cfg.AllowCredentials {
+		return nil
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			return errors.New("TODO_CORS_ALLOW_CREDENTIALS cannot be combined with the * origin")
+		}
+	}
+	return nil
+}
+
+func runMigrations(db *database.DB, dir string) error {
+	return db.Migrate(dir)
+}
+
+func (s *Server) setupRoutes() {
+	r := s.router
+	service := s.service
+	commentService := s.comments
+	attachmentService := s.attachments
+	grantService := s.grants
+	shareLinkService := s.shareLinks
+	webhookService := s.webhooks
+	apiKeyService := s.apiKeys
+	userService := s.users
+	
 	// Swagger documentation
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	
-	api := r.Group("/api/v1")
+	// Registration and login are how callers get a token, so they are open.
+	accounts := r.Group("/api/v1/auth", s.rateLimit())
+	{
+		if s.config.Auth.Registration {
+			accounts.POST("/register", account.Register(userService))
+		}
+		accounts.POST("/login", account.Login(userService))
+	}
+	
+	// Share links are opened without an account, so they are rate limited
+	// per client instead.
+	shared := r.Group("/api/v1/shared", middleware.RateLimitByIP(ratelimit.New(s.config.Share.RateLimit, time.Minute)))
+	{
+		shared.GET("/:token", sharelink.GetSharedTodo(shareLinkService))
+	}
+	
+	// Creating requests may carry an idempotency key, checked after the
+	// scopes so a refused request is not stored.
+	idempotent := middleware.Idempotency(s.idempotency)
+	api := r.Group("/api/v1", append(s.authenticate(), s.rateLimit())...)
 	{
-		todos := api.Group("/todos")
+		api.GET("/auth/me", account.GetMe(userService))
+		if s.tokens != nil {
+			api.POST("/auth/identities", account.LinkIdentity(s.tokens, userService))
+		}
+		
+		todos := api.Group("/todos", s.requireReadWrite(models.ScopeTodosRead, models.ScopeTodosWrite)...)
 		{
 			todos.GET("", todo.GetTodos(service))
+			todos.GET("/stream", todo.StreamTodos(s.broker, s.config.Stream.HeartbeatInterval))
 			todos.GET("/:id", todo.GetTodo(service))
-			todos.POST("", todo.CreateTodo(service))
+			todos.POST("", idempotent, todo.CreateTodo(service))
 			todos.PUT("/:id", todo.UpdateTodo(service))
 			todos.DELETE("/:id", todo.DeleteTodo(service))
+			
+			comments := todos.Group("/:id/comments")
+			{
+				comments.GET("", comment.GetComments(commentService))
+				comments.GET("/:comment_id", comment.GetComment(commentService))
+				comments.POST("", idempotent, comment.CreateComment(commentService))
+				comments.PUT("/:comment_id", comment.UpdateComment(commentService))
+				comments.DELETE("/:comment_id", comment.DeleteComment(commentService))
+			}
+			
+			attachments := todos.Group("/:id/attachments")
+			{
+				attachments.GET("", attachment.GetAttachments(attachmentService))
+				attachments.GET("/:attachment_id", attachment.GetAttachment(attachmentService))
+				attachments.GET("/:attachment_id/content", attachment.DownloadAttachment(attachmentService))
+				attachments.POST("", attachment.UploadAttachment(attachmentService))
+				attachments.DELETE("/:attachment_id", attachment.DeleteAttachment(attachmentService))
+			}
+			
+			grants := todos.Group("/:id/grants")
+			{
+				grants.GET("", grant.GetGrants(grantService))
+				grants.POST("", idempotent, grant.CreateGrant(grantService))
+				grants.DELETE("/:user_id", grant.DeleteGrant(grantService))
+			}
+			
+			shareLinks := todos.Group("/:id/share-links")
+			{
+				shareLinks.GET("", sharelink.GetShareLinks(shareLinkService))
+				shareLinks.POST("", idempotent, sharelink.CreateShareLink(shareLinkService))
+				shareLinks.DELETE("/:link_id", sharelink.DeleteShareLink(shareLinkService))
+			}
+		}
+		
+		collab := api.Group("/collab", s.requireScope(models.ScopeTodosRead)...)
+		{
+			collab.GET("", collabHandler.Connect(s.hub, s.users, middleware.WebSocketOrigin(s.config.HTTP.CORS)))
+		}
+		
+		hooks := api.Group("/webhooks", s.requireScope(models.ScopeAdmin)...)
+		{
+			hooks.GET("", webhook.GetWebhooks(webhookService))
+			hooks.GET("/:id", webhook.GetWebhook(webhookService))
+			hooks.POST("", idempotent, webhook.CreateWebhook(webhookService))
+			hooks.PUT("/:id", webhook.UpdateWebhook(webhookService))
+			hooks.DELETE("/:id", webhook.DeleteWebhook(webhookService))
+			hooks.GET("/:id/deliveries", webhook.GetDeliveries(webhookService))
+			hooks.POST("/:id/deliveries/replay", webhook.ReplayDeliveries(webhookService))
+			hooks.POST("/:id/deliveries/:delivery_id/replay", webhook.ReplayDelivery(webhookService))
+		}
+		
+		keys := api.Group("/api-keys", s.requireScope(models.ScopeAdmin)...)
+		{
+			keys.GET("", apikey.GetAPIKeys(apiKeyService))
+			keys.GET("/:id", apikey.GetAPIKey(apiKeyService))
+			keys.POST("", idempotent, apikey.CreateAPIKey(apiKeyService))
+			keys.DELETE("/:id", apikey.DeleteAPIKey(apiKeyService))
 		}
 	}
 	
+	// Mutations additionally require todos:write, checked by the GraphQL server.
+	gql := r.Group("/graphql", append(append(s.authenticate(), s.rateLimit()), s.requireScope(models.ScopeTodosRead)...)...)
+	{
+		serveGraphQL := graphqlHandler.Serve(s.graphql, middleware.WebSocketOrigin(s.config.HTTP.CORS))
+		gql.GET("", serveGraphQL)
+		gql.POST("", serveGraphQL)
+	}
+	
+	// Without an admin address the metrics share the API port, so they are
+	// kept to admin keys.
+	if s.config.Metrics.Addr == "" {
+		r.GET("/metrics", append(append(s.authenticate(), s.requireScope(models.ScopeAdmin)...), gin.WrapH(s.metrics.Handler()))...)
+	}
+	
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "ok",
 			"service": "todo-api",
 		})
 	})
+	
+	// Probes for orchestrators: /livez only says the process serves
+	// requests, /readyz checks its dependencies.
+	r.GET("/livez", livez)
+	r.GET("/readyz", s.readyz)
 }
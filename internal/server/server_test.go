@@ -0,0 +1,480 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/middleware"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/server"
+	"todo-api/internal/services"
+)
+
+// setupEnv points the server at a fresh database and blob directory with
+// authentication disabled.
+func setupEnv(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("TODO_DB_PATH", filepath.Join(dir, "todos.db"))
+	t.Setenv("TODO_BLOB_DIR", filepath.Join(dir, "blobs"))
+	t.Setenv("TODO_MIGRATIONS_DIR", filepath.Join("..", "..", "migrations"))
+	t.Setenv("TODO_AUTH_ENABLED", "false")
+}
+
+func TestCORSRejectsCredentialsForAnyOrigin(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("TODO_CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("TODO_CORS_ALLOW_CREDENTIALS", "true")
+
+	if _, err := server.NewServer(); err == nil {
+		t.Fatal("NewServer accepted credentials with the * origin")
+	}
+}
+
+func TestShareRateLimitMustBePositive(t *testing.T) {
+	for _, limit := range []string{"0", "-5"} {
+		setupEnv(t)
+		t.Setenv("TODO_SHARE_RATE_LIMIT", limit)
+
+		if _, err := server.NewServer(); err == nil {
+			t.Errorf("NewServer accepted a share rate limit of %s", limit)
+		}
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	setupEnv(t)
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+// slowCreate is a request creating a todo whose body is only half sent.
+type slowCreate struct {
+	conn net.Conn
+	rest string
+}
+
+// startSlowCreate serves srv and starts creating a todo on it, sending the
+// headers and half the body, which the server then waits for.
+func startSlowCreate(t *testing.T, srv *server.Server) *slowCreate {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go srv.Serve(lis)
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	body := `{"title": "Finish the report"}`
+	fmt.Fprintf(conn, "POST /api/v1/todos HTTP/1.1\r\nHost: todo\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body[:10])
+
+	// Requests whose headers have not been read when shutdown begins are
+	// dropped rather than served.
+	time.Sleep(100 * time.Millisecond)
+	return &slowCreate{conn: conn, rest: body[10:]}
+}
+
+// finish sends the rest of the body and returns the response status.
+func (r *slowCreate) finish() (int, error) {
+	if _, err := io.WriteString(r.conn, r.rest); err != nil {
+		return 0, err
+	}
+	r.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(r.conn), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestCloseLetsRequestsInFlightFinish(t *testing.T) {
+	setupEnv(t)
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	request := startSlowCreate(t, srv)
+
+	closed := make(chan error, 1)
+	go func() { closed <- srv.Close() }()
+
+	time.Sleep(200 * time.Millisecond)
+	if status, err := request.finish(); status != http.StatusCreated {
+		t.Errorf("request in flight at Close = %d (%v), want 201", status, err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestCloseCutsOffRequestsAfterTheShutdownTimeout(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("TODO_SHUTDOWN_TIMEOUT", "100ms")
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	request := startSlowCreate(t, srv)
+
+	closed := make(chan error, 1)
+	go func() { closed <- srv.Close() }()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited past the shutdown timeout for a stalled request")
+	}
+
+	if status, err := request.finish(); err == nil {
+		t.Errorf("a stalled request was answered %d after Close", status)
+	}
+}
+
+func TestAttachmentsHaveTheirOwnBodyLimit(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("TODO_MAX_BODY_SIZE", "1024")
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	big := `{"title": "too big", "description": "` + strings.Repeat("x", 2048) + `"}`
+	resp, err := http.Post(ts.URL+"/api/v1/todos", "application/json", strings.NewReader(big))
+	if err != nil {
+		t.Fatalf("POST /todos: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("POST /todos of 2 KiB = %d, want 413", resp.StatusCode)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "notes.txt")
+	part.Write(bytes.Repeat([]byte("a"), 4096))
+	form.Close()
+
+	// The todo does not exist, but the upload gets past the body limit.
+	resp, err = http.Post(ts.URL+"/api/v1/todos/999/attachments", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST /attachments: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("upload of 4 KiB = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestCommentAuthorIsNotChosenByTheCaller(t *testing.T) {
+	setupEnv(t)
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	resp, err := http.Post(ts.URL+"/api/v1/todos", "application/json", strings.NewReader(`{"title": "Discuss"}`))
+	if err != nil {
+		t.Fatalf("POST /todos: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Post(ts.URL+"/api/v1/todos/1/comments", "application/json", strings.NewReader(`{"author": "mallory", "body": "Approved"}`))
+	if err != nil {
+		t.Fatalf("POST /comments: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var comment struct {
+		Author string `json:"author"`
+	}
+	json.NewDecoder(resp.Body).Decode(&comment)
+	if resp.StatusCode != http.StatusCreated || comment.Author != "anonymous" {
+		t.Errorf("POST /comments = %d with author %q, want 201 with anonymous", resp.StatusCode, comment.Author)
+	}
+}
+
+func TestGroupGrantsAreRefused(t *testing.T) {
+	setupEnv(t)
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	resp, err := http.Post(ts.URL+"/api/v1/todos", "application/json", strings.NewReader(`{"title": "Plan offsite"}`))
+	if err != nil {
+		t.Fatalf("POST /todos: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, body := range []string{
+		`{"email": "grace@example.com", "group": "engineering", "role": "editor"}`,
+		`{"email": "grace@example.com", "group_id": 7, "role": "viewer"}`,
+	} {
+		resp, err := http.Post(ts.URL+"/api/v1/todos/1/grants", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /grants: %v", err)
+		}
+
+		var failure struct {
+			Details string `json:"details"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(failure.Details, "group grants are not supported") {
+			t.Errorf("POST /grants %s = %d (%q), want 400 refusing the group", body, resp.StatusCode, failure.Details)
+		}
+	}
+}
+
+func TestIdempotencyKeysApplyToAuthorizedCreates(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("TODO_AUTH_ENABLED", "true")
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	db, err := database.NewConnection(&config.NewConfig().Database)
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	defer db.Close()
+	keys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db))
+	reader := models.APIKey{Name: "reader", Scopes: []string{models.ScopeTodosRead}}
+	writer := models.APIKey{Name: "writer", Scopes: []string{models.ScopeTodosRead, models.ScopeTodosWrite}}
+	for _, key := range []*models.APIKey{&reader, &writer} {
+		if err := keys.Create(key); err != nil {
+			t.Fatalf("Create %s key: %v", key.Name, err)
+		}
+	}
+
+	post := func(secret, key, path, contentType string, body []byte) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	replayed := func(resp *http.Response) bool {
+		return resp.Header.Get(middleware.IdempotentReplayedHeader) != ""
+	}
+
+	// A request refused by the scope checks never gets to its key.
+	if resp := post(reader.Secret, strings.Repeat("k", services.MaxIdempotencyKeyLength+1), "/api/v1/todos", "application/json", []byte(`{}`)); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST /todos with a read-only key and an invalid idempotency key = %d, want 403", resp.StatusCode)
+	}
+	for range 2 {
+		if resp := post(reader.Secret, "retry-1", "/api/v1/todos", "application/json", []byte(`{"title": "Read only"}`)); resp.StatusCode != http.StatusForbidden || replayed(resp) {
+			t.Errorf("POST /todos with a read-only key = %d %v, want 403 not replayed", resp.StatusCode, resp.Header)
+		}
+	}
+
+	created := post(writer.Secret, "retry-1", "/api/v1/todos", "application/json", []byte(`{"title": "Write"}`))
+	retried := post(writer.Secret, "retry-1", "/api/v1/todos", "application/json", []byte(`{"title": "Write"}`))
+	if created.StatusCode != http.StatusCreated || replayed(created) || !replayed(retried) {
+		t.Errorf("POST /todos = %d, then replayed %v, want 201 then a replay", created.StatusCode, replayed(retried))
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "notes.txt")
+	part.Write([]byte("milk, eggs"))
+	form.Close()
+
+	// Uploads are streamed to the handler and not replayed.
+	for range 2 {
+		if resp := post(writer.Secret, "retry-2", "/api/v1/todos/1/attachments", form.FormDataContentType(), body.Bytes()); resp.StatusCode != http.StatusCreated || replayed(resp) {
+			t.Errorf("upload = %d %v, want 201 not replayed", resp.StatusCode, resp.Header)
+		}
+	}
+}
+
+func TestServesTLSOverHTTP2(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("TODO_TLS_SELF_SIGNED", "true")
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true},
+		Timeout:   5 * time.Second,
+	}
+	defer httpClient.CloseIdleConnections()
+
+	resp, err := httpClient.Get("https://" + lis.Addr().String() + "/livez")
+	if err != nil {
+		t.Fatalf("GET /livez: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Errorf("status %d over %s, want 200 over HTTP/2", resp.StatusCode, resp.Proto)
+	}
+	if err := srv.ReloadTLS(); err != nil {
+		t.Errorf("ReloadTLS of a self-signed certificate: %v", err)
+	}
+}
+
+func TestServesFromPostgres(t *testing.T) {
+	dsn := os.Getenv("TODO_TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("set TODO_TEST_POSTGRES_URL to a PostgreSQL URL to run")
+	}
+
+	setupEnv(t)
+	t.Setenv("TODO_DATABASE_URL", postgresSchema(t, dsn))
+	t.Setenv("TODO_MIGRATIONS_DIR", filepath.Join("..", "..", "migrations", "postgres"))
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	post := func(path, body string) map[string]any {
+		t.Helper()
+
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		var created map[string]any
+		json.NewDecoder(resp.Body).Decode(&created)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("POST %s = %d %v", path, resp.StatusCode, created)
+		}
+		return created
+	}
+
+	todo := post("/api/v1/todos", `{"title": "Runs on PostgreSQL"}`)
+	id := strconv.FormatFloat(todo["id"].(float64), 'f', 0, 64)
+	post("/api/v1/todos/"+id+"/comments", `{"body": "Confirmed"}`)
+	post("/api/v1/webhooks", `{"url": "https://hooks.example.com", "event_types": ["todo.created"]}`)
+
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/readyz = %d, want 200", resp.StatusCode)
+	}
+}
+
+// postgresSchema creates a schema of its own for the test in the database
+// at dsn, dropped when the test ends, and returns a URL working in it.
+func postgresSchema(t *testing.T, dsn string) string {
+	t.Helper()
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "todo_test_" + hex.EncodeToString(suffix)
+
+	admin, err := database.NewConnection(&config.DatabaseConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse TODO_TEST_POSTGRES_URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/storage"
+)
+
+var (
+	// ErrAttachmentTooLarge is returned when an upload exceeds the configured size limit.
+	ErrAttachmentTooLarge = errors.New("attachment exceeds the maximum size")
+	// ErrUnsupportedAttachmentType is returned when the sniffed content type is not allowed.
+	ErrUnsupportedAttachmentType = errors.New("attachment type is not allowed")
+	// ErrAttachmentContentMissing is returned when an attachment's blob is
+	// gone from the store, which means the stored data is inconsistent.
+	ErrAttachmentContentMissing = errors.New("attachment content is missing")
+)
+
+// AttachmentService manages the files attached to todos. Methods taking a
+// context only reach attachments of todos the caller of ctx may see.
+type AttachmentService interface {
+	List(ctx context.Context, todoID int64) ([]models.Attachment, error)
+	ListByTodoIDs(todoIDs []int64) (map[int64][]models.Attachment, error)
+	GetByID(ctx context.Context, todoID, id int64) (*models.Attachment, error)
+	Upload(ctx context.Context, todoID int64, filename string, content io.Reader) (*models.Attachment, error)
+	Open(ctx context.Context, todoID, id int64) (*models.Attachment, *os.File, error)
+	Delete(ctx context.Context, todoID, id int64) error
+	CollectGarbage(gracePeriod time.Duration) (int, error)
+}
+
+type attachmentService struct {
+	repo         repositories.AttachmentRepository
+	todoRepo     repositories.TodoRepository
+	policy       accessPolicy
+	blobs        *storage.BlobStore
+	allowedTypes map[string]bool
+}
+
+func NewAttachmentService(repo repositories.AttachmentRepository, todoRepo repositories.TodoRepository, grants repositories.GrantRepository, blobs *storage.BlobStore, allowedTypes []string) AttachmentService {
+	allowed := make(map[string]bool, len(allowedTypes))
+	for _, contentType := range allowedTypes {
+		allowed[strings.ToLower(contentType)] = true
+	}
+
+	return &attachmentService{
+		repo:         repo,
+		todoRepo:     todoRepo,
+		policy:       newAccessPolicy(grants),
+		blobs:        blobs,
+		allowedTypes: allowed,
+	}
+}
+
+func (s *attachmentService) List(ctx context.Context, todoID int64) ([]models.Attachment, error) {
+	if err := s.ensureTodo(ctx, todoID, ActionView); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByTodoID(todoID)
+}
+
+// ListByTodoIDs returns the attachments of several todos keyed by todo id.
+// The ids must be of todos the caller has already loaded.
+func (s *attachmentService) ListByTodoIDs(todoIDs []int64) (map[int64][]models.Attachment, error) {
+	attachments, err := s.repo.GetByTodoIDs(todoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byTodo := make(map[int64][]models.Attachment)
+	for _, attachment := range attachments {
+		byTodo[attachment.TodoID] = append(byTodo[attachment.TodoID], attachment)
+	}
+
+	return byTodo, nil
+}
+
+func (s *attachmentService) GetByID(ctx context.Context, todoID, id int64) (*models.Attachment, error) {
+	return s.get(ctx, todoID, id, ActionView)
+}
+
+// get loads an attachment and checks that it belongs to the given todo, on
+// which the caller may perform action.
+func (s *attachmentService) get(ctx context.Context, todoID, id int64, action Action) (*models.Attachment, error) {
+	if id <= 0 {
+		return nil, invalidf("invalid attachment id: %d", id)
+	}
+
+	if err := s.ensureTodo(ctx, todoID, action); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment.TodoID != todoID {
+		return nil, fmt.Errorf("attachment with id %d %w", id, repositories.ErrNotFound)
+	}
+
+	return attachment, nil
+}
+
+// Upload sniffs the content type from the first bytes of content, stores the
+// content in the blob store and records its metadata.
+func (s *attachmentService) Upload(ctx context.Context, todoID int64, filename string, content io.Reader) (*models.Attachment, error) {
+	filename = strings.TrimSpace(filepath.Base(filename))
+	if filename == "" || filename == "." || filename == string(filepath.Separator) {
+		return nil, invalidf("filename is required")
+	}
+
+	if len(filename) > 255 {
+		return nil, invalidf("filename must be less than 255 characters")
+	}
+
+	if err := s.ensureTodo(ctx, todoID, ActionEdit); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReaderSize(content, 512)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	contentType := http.DetectContentType(head)
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !s.allowedTypes[mediaType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAttachmentType, contentType)
+	}
+
+	sum, size, err := s.blobs.Put(reader)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobTooLarge) {
+			return nil, ErrAttachmentTooLarge
+		}
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		TodoID:      todoID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      sum,
+	}
+
+	if err := s.repo.Create(attachment); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *attachmentService) Open(ctx context.Context, todoID, id int64) (*models.Attachment, *os.File, error) {
+	attachment, err := s.GetByID(ctx, todoID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.blobs.Open(attachment.SHA256)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, nil, fmt.Errorf("attachment %d: %w", id, ErrAttachmentContentMissing)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("content of attachment %d is unavailable: %w", id, err)
+	}
+
+	return attachment, file, nil
+}
+
+// Delete removes the attachment metadata. The blob is left for
+// CollectGarbage, since other attachments may share the same content.
+func (s *attachmentService) Delete(ctx context.Context, todoID, id int64) error {
+	_, err := s.get(ctx, todoID, id, ActionEdit)
+	if errors.Is(err, ErrForbidden) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("attachment not found for delete: %w", err)
+	}
+
+	return s.repo.Delete(id)
+}
+
+// CollectGarbage removes blobs that no attachment references any more and
+// that were last written more than gracePeriod ago, which protects uploads
+// whose metadata has not been recorded yet. It returns the number of removed
+// blobs.
+func (s *attachmentService) CollectGarbage(gracePeriod time.Duration) (int, error) {
+	referenced, err := s.repo.ReferencedSHA256()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-gracePeriod)
+	removed := 0
+	err = s.blobs.Walk(func(sum string, modTime time.Time) error {
+		if referenced[sum] || modTime.After(cutoff) {
+			return nil
+		}
+
+		if err := s.blobs.Delete(sum); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to collect blobs: %w", err)
+	}
+
+	return removed, nil
+}
+
+// ensureTodo checks that the todo exists, the caller of ctx may see it and
+// may perform action on it.
+func (s *attachmentService) ensureTodo(ctx context.Context, todoID int64, action Action) error {
+	if todoID <= 0 {
+		return invalidf("invalid todo id: %d", todoID)
+	}
+
+	todo, err := s.todoRepo.GetByID(ctx, todoID)
+	if err != nil {
+		return err
+	}
+
+	return s.policy.authorize(ctx, todo, action)
+}
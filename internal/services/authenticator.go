@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// identityCacheTTL is how long the user linked to a token subject is
+// remembered, sparing a query on every request.
+const identityCacheTTL = 5 * time.Minute
+
+// Authenticator resolves the bearer token of a request, an API key or a JWT
+// from the OpenID Connect provider, to the calling principal.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*auth.Principal, error)
+}
+
+// TokenPolicy maps the claims of a verified JWT to scopes.
+type TokenPolicy struct {
+	// DefaultScopes are granted to every valid token, in addition to the
+	// todo scopes listed in its scope claim.
+	DefaultScopes []string
+	// AdminRole is the role granting the admin scope.
+	AdminRole string
+}
+
+type authenticator struct {
+	keys   APIKeyService
+	users  UserService
+	tokens *auth.TokenVerifier
+	policy TokenPolicy
+	now    func() time.Time
+
+	mu         sync.Mutex
+	identities map[string]cachedIdentity
+}
+
+type cachedIdentity struct {
+	user    *models.User
+	expires time.Time
+}
+
+// NewAuthenticator builds the authenticator. JWTs are only accepted when
+// tokens is not nil.
+func NewAuthenticator(keys APIKeyService, users UserService, tokens *auth.TokenVerifier, policy TokenPolicy) Authenticator {
+	return &authenticator{
+		keys:       keys,
+		users:      users,
+		tokens:     tokens,
+		policy:     policy,
+		now:        time.Now,
+		identities: make(map[string]cachedIdentity),
+	}
+}
+
+func (a *authenticator) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	if a.tokens == nil || !auth.IsJWT(token) {
+		key, err := a.keys.Authenticate(token)
+		if err != nil {
+			return nil, err
+		}
+		return auth.FromAPIKey(key), nil
+	}
+
+	identity, err := a.tokens.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrUnauthorized)
+	}
+
+	user, err := a.user(identity)
+	if err != nil {
+		// Tokens without a usable email, or with the email of an account
+		// they are not linked to, cannot be mapped to a user.
+		if errors.Is(err, ErrInvalidInput) || errors.Is(err, repositories.ErrConflict) {
+			return nil, fmt.Errorf("%v: %w", err, ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	return &auth.Principal{
+		UserID:  user.ID,
+		Subject: identity.Subject,
+		Name:    user.Email,
+		Roles:   identity.Roles,
+		Scopes:  a.scopes(identity),
+	}, nil
+}
+
+func (a *authenticator) user(identity *auth.Identity) (*models.User, error) {
+	cacheKey := identity.Issuer + "\x00" + identity.Subject
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.identities[cacheKey]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.user, nil
+	}
+
+	user, err := a.users.ResolveIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	for key, entry := range a.identities {
+		if !now.Before(entry.expires) {
+			delete(a.identities, key)
+		}
+	}
+	a.identities[cacheKey] = cachedIdentity{user: user, expires: now.Add(identityCacheTTL)}
+	a.mu.Unlock()
+
+	return user, nil
+}
+
+// scopes grants the default scopes, the todo scopes of the scope claim and,
+// for the admin role only, the admin scope. A scope claim alone never
+// grants admin.
+func (a *authenticator) scopes(identity *auth.Identity) []string {
+	scopes := append([]string(nil), a.policy.DefaultScopes...)
+	for _, scope := range identity.Scopes {
+		if scope == models.ScopeTodosRead || scope == models.ScopeTodosWrite {
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, role := range identity.Roles {
+		if a.policy.AdminRole != "" && role == a.policy.AdminRole {
+			scopes = append(scopes, models.ScopeAdmin)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// anonymousAuthor is the author of comments written while authentication is
+// disabled.
+const anonymousAuthor = "anonymous"
+
+// CommentService manages the comments of todos. Methods taking a context
+// only reach comments of todos the caller of ctx may see, and changing
+// comments requires the editor role. Comments are written under the name of
+// their caller, never a name the caller chose.
+type CommentService interface {
+	List(ctx context.Context, todoID int64, limit, offset int) ([]models.Comment, int64, error)
+	ListByTodoIDs(todoIDs []int64, limit int) (map[int64][]models.Comment, error)
+	GetByID(ctx context.Context, todoID, id int64) (*models.Comment, error)
+	Create(ctx context.Context, comment *models.Comment) error
+	Update(ctx context.Context, comment *models.Comment) error
+	Delete(ctx context.Context, todoID, id int64) error
+}
+
+type commentService struct {
+	repo     repositories.CommentRepository
+	todoRepo repositories.TodoRepository
+	users    repositories.UserRepository
+	policy   accessPolicy
+}
+
+func NewCommentService(repo repositories.CommentRepository, todoRepo repositories.TodoRepository, users repositories.UserRepository, grants repositories.GrantRepository) CommentService {
+	return &commentService{repo: repo, todoRepo: todoRepo, users: users, policy: newAccessPolicy(grants)}
+}
+
+func (s *commentService) List(ctx context.Context, todoID int64, limit, offset int) ([]models.Comment, int64, error) {
+	if err := s.ensureTodo(ctx, todoID, ActionView); err != nil {
+		return nil, 0, err
+	}
+
+	comments, err := s.repo.GetByTodoID(todoID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.repo.CountByTodoID(todoID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range comments {
+		redactDeleted(&comments[i])
+	}
+
+	return comments, total, nil
+}
+
+// ListByTodoIDs returns up to limit comments for each of several todos,
+// keyed by todo id. Todos without comments are absent from the map. The
+// ids must be of todos the caller has already loaded.
+func (s *commentService) ListByTodoIDs(todoIDs []int64, limit int) (map[int64][]models.Comment, error) {
+	comments, err := s.repo.GetByTodoIDs(todoIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	byTodo := make(map[int64][]models.Comment)
+	for i := range comments {
+		redactDeleted(&comments[i])
+		byTodo[comments[i].TodoID] = append(byTodo[comments[i].TodoID], comments[i])
+	}
+
+	return byTodo, nil
+}
+
+func (s *commentService) GetByID(ctx context.Context, todoID, id int64) (*models.Comment, error) {
+	comment, err := s.get(ctx, todoID, id, ActionView)
+	if err != nil {
+		return nil, err
+	}
+
+	redactDeleted(comment)
+	return comment, nil
+}
+
+func (s *commentService) Create(ctx context.Context, comment *models.Comment) error {
+	if err := s.validateComment(comment); err != nil {
+		return err
+	}
+
+	if err := s.ensureTodo(ctx, comment.TodoID, ActionEdit); err != nil {
+		return err
+	}
+
+	if comment.ParentCommentID != nil {
+		parent, err := s.get(ctx, comment.TodoID, *comment.ParentCommentID, ActionEdit)
+		if err != nil {
+			return fmt.Errorf("parent comment not found: %w", err)
+		}
+
+		if parent.DeletedAt != nil {
+			return invalidf("cannot reply to a deleted comment")
+		}
+	}
+
+	author, ok, err := CallerName(ctx, s.users)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		author = anonymousAuthor
+	}
+
+	comment.Author = author
+	comment.Body = strings.TrimSpace(comment.Body)
+
+	return s.repo.Create(comment)
+}
+
+func (s *commentService) Update(ctx context.Context, comment *models.Comment) error {
+	if err := s.validateComment(comment); err != nil {
+		return err
+	}
+
+	existing, err := s.get(ctx, comment.TodoID, comment.ID, ActionEdit)
+	if errors.Is(err, ErrForbidden) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("comment not found for update: %w", err)
+	}
+
+	if existing.DeletedAt != nil {
+		return fmt.Errorf("comment with id %d %w", comment.ID, repositories.ErrNotFound)
+	}
+
+	existing.Body = strings.TrimSpace(comment.Body)
+	if err := s.repo.Update(existing); err != nil {
+		return err
+	}
+
+	*comment = *existing
+	return nil
+}
+
+func (s *commentService) Delete(ctx context.Context, todoID, id int64) error {
+	_, err := s.get(ctx, todoID, id, ActionEdit)
+	if errors.Is(err, ErrForbidden) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("comment not found for delete: %w", err)
+	}
+
+	return s.repo.SoftDelete(id)
+}
+
+// get loads a comment and checks that it belongs to the given todo, on
+// which the caller may perform action.
+func (s *commentService) get(ctx context.Context, todoID, id int64, action Action) (*models.Comment, error) {
+	if id <= 0 {
+		return nil, invalidf("invalid comment id: %d", id)
+	}
+
+	if err := s.ensureTodo(ctx, todoID, action); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.TodoID != todoID {
+		return nil, fmt.Errorf("comment with id %d %w", id, repositories.ErrNotFound)
+	}
+
+	return comment, nil
+}
+
+// ensureTodo checks that the todo exists, the caller of ctx may see it and
+// may perform action on it.
+func (s *commentService) ensureTodo(ctx context.Context, todoID int64, action Action) error {
+	if todoID <= 0 {
+		return invalidf("invalid todo id: %d", todoID)
+	}
+
+	todo, err := s.todoRepo.GetByID(ctx, todoID)
+	if err != nil {
+		return err
+	}
+
+	return s.policy.authorize(ctx, todo, action)
+}
+
+func (s *commentService) validateComment(comment *models.Comment) error {
+	if comment == nil {
+		return invalidf("comment cannot be nil")
+	}
+
+	body := strings.TrimSpace(comment.Body)
+	if body == "" {
+		return invalidf("body is required")
+	}
+
+	if len(body) > 2000 {
+		return invalidf("body must be less than 2000 characters")
+	}
+
+	return nil
+}
+
+// redactDeleted hides the body of a soft-deleted comment while keeping it
+// in place as a parent for its replies.
+func redactDeleted(comment *models.Comment) {
+	if comment.DeletedAt != nil {
+		comment.Body = ""
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidInput is matched by errors.Is for errors caused by invalid
+// caller input, as opposed to storage failures.
+var ErrInvalidInput = errors.New("invalid input")
+
+type inputError struct {
+	msg string
+}
+
+func (e *inputError) Error() string { return e.msg }
+
+func (e *inputError) Is(target error) bool { return target == ErrInvalidInput }
+
+// invalidf formats a validation error. Its message is kept as is so
+// existing API responses do not change.
+func invalidf(format string, args ...any) error {
+	return &inputError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrUnauthorized is returned when a credential is missing, unknown or
+// expired.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrForbidden is matched by errors.Is for errors refusing an operation the
+// caller lacks the role for. The message says which role is needed.
+var ErrForbidden = errors.New("forbidden")
+
+type forbiddenError struct {
+	msg string
+}
+
+func (e *forbiddenError) Error() string { return e.msg }
+
+func (e *forbiddenError) Is(target error) bool { return target == ErrForbidden }
+
+func forbiddenf(format string, args ...any) error {
+	return &forbiddenError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrQuotaExceeded is matched by errors.Is for a *QuotaError.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaError refuses a write that would take the caller past a quota until
+// Reset.
+type QuotaError struct {
+	Limit int
+	Reset time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("daily quota of %d todos reached; it resets at %s", e.Limit, e.Reset.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+// ErrKeyReused is returned when an idempotency key is sent again with a
+// different request.
+var ErrKeyReused = errors.New("idempotency key was already used with a different request")
+
+// ErrRequestInFlight is returned when an idempotency key is sent again while
+// the first request with it is still running.
+var ErrRequestInFlight = errors.New("a request with this idempotency key is still in progress")
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// MaxIdempotencyKeyLength bounds the Idempotency-Key header.
+const MaxIdempotencyKeyLength = 255
+
+// idempotencyLease is how long a request holds its key while running. A
+// key whose request is not done by then, say because the server stopped
+// while running it, can be claimed again.
+const idempotencyLease = time.Minute
+
+// IdempotencyService remembers the responses to requests sent with an
+// idempotency key, so a retried request is answered with the original
+// response instead of running again. Keys belong to the caller of ctx.
+type IdempotencyService interface {
+	// Begin claims key for the request with fingerprint. It returns the
+	// stored response when the request already completed, ErrRequestInFlight
+	// while it is still running and ErrKeyReused when the key came with
+	// another request. With neither, the caller runs the request and then
+	// completes or releases the key.
+	Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	// Release forgets key, so the request can be retried with it.
+	Release(ctx context.Context, key string) error
+	DeleteExpired() (int64, error)
+}
+
+type idempotencyService struct {
+	repo repositories.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService builds the service. Responses are kept for ttl.
+func NewIdempotencyService(repo repositories.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return nil, invalidf("idempotency key must be between 1 and %d characters", MaxIdempotencyKeyLength)
+	}
+
+	existing, err := s.repo.Reserve(&models.IdempotencyRecord{
+		Scope:       idempotencyScope(ctx),
+		Key:         key,
+		Fingerprint: fingerprint,
+	}, idempotencyLease)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, repositories.ErrConflict) {
+		return nil, err
+	}
+
+	switch {
+	case existing.Fingerprint != fingerprint:
+		return nil, ErrKeyReused
+	case existing.StatusCode == 0:
+		return nil, ErrRequestInFlight
+	default:
+		return existing, nil
+	}
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	return s.repo.Complete(&models.IdempotencyRecord{
+		Scope:       idempotencyScope(ctx),
+		Key:         key,
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Body:        body,
+	}, s.ttl)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, key string) error {
+	return s.repo.Release(idempotencyScope(ctx), key)
+}
+
+func (s *idempotencyService) DeleteExpired() (int64, error) {
+	return s.repo.DeleteExpired()
+}
+
+// idempotencyScope names the caller of ctx: its user, or its API key when
+// not tied to one. Without authentication every caller shares one scope.
+func idempotencyScope(ctx context.Context) string {
+	p, ok := auth.FromContext(ctx)
+	switch {
+	case !ok:
+		return ""
+	case p.UserID != 0:
+		return "user:" + strconv.FormatInt(p.UserID, 10)
+	case p.APIKeyID != 0:
+		return "key:" + strconv.FormatInt(p.APIKeyID, 10)
+	default:
+		return "subject:" + p.Subject
+	}
+}
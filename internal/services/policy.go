@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+// Action is an operation on a todo, checked by the access policy.
+type Action string
+
+const (
+	// ActionView reads a todo, its comments, attachments and grants.
+	ActionView Action = "view"
+	// ActionEdit changes a todo, its comments and attachments.
+	ActionEdit Action = "edit"
+	// ActionDelete deletes a todo.
+	ActionDelete Action = "delete"
+	// ActionShare grants and revokes access to a todo.
+	ActionShare Action = "share"
+)
+
+// requiredRoles is the least role each action needs.
+var requiredRoles = map[Action]string{
+	ActionView:   models.RoleViewer,
+	ActionEdit:   models.RoleEditor,
+	ActionDelete: models.RoleAdmin,
+	ActionShare:  models.RoleAdmin,
+}
+
+var roleRanks = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleAdmin:  3,
+}
+
+// validRole reports whether role can be granted.
+func validRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// accessPolicy decides what the caller of a context may do with a todo.
+// Owners and callers not tied to a user may do everything; anyone else needs
+// a grant with a role of at least the one the action requires.
+type accessPolicy struct {
+	grants repositories.GrantRepository
+}
+
+func newAccessPolicy(grants repositories.GrantRepository) accessPolicy {
+	return accessPolicy{grants: grants}
+}
+
+// authorize returns an ErrForbidden error saying why the caller of ctx may
+// not perform action on todo.
+func (p accessPolicy) authorize(ctx context.Context, todo *models.Todo, action Action) error {
+	user := auth.Owner(ctx)
+	if user == 0 || (todo.OwnerID != nil && *todo.OwnerID == user) {
+		return nil
+	}
+
+	required := requiredRoles[action]
+	role, err := p.grants.GetRole(todo.ID, user)
+	if errors.Is(err, repositories.ErrNotFound) {
+		role, err = "", nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if roleRanks[role] < roleRanks[required] {
+		slog.InfoContext(ctx, "Access denied", "todo_id", todo.ID, "user_id", user, "action", action, "role", role)
+		if role == "" {
+			return forbiddenf("todo %d is not shared with you; %s requires the %s role", todo.ID, action, required)
+		}
+		return forbiddenf("you are a %s of todo %d; %s requires the %s role", role, todo.ID, action, required)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+const (
+	shareLinkPrefix = "shr_"
+	// sharedCommentLimit is how many comments the shared view of a todo
+	// includes.
+	sharedCommentLimit = 100
+)
+
+// SharedPath is where share links are opened; the token is appended.
+const SharedPath = "/api/v1/shared/"
+
+// ShareLinkService manages public read-only links to todos. Listing,
+// creating and revoking links requires the admin role on the todo.
+type ShareLinkService interface {
+	List(ctx context.Context, todoID int64) ([]models.ShareLink, error)
+	Create(ctx context.Context, link *models.ShareLink, password string) error
+	Revoke(ctx context.Context, todoID, id int64) error
+	Open(ctx context.Context, token, password string) (*models.SharedTodo, error)
+}
+
+type shareLinkService struct {
+	repo     repositories.ShareLinkRepository
+	todoRepo repositories.TodoRepository
+	comments repositories.CommentRepository
+	policy   accessPolicy
+	now      func() time.Time
+}
+
+func NewShareLinkService(repo repositories.ShareLinkRepository, todoRepo repositories.TodoRepository, comments repositories.CommentRepository, grants repositories.GrantRepository) ShareLinkService {
+	return &shareLinkService{
+		repo:     repo,
+		todoRepo: todoRepo,
+		comments: comments,
+		policy:   newAccessPolicy(grants),
+		now:      time.Now,
+	}
+}
+
+func (s *shareLinkService) List(ctx context.Context, todoID int64) ([]models.ShareLink, error) {
+	if err := s.ensureTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByTodoID(todoID)
+}
+
+// Create generates a token for link and stores its hash. The token is
+// returned on the link so it can be shown this one time. An empty password
+// leaves the link open to anyone holding the token.
+func (s *shareLinkService) Create(ctx context.Context, link *models.ShareLink, password string) error {
+	if link == nil {
+		return invalidf("share link cannot be nil")
+	}
+
+	if err := s.ensureTodo(ctx, link.TodoID); err != nil {
+		return err
+	}
+
+	if link.ExpiresAt != nil {
+		if !link.ExpiresAt.After(s.now()) {
+			return invalidf("expires_at must be in the future")
+		}
+		expiresAt := link.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
+
+	var passwordHash string
+	if password != "" {
+		if len(password) < minPasswordLength {
+			return invalidf("password must be at least %d characters long", minPasswordLength)
+		}
+		if len(password) > maxPasswordLength {
+			return invalidf("password must be at most %d bytes long", maxPasswordLength)
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		passwordHash = string(hash)
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate share link: %w", err)
+	}
+
+	link.Token = shareLinkPrefix + hex.EncodeToString(b)
+	link.Prefix = link.Token[:len(shareLinkPrefix)+8]
+	link.URL = SharedPath + link.Token
+
+	return s.repo.Create(link, hashAPIKey(link.Token), passwordHash)
+}
+
+func (s *shareLinkService) Revoke(ctx context.Context, todoID, id int64) error {
+	if id <= 0 {
+		return invalidf("invalid share link id: %d", id)
+	}
+
+	if err := s.ensureTodo(ctx, todoID); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(todoID, id)
+}
+
+// Open returns the read-only view behind a token and counts the access.
+// Unknown, revoked and expired links yield repositories.ErrNotFound; a
+// missing or wrong password yields ErrUnauthorized.
+func (s *shareLinkService) Open(ctx context.Context, token, password string) (*models.SharedTodo, error) {
+	if !strings.HasPrefix(token, shareLinkPrefix) {
+		return nil, fmt.Errorf("share link %w", repositories.ErrNotFound)
+	}
+
+	link, passwordHash, err := s.repo.GetByHash(hashAPIKey(token))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if link.ExpiresAt != nil && !now.Before(*link.ExpiresAt) {
+		return nil, fmt.Errorf("share link %s expired: %w", link.Prefix, repositories.ErrNotFound)
+	}
+
+	if passwordHash != "" {
+		if password == "" {
+			return nil, fmt.Errorf("share link %s requires a password: %w", link.Prefix, ErrUnauthorized)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+			return nil, fmt.Errorf("wrong password for share link %s: %w", link.Prefix, ErrUnauthorized)
+		}
+	}
+
+	// The link stands in for the owner's permission, so the todo is read
+	// without limiting it to the caller, who has no account.
+	todo, err := s.todoRepo.GetByID(ctx, link.TodoID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := s.comments.GetByTodoID(todo.ID, sharedCommentLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		redactDeleted(&comments[i])
+	}
+
+	// Counting is best effort and never fails the view.
+	if err := s.repo.RecordAccess(link.ID, now.UTC()); err != nil {
+		slog.WarnContext(ctx, "Failed to count share link access", "share_link_id", link.ID, "error", err)
+	}
+
+	return &models.SharedTodo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		CreatedAt:   todo.CreatedAt,
+		UpdatedAt:   todo.UpdatedAt,
+		Comments:    comments,
+		ExpiresAt:   link.ExpiresAt,
+	}, nil
+}
+
+// ensureTodo checks that the caller of ctx may manage the links of a todo.
+func (s *shareLinkService) ensureTodo(ctx context.Context, todoID int64) error {
+	if todoID <= 0 {
+		return invalidf("invalid todo id: %d", todoID)
+	}
+
+	todo, err := s.todoRepo.GetByID(ctx, todoID)
+	if err != nil {
+		return err
+	}
+
+	return s.policy.authorize(ctx, todo, ActionShare)
+}
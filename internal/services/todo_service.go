@@ -1,42 +1,101 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
 	"todo-api/internal/models"
 	"todo-api/internal/repositories"
 )
 
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// TodoService manages todos. It only reaches the todos the user
+// authenticated in ctx owns or was granted a role on, see
+// repositories.TodoRepository, and checks the role for every change.
 type TodoService interface {
-	GetAll() ([]models.Todo, error)
-	GetByID(id int64) (*models.Todo, error)
-	Create(todo *models.Todo) error
-	Update(todo *models.Todo) error
-	Delete(id int64) error
+	GetAll(ctx context.Context) ([]models.Todo, error)
+	List(ctx context.Context, filter models.TodoFilter) ([]models.Todo, int64, error)
+	GetByID(ctx context.Context, id int64) (*models.Todo, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]models.Todo, error)
+	Create(ctx context.Context, todo *models.Todo) error
+	Update(ctx context.Context, todo *models.Todo) error
+	Delete(ctx context.Context, id int64) error
 }
 
 type todoService struct {
-	repo repositories.TodoRepository
+	repo       repositories.TodoRepository
+	policy     accessPolicy
+	publisher  events.Publisher
+	dailyQuota int
+}
+
+// NewTodoService builds the todo service. Every successful write is
+// announced to publisher. Each user may create up to dailyQuota todos per
+// UTC day; zero disables the quota.
+func NewTodoService(repo repositories.TodoRepository, grants repositories.GrantRepository, publisher events.Publisher, dailyQuota int) TodoService {
+	return &todoService{repo: repo, policy: newAccessPolicy(grants), publisher: publisher, dailyQuota: dailyQuota}
 }
 
-func NewTodoService(repo repositories.TodoRepository) TodoService {
-	return &todoService{repo: repo}
+func (s *todoService) GetAll(ctx context.Context) ([]models.Todo, error) {
+	return s.repo.GetAll(ctx)
 }
 
-func (s *todoService) GetAll() ([]models.Todo, error) {
-	return s.repo.GetAll()
+// List returns a page of todos matching filter and the total number of
+// matches. A zero limit selects the default page size.
+func (s *todoService) List(ctx context.Context, filter models.TodoFilter) ([]models.Todo, int64, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	
+	if filter.Limit < 1 || filter.Limit > MaxListLimit {
+		return nil, 0, invalidf("limit must be a number between 1 and %d", MaxListLimit)
+	}
+	
+	if filter.Offset < 0 {
+		return nil, 0, invalidf("offset must be a non-negative number")
+	}
+	
+	return s.repo.List(ctx, filter)
 }
 
-func (s *todoService) GetByID(id int64) (*models.Todo, error) {
+func (s *todoService) GetByID(ctx context.Context, id int64) (*models.Todo, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid id: %d", id)
+		return nil, invalidf("invalid id: %d", id)
+	}
+	
+	todo, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.policy.authorize(ctx, todo, ActionView); err != nil {
+		return nil, err
 	}
 	
-	return s.repo.GetByID(id)
+	return todo, nil
 }
 
-func (s *todoService) Create(todo *models.Todo) error {
+// GetByIDs loads several todos at once, skipping ids that do not exist.
+func (s *todoService) GetByIDs(ctx context.Context, ids []int64) ([]models.Todo, error) {
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, invalidf("invalid id: %d", id)
+		}
+	}
+	
+	return s.repo.GetByIDs(ctx, ids)
+}
+
+func (s *todoService) Create(ctx context.Context, todo *models.Todo) error {
 	if err := s.validateTodo(todo); err != nil {
 		return err
 	}
@@ -44,64 +103,148 @@ func (s *todoService) Create(todo *models.Todo) error {
 	todo.Title = strings.TrimSpace(todo.Title)
 	todo.Description = strings.TrimSpace(todo.Description)
 	
-	return s.repo.Create(todo)
+	if err := s.checkQuota(ctx); err != nil {
+		return err
+	}
+	
+	writeCtx, recorded := events.WithRecorder(ctx)
+	if err := s.repo.Create(writeCtx, todo); err != nil {
+		return err
+	}
+	
+	slog.InfoContext(ctx, "Todo created", "todo_id", todo.ID)
+	s.publish(recorded, nil)
+	return nil
 }
 
-func (s *todoService) Update(todo *models.Todo) error {
+func (s *todoService) Update(ctx context.Context, todo *models.Todo) error {
 	if err := s.validateTodo(todo); err != nil {
 		return err
 	}
 	
 	if todo.ID <= 0 {
-		return fmt.Errorf("invalid id for update: %d", todo.ID)
+		return invalidf("invalid id for update: %d", todo.ID)
 	}
 	
-	_, err := s.repo.GetByID(todo.ID)
+	existing, err := s.repo.GetByID(ctx, todo.ID)
 	if err != nil {
 		return fmt.Errorf("todo not found for update: %w", err)
 	}
 	
+	if err := s.policy.authorize(ctx, existing, ActionEdit); err != nil {
+		return err
+	}
+	
+	todo.CommentCount = existing.CommentCount
 	todo.Title = strings.TrimSpace(todo.Title)
 	todo.Description = strings.TrimSpace(todo.Description)
 	
-	return s.repo.Update(todo)
+	writeCtx, recorded := events.WithRecorder(ctx)
+	if err := s.repo.Update(writeCtx, todo); err != nil {
+		return err
+	}
+	
+	slog.InfoContext(ctx, "Todo updated", "todo_id", todo.ID, "completed", todo.Completed)
+	s.publish(recorded, s.grantees(ctx, todo.ID))
+	return nil
 }
 
-func (s *todoService) Delete(id int64) error {
+func (s *todoService) Delete(ctx context.Context, id int64) error {
 	if id <= 0 {
-		return fmt.Errorf("invalid id for delete: %d", id)
+		return invalidf("invalid id for delete: %d", id)
 	}
 	
-	_, err := s.repo.GetByID(id)
+	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("todo not found for delete: %w", err)
 	}
 	
-	return s.repo.Delete(id)
+	if err := s.policy.authorize(ctx, existing, ActionDelete); err != nil {
+		return err
+	}
+	
+	// Grants go with the todo, so look up who it was shared with first.
+	grantees := s.grantees(ctx, id)
+	
+	writeCtx, recorded := events.WithRecorder(ctx)
+	if err := s.repo.Delete(writeCtx, id); err != nil {
+		return err
+	}
+	
+	slog.InfoContext(ctx, "Todo deleted", "todo_id", id)
+	s.publish(recorded, grantees)
+	return nil
+}
+
+// publish announces the events a committed write recorded to the todo's
+// owner and grantees. They are the events the webhook outbox stored, so
+// stream and webhook ids match.
+func (s *todoService) publish(recorded *events.Recorder, grantees []int64) {
+	for _, event := range recorded.Events() {
+		event.Grantees = grantees
+		s.publisher.Publish(event)
+	}
+}
+
+// grantees lists the users a todo is shared with. Failing to load them only
+// keeps the event from their streams.
+func (s *todoService) grantees(ctx context.Context, id int64) []int64 {
+	grants, err := s.policy.grants.GetByTodoID(id)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load the grantees of a todo", "todo_id", id, "error", err)
+		return nil
+	}
+	
+	users := make([]int64, 0, len(grants))
+	for _, grant := range grants {
+		users = append(users, grant.UserID)
+	}
+	return users
+}
+
+// checkQuota refuses a new todo once the user of ctx has created the daily
+// quota since midnight UTC. Callers not tied to a user have no quota.
+func (s *todoService) checkQuota(ctx context.Context) error {
+	owner := auth.Owner(ctx)
+	if s.dailyQuota <= 0 || owner == 0 {
+		return nil
+	}
+	
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	created, err := s.repo.CountCreatedSince(ctx, owner, today)
+	if err != nil {
+		return err
+	}
+	
+	if created >= int64(s.dailyQuota) {
+		return &QuotaError{Limit: s.dailyQuota, Reset: today.Add(24 * time.Hour)}
+	}
+	
+	return nil
 }
 
 func (s *todoService) validateTodo(todo *models.Todo) error {
 	if todo == nil {
-		return fmt.Errorf("todo cannot be nil")
+		return invalidf("todo cannot be nil")
 	}
 	
 	title := strings.TrimSpace(todo.Title)
 	if title == "" {
-		return fmt.Errorf("title is required")
+		return invalidf("title is required")
 	}
 	
 	if len(title) < 3 {
-		return fmt.Errorf("title must be at least 3 characters long")
+		return invalidf("title must be at least 3 characters long")
 	}
 	
 	if len(title) > 100 {
-		return fmt.Errorf("title must be less than 100 characters")
+		return invalidf("title must be less than 100 characters")
 	}
 	
 	if todo.Description != "" {
 		description := strings.TrimSpace(todo.Description)
 		if len(description) > 500 {
-			return fmt.Errorf("description must be less than 500 characters")
+			return invalidf("description must be less than 500 characters")
 		}
 	}
 	
@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/services"
+)
+
+// noGrants is a GrantRepository of todos shared with nobody.
+type noGrants struct {
+	repositories.GrantRepository
+}
+
+func (noGrants) GetRole(todoID, userID int64) (string, error) {
+	return "", repositories.ErrNotFound
+}
+
+func (noGrants) GetByTodoID(todoID int64) ([]models.Grant, error) {
+	return nil, nil
+}
+
+func TestTodoServiceEnforcesTheDailyQuota(t *testing.T) {
+	broker := events.NewBroker(10, 16)
+	defer broker.Close()
+	service := services.NewTodoService(repositories.NewMemoryTodoRepository(noGrants{}), noGrants{}, broker, 2)
+	alice := auth.NewContext(context.Background(), &auth.Principal{UserID: 1})
+
+	for _, title := range []string{"First todo", "Second todo"} {
+		if err := service.Create(alice, &models.Todo{Title: title}); err != nil {
+			t.Fatalf("Create %q: %v", title, err)
+		}
+	}
+
+	err := service.Create(alice, &models.Todo{Title: "Third todo"})
+	var quota *services.QuotaError
+	if !errors.As(err, &quota) || !errors.Is(err, services.ErrQuotaExceeded) {
+		t.Fatalf("third todo of the day: got %v, want a QuotaError", err)
+	}
+	if wait := time.Until(quota.Reset); quota.Limit != 2 || wait <= 0 || wait > 24*time.Hour {
+		t.Errorf("quota of %d resetting in %v, want 2 resetting by midnight", quota.Limit, wait)
+	}
+
+	// Other users and callers not tied to a user are not held back.
+	bob := auth.NewContext(context.Background(), &auth.Principal{UserID: 2})
+	if err := service.Create(bob, &models.Todo{Title: "Bob's todo"}); err != nil {
+		t.Errorf("bob's Create: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := service.Create(context.Background(), &models.Todo{Title: "Unowned todo"}); err != nil {
+			t.Errorf("Create without a user: %v", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"todo-api/internal/models"
+)
+
+const tracerName = "todo-api/internal/services"
+
+// TraceTodoService wraps service to record a span around every call, named
+// like "TodoService.Update", under which the repository's SQL spans nest.
+func TraceTodoService(service TodoService) TodoService {
+	return &tracedTodoService{next: service}
+}
+
+type tracedTodoService struct {
+	next TodoService
+}
+
+func (s *tracedTodoService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "TodoService."+method, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedTodoService) GetAll(ctx context.Context) (todos []models.Todo, err error) {
+	ctx, span := s.start(ctx, "GetAll")
+	defer func() { span.SetAttributes(attribute.Int("todo.count", len(todos))); endSpan(span, err) }()
+	return s.next.GetAll(ctx)
+}
+
+func (s *tracedTodoService) List(ctx context.Context, filter models.TodoFilter) (todos []models.Todo, total int64, err error) {
+	ctx, span := s.start(ctx, "List", attribute.Int("todo.limit", filter.Limit), attribute.Int("todo.offset", filter.Offset))
+	defer func() {
+		span.SetAttributes(attribute.Int("todo.count", len(todos)), attribute.Int64("todo.total", total))
+		endSpan(span, err)
+	}()
+	return s.next.List(ctx, filter)
+}
+
+func (s *tracedTodoService) GetByID(ctx context.Context, id int64) (todo *models.Todo, err error) {
+	ctx, span := s.start(ctx, "GetByID", attribute.Int64("todo.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.GetByID(ctx, id)
+}
+
+func (s *tracedTodoService) GetByIDs(ctx context.Context, ids []int64) (todos []models.Todo, err error) {
+	ctx, span := s.start(ctx, "GetByIDs", attribute.Int("todo.requested", len(ids)))
+	defer func() { span.SetAttributes(attribute.Int("todo.count", len(todos))); endSpan(span, err) }()
+	return s.next.GetByIDs(ctx, ids)
+}
+
+func (s *tracedTodoService) Create(ctx context.Context, todo *models.Todo) (err error) {
+	ctx, span := s.start(ctx, "Create")
+	defer func() { span.SetAttributes(attribute.Int64("todo.id", todo.ID)); endSpan(span, err) }()
+	return s.next.Create(ctx, todo)
+}
+
+func (s *tracedTodoService) Update(ctx context.Context, todo *models.Todo) (err error) {
+	ctx, span := s.start(ctx, "Update", attribute.Int64("todo.id", todo.ID))
+	defer func() { endSpan(span, err) }()
+	return s.next.Update(ctx, todo)
+}
+
+func (s *tracedTodoService) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := s.start(ctx, "Delete", attribute.Int64("todo.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.Delete(ctx, id)
+}
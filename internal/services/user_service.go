@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+const (
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt hashes; longer passwords would
+	// be silently truncated.
+	maxPasswordLength = 72
+)
+
+type UserService interface {
+	GetByID(id int64) (*models.User, error)
+	Register(email, password string) (*models.User, error)
+	Login(email, password string) (*models.Session, error)
+	ResolveIdentity(identity *auth.Identity) (*models.User, error)
+	LinkIdentity(ctx context.Context, identity *auth.Identity) (*models.User, error)
+}
+
+type userService struct {
+	repo       repositories.UserRepository
+	keys       APIKeyService
+	sessionTTL time.Duration
+	// dummyHash is compared against when the email is unknown, so failed
+	// logins take as long whether or not the account exists.
+	dummyHash []byte
+}
+
+// NewUserService builds the account service. Logins are issued API keys
+// belonging to the user that expire after sessionTTL.
+func NewUserService(repo repositories.UserRepository, keys APIKeyService, sessionTTL time.Duration) UserService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+	return &userService{repo: repo, keys: keys, sessionTTL: sessionTTL, dummyHash: dummyHash}
+}
+
+func (s *userService) GetByID(id int64) (*models.User, error) {
+	if id <= 0 {
+		return nil, invalidf("invalid id: %d", id)
+	}
+
+	return s.repo.GetByID(id)
+}
+
+// Register creates an account. An email that is already registered yields
+// repositories.ErrConflict.
+func (s *userService) Register(email, password string) (*models.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(password) < minPasswordLength {
+		return nil, invalidf("password must be at least %d characters long", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return nil, invalidf("password must be at most %d bytes long", maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{Email: email}
+	if err := s.repo.Create(user, string(hash)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Login checks the password of the account registered with email and issues
+// a token for it. Unknown emails and wrong passwords both yield
+// ErrUnauthorized.
+func (s *userService) Login(email, password string) (*models.Session, error) {
+	user, hash, err := s.repo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if errors.Is(err, repositories.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, fmt.Errorf("unknown email: %w", ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return nil, fmt.Errorf("wrong password for user %d: %w", user.ID, ErrUnauthorized)
+	}
+
+	expiresAt := time.Now().Add(s.sessionTTL).UTC()
+	key := &models.APIKey{
+		Name:      "login",
+		Scopes:    []string{models.ScopeTodosRead, models.ScopeTodosWrite},
+		UserID:    &user.ID,
+		ExpiresAt: &expiresAt,
+	}
+	if err := s.keys.Create(key); err != nil {
+		return nil, fmt.Errorf("failed to issue login token: %w", err)
+	}
+
+	return &models.Session{Token: key.Secret, ExpiresAt: expiresAt, User: user}, nil
+}
+
+// ResolveIdentity returns the user linked to an identity verified by the
+// OpenID Connect provider, creating one on first sign-in. An identity is
+// never linked to an existing account by its email, even a verified one:
+// local registration does not verify emails, so whoever registered the
+// address first would get the identity's todos. Such identities yield
+// repositories.ErrConflict until the account holder links them with
+// LinkIdentity.
+func (s *userService) ResolveIdentity(identity *auth.Identity) (*models.User, error) {
+	user, err := s.repo.GetByIdentity(identity.Issuer, identity.Subject)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return user, err
+	}
+
+	email, err := normalizeEmail(identity.Email)
+	if err != nil {
+		return nil, fmt.Errorf("identity %s: %w", identity.Subject, err)
+	}
+
+	user = &models.User{Email: email}
+	if err := s.repo.CreateWithIdentity(user, identity.Issuer, identity.Subject); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return nil, fmt.Errorf("email %s of identity %s is registered to an account it is not linked to: %w", email, identity.Subject, err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// LinkIdentity links an identity to the account of the caller of ctx, who
+// proves holding both by signing in to the account and presenting a token
+// of the identity. Callers signed in with a JWT, or without an account, are
+// refused with ErrForbidden; an identity linked to another account yields
+// repositories.ErrConflict.
+func (s *userService) LinkIdentity(ctx context.Context, identity *auth.Identity) (*models.User, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok || principal.UserID == 0 || principal.Subject != "" {
+		return nil, forbiddenf("identities are linked with a login token of the account")
+	}
+
+	linked, err := s.repo.GetByIdentity(identity.Issuer, identity.Subject)
+	switch {
+	case err == nil && linked.ID == principal.UserID:
+		return linked, nil
+	case err == nil:
+		return nil, fmt.Errorf("identity %s is linked to another account: %w", identity.Subject, repositories.ErrConflict)
+	case !errors.Is(err, repositories.ErrNotFound):
+		return nil, err
+	}
+
+	if err := s.repo.LinkIdentity(principal.UserID, identity.Issuer, identity.Subject); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(principal.UserID)
+}
+
+// userGetter loads accounts by id, as UserService and UserRepository do.
+type userGetter interface {
+	GetByID(id int64) (*models.User, error)
+}
+
+// CallerName returns the name the caller of ctx is shown under: the email
+// of its account, or the API key or JWT name of callers without one. It
+// returns false when authentication is disabled and the caller is unknown.
+func CallerName(ctx context.Context, users userGetter) (string, bool, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", false, nil
+	}
+
+	if principal.UserID == 0 || principal.Subject != "" {
+		return principal.Name, true, nil
+	}
+
+	// API keys of an account, such as login tokens, are shown as the
+	// account rather than the key.
+	account, err := users.GetByID(principal.UserID)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load user %d: %w", principal.UserID, err)
+	}
+	return account.Email, true, nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", invalidf("email is required")
+	}
+	if len(email) > 254 {
+		return "", invalidf("email must be at most 254 characters long")
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", invalidf("email is not a valid address")
+	}
+
+	return email, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+)
+
+type WebhookService interface {
+	GetAll() ([]models.WebhookSubscription, error)
+	GetByID(id int64) (*models.WebhookSubscription, error)
+	Create(subscription *models.WebhookSubscription) error
+	Update(subscription *models.WebhookSubscription) error
+	Delete(id int64) error
+	GetDeliveries(subscriptionID int64, status string, limit, offset int) ([]models.WebhookDelivery, int64, error)
+	Replay(subscriptionID, deliveryID int64) (int64, error)
+}
+
+type webhookService struct {
+	repo repositories.WebhookRepository
+}
+
+func NewWebhookService(repo repositories.WebhookRepository) WebhookService {
+	return &webhookService{repo: repo}
+}
+
+// GetAll returns every subscription with its secret withheld.
+func (s *webhookService) GetAll() ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return subscriptions, nil
+}
+
+// GetByID returns a subscription with its secret withheld.
+func (s *webhookService) GetByID(id int64) (*models.WebhookSubscription, error) {
+	if id <= 0 {
+		return nil, invalidf("invalid id: %d", id)
+	}
+
+	subscription, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// Create stores a subscription, generating a secret when none is given. The
+// secret is returned on the subscription so it can be shown this one time.
+func (s *webhookService) Create(subscription *models.WebhookSubscription) error {
+	if err := s.validateWebhook(subscription); err != nil {
+		return err
+	}
+
+	if subscription.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return err
+		}
+		subscription.Secret = secret
+	}
+
+	return s.repo.Create(subscription)
+}
+
+// Update changes a subscription. An empty secret keeps the current one.
+func (s *webhookService) Update(subscription *models.WebhookSubscription) error {
+	if err := s.validateWebhook(subscription); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetByID(subscription.ID)
+	if err != nil {
+		return fmt.Errorf("webhook not found for update: %w", err)
+	}
+
+	if subscription.Secret == "" {
+		subscription.Secret = existing.Secret
+	}
+	subscription.CreatedAt = existing.CreatedAt
+
+	if err := s.repo.Update(subscription); err != nil {
+		return err
+	}
+
+	subscription.Secret = ""
+	return nil
+}
+
+func (s *webhookService) Delete(id int64) error {
+	if id <= 0 {
+		return invalidf("invalid id for delete: %d", id)
+	}
+
+	return s.repo.Delete(id)
+}
+
+func (s *webhookService) GetDeliveries(subscriptionID int64, status string, limit, offset int) ([]models.WebhookDelivery, int64, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return nil, 0, invalidf("invalid delivery status: %s", status)
+	}
+
+	if _, err := s.repo.GetByID(subscriptionID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.repo.GetDeliveries(subscriptionID, status, limit, offset)
+}
+
+// Replay requeues dead deliveries of a subscription. A deliveryID of 0
+// replays the whole dead-letter list.
+func (s *webhookService) Replay(subscriptionID, deliveryID int64) (int64, error) {
+	if _, err := s.repo.GetByID(subscriptionID); err != nil {
+		return 0, err
+	}
+
+	return s.repo.Replay(subscriptionID, deliveryID)
+}
+
+func (s *webhookService) validateWebhook(subscription *models.WebhookSubscription) error {
+	if subscription == nil {
+		return invalidf("webhook cannot be nil")
+	}
+
+	subscription.URL = strings.TrimSpace(subscription.URL)
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return invalidf("url must be an absolute http or https URL")
+	}
+
+	if len(subscription.EventTypes) == 0 {
+		return invalidf("at least one event type is required")
+	}
+
+	seen := make(map[string]bool)
+	eventTypes := subscription.EventTypes[:0]
+	for _, eventType := range subscription.EventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if !events.Type(eventType).Valid() {
+			return invalidf("unknown event type: %s", eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	subscription.EventTypes = eventTypes
+
+	if subscription.Secret != "" && len(subscription.Secret) < 16 {
+		return invalidf("secret must be at least 16 characters long")
+	}
+
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// ErrBlobTooLarge is returned by Put when the content exceeds the size limit.
+	ErrBlobTooLarge = errors.New("blob exceeds the maximum size")
+	// ErrBlobNotFound is returned when no blob is stored under a hash.
+	ErrBlobNotFound = errors.New("blob not found")
+)
+
+// BlobStore keeps file contents on local disk under their SHA-256 hash, so
+// identical uploads are stored once.
+type BlobStore struct {
+	dir     string
+	maxSize int64
+}
+
+func NewBlobStore(dir string, maxSize int64) (*BlobStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	return &BlobStore{dir: dir, maxSize: maxSize}, nil
+}
+
+// Put streams r into the store and returns the hex SHA-256 of the content
+// and its size. Content that is already stored is not written twice.
+func (s *BlobStore) Put(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	if size > s.maxSize {
+		return "", 0, ErrBlobTooLarge
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return "", 0, fmt.Errorf("failed to sync blob: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to close blob: %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	path := s.path(sum)
+
+	if _, err := os.Stat(path); err == nil {
+		// Already stored: refresh the mtime so a pending GC sweep keeps it.
+		now := time.Now()
+		return sum, size, os.Chtimes(path, now, now)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return sum, size, nil
+}
+
+// CheckWritable writes, syncs and removes a probe file next to uploads in
+// progress, failing when the disk is full or read-only.
+func (s *BlobStore) CheckWritable() error {
+	probe, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "probe-*")
+	if err != nil {
+		return fmt.Errorf("failed to create probe file: %w", err)
+	}
+	defer os.Remove(probe.Name())
+	defer probe.Close()
+
+	if _, err := probe.Write([]byte("ok")); err != nil {
+		return fmt.Errorf("failed to write probe file: %w", err)
+	}
+	if err := probe.Sync(); err != nil {
+		return fmt.Errorf("failed to sync probe file: %w", err)
+	}
+
+	return nil
+}
+
+// Open returns the blob stored under sum for reading.
+func (s *BlobStore) Open(sum string) (*os.File, error) {
+	if !validSum(sum) {
+		return nil, ErrBlobNotFound
+	}
+
+	file, err := os.Open(s.path(sum))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+
+	return file, nil
+}
+
+// Delete removes the blob stored under sum. Missing blobs are ignored.
+func (s *BlobStore) Delete(sum string) error {
+	if !validSum(sum) {
+		return nil
+	}
+
+	if err := os.Remove(s.path(sum)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+
+	return nil
+}
+
+// Walk calls fn with the hash and modification time of every stored blob.
+func (s *BlobStore) Walk(fn func(sum string, modTime time.Time) error) error {
+	return filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == "tmp" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !validSum(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return fn(d.Name(), info.ModTime())
+	})
+}
+
+func (s *BlobStore) path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum)
+}
+
+func validSum(sum string) bool {
+	if len(sum) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
@@ -0,0 +1,97 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"todo-api/internal/config"
+	"todo-api/internal/tracing"
+)
+
+// exportedSpan is the part of a span written by the file exporter that the
+// tests look at.
+type exportedSpan struct {
+	Name        string
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ TraceID, SpanID string }
+}
+
+func TestSetupExportsSpansFollowingTraceparent(t *testing.T) {
+	traces := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := tracing.Setup(config.TracingConfig{
+		Exporter:    tracing.ExporterFile,
+		File:        traces,
+		ServiceName: "todo-api-test",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	header := http.Header{}
+	header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	_, span := otel.Tracer("tracing_test").Start(ctx, "GET /api/v1/todos/:id")
+	span.End()
+
+	// Shutting down flushes the spans.
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	file, err := os.Open(traces)
+	if err != nil {
+		t.Fatalf("Open traces: %v", err)
+	}
+	defer file.Close()
+
+	var spans []exportedSpan
+	decoder := json.NewDecoder(file)
+	for {
+		var span exportedSpan
+		if err := decoder.Decode(&span); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode span: %v", err)
+		}
+		spans = append(spans, span)
+	}
+
+	if len(spans) != 1 || spans[0].SpanContext.TraceID != traceID || spans[0].Parent.SpanID != parentID {
+		t.Fatalf("exported %+v, want one span continuing trace %s from %s", spans, traceID, parentID)
+	}
+}
+
+func TestSetupWithoutExporterPropagatesTraceContext(t *testing.T) {
+	shutdown, err := tracing.Setup(config.TracingConfig{Exporter: tracing.ExporterNone})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	fields := otel.GetTextMapPropagator().Fields()
+	for _, want := range []string{"traceparent", "baggage"} {
+		found := false
+		for _, field := range fields {
+			found = found || field == want
+		}
+		if !found {
+			t.Errorf("propagated fields %v lack %s", fields, want)
+		}
+	}
+}
+
+func TestSetupRejectsUnknownExporters(t *testing.T) {
+	if _, err := tracing.Setup(config.TracingConfig{Exporter: "zipkin"}); err == nil {
+		t.Fatal("Setup with an unknown exporter succeeded")
+	}
+}
@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-api/internal/config"
+	"todo-api/internal/repositories"
+)
+
+// Dispatcher delivers pending webhook deliveries from the outbox, retrying
+// failures with exponential backoff until they are moved to the dead-letter list.
+type Dispatcher struct {
+	repo   repositories.WebhookRepository
+	client *http.Client
+	cfg    config.WebhookConfig
+}
+
+func NewDispatcher(repo repositories.WebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+	}
+}
+
+// Run polls the outbox until stop is closed.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := d.DeliverDue(); err != nil {
+				slog.Error("Webhook dispatch failed", "error", err)
+			}
+		}
+	}
+}
+
+// DeliverDue attempts every delivery that is currently due and returns how
+// many attempts were recorded. When recording one fails, it stops there and
+// returns the attempts recorded before it.
+func (d *Dispatcher) DeliverDue() (int, error) {
+	due, err := d.repo.DueDeliveries(d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, delivery := range due {
+		if err := d.deliver(delivery); err != nil {
+			return i, err
+		}
+	}
+
+	return len(due), nil
+}
+
+func (d *Dispatcher) deliver(delivery repositories.DueDelivery) error {
+	statusCode, sendErr := d.send(delivery)
+	if sendErr == nil {
+		return d.repo.MarkDelivered(delivery.ID, statusCode)
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= d.cfg.MaxAttempts
+	if dead {
+		slog.Warn("Webhook delivery moved to dead letters",
+			"delivery_id", delivery.ID, "url", delivery.URL, "attempts", attempts, "error", sendErr)
+	}
+
+	return d.repo.MarkFailed(delivery.ID, statusCode, sendErr.Error(), d.Backoff(attempts), dead)
+}
+
+func (d *Dispatcher) send(delivery repositories.DueDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.EventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the wait before the attempt following the given number
+// of failed attempts: the initial backoff doubled per attempt, capped at
+// the maximum backoff.
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	backoff := d.cfg.InitialBackoff
+	for i := 1; i < attempts && backoff < d.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > d.cfg.MaxBackoff {
+		backoff = d.cfg.MaxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,290 @@
+package webhooks_test
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/webhooks"
+)
+
+const testSecret = "whsec_test"
+
+// receiver is a webhook endpoint answering with the queued statuses, then
+// 200, and recording what it was sent.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// outbox is a migrated SQLite database with one subscription pointing at
+// a local receiver.
+type outbox struct {
+	db           *database.DB
+	repo         repositories.WebhookRepository
+	receiver     *receiver
+	subscription *models.WebhookSubscription
+}
+
+func newOutbox(t *testing.T) *outbox {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todos.db")
+	cfg := &config.DatabaseConfig{
+		Path:          path,
+		DSN:           path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+		MigrationsDir: filepath.Join("..", "..", "migrations"),
+	}
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(cfg.MigrationsDir); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	rc := &receiver{}
+	ts := httptest.NewServer(rc)
+	t.Cleanup(ts.Close)
+
+	repo := repositories.NewWebhookRepository(db)
+	subscription := &models.WebhookSubscription{
+		URL:        ts.URL,
+		EventTypes: []string{string(events.TodoCreated)},
+		Secret:     testSecret,
+		Active:     true,
+	}
+	if err := repo.Create(subscription); err != nil {
+		t.Fatalf("Create subscription: %v", err)
+	}
+
+	return &outbox{db: db, repo: repo, receiver: rc, subscription: subscription}
+}
+
+// enqueue writes a todo.created event to the outbox and returns it.
+func (o *outbox) enqueue(t *testing.T, title string) events.Event {
+	t.Helper()
+
+	event := events.New(events.TodoCreated, &models.Todo{ID: 1, Title: title})
+	err := o.db.WithTx(func(tx *sql.Tx) error {
+		return o.repo.Enqueue(tx, event)
+	})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	return event
+}
+
+// delivery returns the only delivery of the subscription.
+func (o *outbox) delivery(t *testing.T) models.WebhookDelivery {
+	t.Helper()
+
+	deliveries, _, err := o.repo.GetDeliveries(o.subscription.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// testConfig retries at once, so that tests need not wait for backoff.
+func testConfig(maxAttempts int) config.WebhookConfig {
+	return config.WebhookConfig{
+		PollInterval:   time.Second,
+		Timeout:        5 * time.Second,
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		BatchSize:      10,
+	}
+}
+
+func deliverDue(t *testing.T, d *webhooks.Dispatcher, want int) {
+	t.Helper()
+
+	n, err := d.DeliverDue()
+	if err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if n != want {
+		t.Fatalf("DeliverDue attempted %d deliveries, want %d", n, want)
+	}
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	o := newOutbox(t)
+	event := o.enqueue(t, "Buy groceries")
+
+	deliverDue(t, webhooks.NewDispatcher(o.repo, testConfig(3)), 1)
+
+	if o.receiver.received() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", o.receiver.received())
+	}
+	req, body := o.receiver.requests[0], o.receiver.bodies[0]
+	if got := req.Header.Get(webhooks.HeaderEvent); got != string(events.TodoCreated) {
+		t.Errorf("%s = %q, want %q", webhooks.HeaderEvent, got, events.TodoCreated)
+	}
+	if got := req.Header.Get(webhooks.HeaderDelivery); got != event.ID {
+		t.Errorf("%s = %q, want the event id %q", webhooks.HeaderDelivery, got, event.ID)
+	}
+
+	signature, timestamp := req.Header.Get(webhooks.HeaderSignature), req.Header.Get(webhooks.HeaderTimestamp)
+	if !webhooks.Verify(testSecret, signature, timestamp, body, time.Minute) {
+		t.Errorf("signature %q of timestamp %s does not verify", signature, timestamp)
+	}
+	if webhooks.Verify("another secret", signature, timestamp, body, time.Minute) {
+		t.Error("signature verifies with another secret")
+	}
+	if webhooks.Verify(testSecret, signature, timestamp, append(body, ' '), time.Minute) {
+		t.Error("signature verifies a changed body")
+	}
+
+	if delivery := o.delivery(t); delivery.Status != models.DeliveryDelivered || delivery.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery is %s with status code %d, want delivered with 200", delivery.Status, delivery.LastStatusCode)
+	}
+	deliverDue(t, webhooks.NewDispatcher(o.repo, testConfig(3)), 0)
+}
+
+func TestDispatcherRetriesFailedDeliveries(t *testing.T) {
+	o := newOutbox(t)
+	o.receiver.statuses = []int{http.StatusInternalServerError, http.StatusBadGateway}
+	o.enqueue(t, "Buy groceries")
+	d := webhooks.NewDispatcher(o.repo, testConfig(5))
+
+	deliverDue(t, d, 1)
+	delivery := o.delivery(t)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("after a failure the delivery is %s after %d attempts with status code %d, want pending after 1 with 500",
+			delivery.Status, delivery.Attempts, delivery.LastStatusCode)
+	}
+	if delivery.LastError == "" {
+		t.Error("failed delivery has no last error")
+	}
+
+	deliverDue(t, d, 1)
+	deliverDue(t, d, 1)
+	delivery = o.delivery(t)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 3 || delivery.LastError != "" {
+		t.Errorf("delivery is %s after %d attempts with error %q, want delivered after 3 without error",
+			delivery.Status, delivery.Attempts, delivery.LastError)
+	}
+
+	// The same event is sent on every attempt.
+	for i, req := range o.receiver.requests {
+		if got, want := req.Header.Get(webhooks.HeaderDelivery), o.receiver.requests[0].Header.Get(webhooks.HeaderDelivery); got != want {
+			t.Errorf("attempt %d sent delivery %q, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestDispatcherBackoffDoublesUpToTheMaximum(t *testing.T) {
+	d := webhooks.NewDispatcher(nil, config.WebhookConfig{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+
+	for attempts, want := range map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	} {
+		if got := d.Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestDispatcherDeadLettersAndReplays(t *testing.T) {
+	o := newOutbox(t)
+	o.receiver.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError}
+	o.enqueue(t, "Buy groceries")
+	d := webhooks.NewDispatcher(o.repo, testConfig(2))
+
+	deliverDue(t, d, 1)
+	deliverDue(t, d, 1)
+	delivery := o.delivery(t)
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != 2 {
+		t.Fatalf("delivery is %s after %d attempts, want dead after 2", delivery.Status, delivery.Attempts)
+	}
+	deliverDue(t, d, 0)
+
+	if _, err := o.repo.Replay(o.subscription.ID, delivery.ID+1); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Replay of an unknown delivery: %v, want ErrNotFound", err)
+	}
+	replayed, err := o.repo.Replay(o.subscription.ID, 0)
+	if err != nil || replayed != 1 {
+		t.Fatalf("Replay = %d, %v; want 1", replayed, err)
+	}
+
+	deliverDue(t, d, 1)
+	if delivery := o.delivery(t); delivery.Status != models.DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("replayed delivery is %s after %d attempts, want delivered after 1", delivery.Status, delivery.Attempts)
+	}
+	if o.receiver.received() != 3 {
+		t.Errorf("receiver got %d requests, want 3", o.receiver.received())
+	}
+}
+
+// failingMarks fails to record the delivery after the first one.
+type failingMarks struct {
+	repositories.WebhookRepository
+	marked int
+}
+
+func (r *failingMarks) MarkDelivered(id int64, statusCode int) error {
+	if r.marked++; r.marked > 1 {
+		return errors.New("database is locked")
+	}
+	return r.WebhookRepository.MarkDelivered(id, statusCode)
+}
+
+func TestDispatcherCountsDeliveriesRecordedBeforeAnError(t *testing.T) {
+	o := newOutbox(t)
+	for _, title := range []string{"First todo", "Second todo", "Third todo"} {
+		o.enqueue(t, title)
+	}
+
+	n, err := webhooks.NewDispatcher(&failingMarks{WebhookRepository: o.repo}, testConfig(3)).DeliverDue()
+	if err == nil {
+		t.Fatal("DeliverDue succeeded although recording a delivery failed")
+	}
+	if n != 1 {
+		t.Errorf("DeliverDue reported %d deliveries, want the 1 recorded", n)
+	}
+}
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS update_todos_updated_at;
+
+DROP INDEX IF EXISTS idx_todos_completed;
+
+DROP INDEX IF EXISTS idx_todos_title;
+
+DROP TABLE IF EXISTS todos;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS update_comments_updated_at;
+
+DROP INDEX IF EXISTS idx_comments_parent_comment_id;
+
+DROP INDEX IF EXISTS idx_comments_todo_id;
+
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    parent_comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    edited_at DATETIME,
+    deleted_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_todo_id ON comments(todo_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_comment_id ON comments(parent_comment_id);
+
+CREATE TRIGGER IF NOT EXISTS update_comments_updated_at 
+    AFTER UPDATE ON comments
+    FOR EACH ROW
+    BEGIN
+        UPDATE comments SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+    END;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_attachments_sha256;
+
+DROP INDEX IF EXISTS idx_attachments_todo_id;
+
+DROP TABLE IF EXISTS attachments;
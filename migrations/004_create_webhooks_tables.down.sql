@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_subscription;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+
+DROP TABLE IF EXISTS webhook_deliveries;
+
+DROP TRIGGER IF EXISTS update_webhook_subscriptions_updated_at;
+
+DROP TABLE IF EXISTS webhook_subscriptions;
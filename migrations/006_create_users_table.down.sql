@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+ALTER TABLE api_keys DROP COLUMN user_id;
+
+DROP INDEX IF EXISTS idx_todos_owner_id;
+
+ALTER TABLE todos DROP COLUMN owner_id;
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_todo_grants_user_id;
+
+DROP TABLE IF EXISTS todo_grants;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_share_links_todo_id;
+
+DROP TABLE IF EXISTS share_links;
@@ -0,0 +1,15 @@
+-- Public read-only links to a todo. Only a hash of the token is stored, like
+-- API keys, and the optional password is a bcrypt hash.
+CREATE TABLE IF NOT EXISTS share_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    password_hash TEXT,
+    expires_at DATETIME,
+    access_count INTEGER NOT NULL DEFAULT 0,
+    last_accessed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_todo_id ON share_links(todo_id);
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS update_todos_updated_at ON todos;
+
+DROP FUNCTION IF EXISTS set_updated_at();
+
+DROP INDEX IF EXISTS idx_todos_owner_id;
+
+DROP INDEX IF EXISTS idx_todos_completed;
+
+DROP INDEX IF EXISTS idx_todos_title;
+
+DROP TABLE IF EXISTS todos;
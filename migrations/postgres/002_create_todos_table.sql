@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS todos (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    title TEXT NOT NULL CHECK (length(title) >= 3),
+    description TEXT,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Todos created by callers not tied to a user have no owner.
+    owner_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_todos_title ON todos(title);
+
+CREATE INDEX IF NOT EXISTS idx_todos_completed ON todos(completed);
+
+CREATE INDEX IF NOT EXISTS idx_todos_owner_id ON todos(owner_id, created_at);
+
+-- Sets updated_at before the row is written, so UPDATE ... RETURNING sees
+-- the new value.
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = clock_timestamp();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_todos_updated_at
+    BEFORE UPDATE ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,11 @@
+-- Shares a todo with another user. The owner has full access without a
+-- grant; grants give viewer, editor or admin access to everyone else.
+CREATE TABLE IF NOT EXISTS todo_grants (
+    todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_grants_user_id ON todo_grants(user_id);
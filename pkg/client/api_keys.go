@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// APIKeyRequest creates an API key.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is when the key stops working; nil never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ListAPIKeys returns every API key without its secret. It requires the
+// admin scope.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if _, err := c.call(ctx, http.MethodGet, apiPrefix+"/api-keys", nil, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *Client) GetAPIKey(ctx context.Context, id int64) (*models.APIKey, error) {
+	var key models.APIKey
+	if _, err := c.call(ctx, http.MethodGet, idPath("/api-keys/%d", id), nil, nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateAPIKey issues a key. Its Secret is only returned by this call.
+func (c *Client) CreateAPIKey(ctx context.Context, req APIKeyRequest) (*models.APIKey, error) {
+	var key models.APIKey
+	if _, err := c.call(ctx, http.MethodPost, apiPrefix+"/api-keys", nil, req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// DeleteAPIKey revokes a key.
+func (c *Client) DeleteAPIKey(ctx context.Context, id int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/api-keys/%d", id), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"todo-api/internal/models"
+)
+
+func (c *Client) ListAttachments(ctx context.Context, todoID int64) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d/attachments", todoID), nil, nil, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (c *Client) GetAttachment(ctx context.Context, todoID, attachmentID int64) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d/attachments/%d", todoID, attachmentID), nil, nil, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// UploadAttachment streams content to the server as a multipart upload.
+// The upload is only retried when content is an io.Seeker, which is
+// rewound before every attempt.
+func (c *Client) UploadAttachment(ctx context.Context, todoID int64, filename string, content io.Reader) (*models.Attachment, error) {
+	seeker, canRewind := content.(io.Seeker)
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	req := request{
+		method:      http.MethodPost,
+		path:        idPath("/todos/%d/attachments", todoID),
+		contentType: "multipart/form-data; boundary=" + boundary,
+		noRetry:     !canRewind,
+		body: func() (io.Reader, error) {
+			if canRewind {
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return nil, fmt.Errorf("failed to rewind attachment: %w", err)
+				}
+			}
+
+			pr, pw := io.Pipe()
+			go func() {
+				form := multipart.NewWriter(pw)
+				form.SetBoundary(boundary)
+				part, err := form.CreateFormFile("file", filename)
+				if err == nil {
+					_, err = io.Copy(part, content)
+				}
+				if err == nil {
+					err = form.Close()
+				}
+				pw.CloseWithError(err)
+			}()
+			return pr, nil
+		},
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var attachment models.Attachment
+	if err := json.NewDecoder(resp.Body).Decode(&attachment); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &attachment, nil
+}
+
+// DownloadAttachment opens the content of an attachment. The caller must
+// close the returned reader.
+func (c *Client) DownloadAttachment(ctx context.Context, todoID, attachmentID int64) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, request{
+		method: http.MethodGet,
+		path:   idPath("/todos/%d/attachments/%d/content", todoID, attachmentID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) DeleteAttachment(ctx context.Context, todoID, attachmentID int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/todos/%d/attachments/%d", todoID, attachmentID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"todo-api/internal/models"
+)
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Register creates an account. An email that is already registered yields
+// ErrConflict.
+func (c *Client) Register(ctx context.Context, email, password string) (*models.User, error) {
+	var user models.User
+	if _, err := c.call(ctx, http.MethodPost, apiPrefix+"/auth/register", nil, credentials{email, password}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login returns a token for the account. Pass it to WithToken to act as the
+// account; wrong credentials yield ErrUnauthorized.
+func (c *Client) Login(ctx context.Context, email, password string) (*models.Session, error) {
+	var session models.Session
+	if _, err := c.call(ctx, http.MethodPost, apiPrefix+"/auth/login", nil, credentials{email, password}, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Me returns the account the client's token belongs to.
+func (c *Client) Me(ctx context.Context) (*models.User, error) {
+	var user models.User
+	if _, err := c.call(ctx, http.MethodGet, apiPrefix+"/auth/me", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkIdentity links the identity jwt was issued to by the OpenID Connect
+// provider to the client's account, so the identity's JWTs act as the
+// account. The client must use a login token; an identity linked to
+// another account yields ErrConflict.
+func (c *Client) LinkIdentity(ctx context.Context, jwt string) (*models.User, error) {
+	var user models.User
+	body := struct {
+		Token string `json:"token"`
+	}{jwt}
+	if _, err := c.call(ctx, http.MethodPost, apiPrefix+"/auth/identities", nil, body, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
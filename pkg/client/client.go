@@ -0,0 +1,300 @@
+// Package client is a Go client for the todo REST API. Every method takes a
+// context, failed requests are retried with exponential backoff where that
+// is safe, and error responses are returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// maxRetryAfter is the longest Retry-After waited out. Longer ones, such as
+// that of an exhausted daily quota, are returned to the caller instead.
+const maxRetryAfter = time.Minute
+
+// RetryPolicy controls how failed requests are retried. Requests are
+// attempted at most MaxAttempts times, waiting a random delay of up to
+// MinBackoff doubled per attempt and capped at MaxBackoff, or the
+// Retry-After the server asked for.
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  200 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// Client talks to one todo API server. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	retry      RetryPolicy
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithToken sends token as a bearer token with every request.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy. A MaxAttempts of 1 disables
+// retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// New returns a client for the server at baseURL, such as
+// "http://localhost:8082".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("base url must be an absolute http or https URL: %q", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, apiPrefix)
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "todo-api-go-client",
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+// request describes one API call. body is called once per attempt; a nil
+// body sends none.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        func() (io.Reader, error)
+	contentType string
+	// header holds extra headers, such as a share link's password.
+	header http.Header
+	// noRetry is set when the body cannot be sent twice.
+	noRetry bool
+}
+
+func jsonBody(v interface{}) (func() (io.Reader, error), error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	return func() (io.Reader, error) { return bytes.NewReader(data), nil }, nil
+}
+
+// call sends a request with a JSON body and decodes a JSON response into out.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out interface{}) (*http.Response, error) {
+	req := request{method: method, path: path, query: query}
+	if in != nil {
+		body, err := jsonBody(in)
+		if err != nil {
+			return nil, err
+		}
+		req.body = body
+		req.contentType = "application/json"
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// send performs a request, retrying it according to the retry policy, and
+// returns the successful response with its body open. Error responses are
+// returned as *Error.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	target := *c.baseURL
+	target.Path += r.path
+	if len(r.query) > 0 {
+		target.RawQuery = r.query.Encode()
+	}
+
+	attempts := c.retry.MaxAttempts
+	if r.noRetry {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if r.body != nil {
+			var err error
+			if body, err = r.body(); err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, r.method, target.String(), body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		if r.contentType != "" {
+			req.Header.Set("Content-Type", r.contentType)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		for name, values := range r.header {
+			req.Header[name] = values
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= attempts || !idempotent(r.method) {
+				return nil, err
+			}
+			if err := c.wait(ctx, attempt, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		apiErr := decodeError(resp)
+		resp.Body.Close()
+
+		if attempt >= attempts || !retryable(r.method, resp.StatusCode) || apiErr.RetryAfter > maxRetryAfter {
+			return nil, apiErr
+		}
+		if err := c.wait(ctx, attempt, apiErr.RetryAfter); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait sleeps before the next attempt, honouring retryAfter when the server
+// sent one.
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = Backoff(c.retry, attempt)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Backoff returns a random delay before retrying after the given attempt,
+// with full jitter over an exponentially growing window.
+func Backoff(policy RetryPolicy, attempt int) time.Duration {
+	window := policy.MinBackoff << (attempt - 1)
+	if window <= 0 || window > policy.MaxBackoff {
+		window = policy.MaxBackoff
+	}
+	if window <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int64N(int64(window))) + 1
+}
+
+func idempotent(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
+
+// retryable reports whether a response status is worth retrying. Requests
+// that may have taken effect are only retried when they are idempotent; 429
+// and 503 mean the request was turned away and are always retried.
+func retryable(method string, status int) bool {
+	switch {
+	case status == http.StatusTooManyRequests, status == http.StatusServiceUnavailable:
+		return true
+	case status >= 500:
+		return idempotent(method)
+	default:
+		return false
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+func pagination(query url.Values, limit, offset int) url.Values {
+	if query == nil {
+		query = url.Values{}
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	return query
+}
+
+func idPath(format string, ids ...int64) string {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return apiPrefix + fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,933 @@
+package client_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/internal/config"
+	"todo-api/internal/database"
+	"todo-api/internal/events"
+	"todo-api/internal/models"
+	"todo-api/internal/repositories"
+	"todo-api/internal/server"
+	"todo-api/internal/services"
+	"todo-api/pkg/client"
+)
+
+// newTestClient starts the real router on a fresh database with
+// authentication disabled and returns a client for it.
+func newTestClient(t *testing.T) *client.Client {
+	t.Helper()
+
+	return newClient(t, startServer(t, false))
+}
+
+// startServer runs the real router on a fresh database and returns its URL.
+func startServer(t *testing.T, authEnabled bool) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("TODO_DB_PATH", filepath.Join(dir, "todos.db"))
+	t.Setenv("TODO_BLOB_DIR", filepath.Join(dir, "blobs"))
+	t.Setenv("TODO_MIGRATIONS_DIR", filepath.Join("..", "..", "migrations"))
+	t.Setenv("TODO_AUTH_ENABLED", strconv.FormatBool(authEnabled))
+
+	srv, err := server.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		ts.Close()
+	})
+
+	return ts.URL
+}
+
+// newClient returns a client for url with retries disabled.
+func newClient(t *testing.T, url string, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	opts = append(opts, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+	c, err := client.New(url, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return c
+}
+
+// createAdminKey issues an admin key straight through the database, the
+// way the server's apikey command bootstraps one.
+func createAdminKey(t *testing.T) string {
+	t.Helper()
+
+	db, err := database.NewConnection(&config.NewConfig().Database)
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	defer db.Close()
+
+	key := models.APIKey{Name: "test admin", Scopes: []string{models.ScopeAdmin}}
+	if err := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db)).Create(&key); err != nil {
+		t.Fatalf("Create admin key: %v", err)
+	}
+
+	return key.Secret
+}
+
+func createTodo(t *testing.T, c *client.Client, title string, completed bool) *models.Todo {
+	t.Helper()
+
+	todo, err := c.CreateTodo(context.Background(), &models.Todo{Title: title, Completed: completed})
+	if err != nil {
+		t.Fatalf("CreateTodo(%q): %v", title, err)
+	}
+	return todo
+}
+
+func TestTodoLifecycle(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := c.CreateTodo(ctx, &models.Todo{Title: "  Buy groceries ", Description: "Milk, eggs"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if created.ID == 0 || created.Title != "Buy groceries" || created.Completed {
+		t.Fatalf("CreateTodo returned %+v", created)
+	}
+
+	got, err := c.GetTodo(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if got.Title != created.Title || got.Description != "Milk, eggs" {
+		t.Fatalf("GetTodo returned %+v", got)
+	}
+
+	got.Completed = true
+	updated, err := c.UpdateTodo(ctx, got)
+	if err != nil {
+		t.Fatalf("UpdateTodo: %v", err)
+	}
+	if !updated.Completed || updated.ID != created.ID {
+		t.Fatalf("UpdateTodo returned %+v", updated)
+	}
+
+	if err := c.DeleteTodo(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteTodo: %v", err)
+	}
+
+	_, err = c.GetTodo(ctx, created.ID)
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetTodo after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestErrorResponsesAreDecoded(t *testing.T) {
+	c := newTestClient(t)
+
+	_, err := c.CreateTodo(context.Background(), &models.Todo{Title: "x"})
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateTodo: got %T %v, want *client.Error", err, err)
+	}
+	if apiErr.StatusCode != 400 || apiErr.Message != "Failed to create todo" || apiErr.Details != "title must be at least 3 characters long" {
+		t.Fatalf("unexpected error %+v", apiErr)
+	}
+	if !errors.Is(err, client.ErrBadRequest) || errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("errors.Is does not match the status of %v", err)
+	}
+
+	err = c.DeleteTodo(context.Background(), 404)
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("DeleteTodo of a missing todo: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestTodosIteratorPagesThroughAllResults(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 25; i++ {
+		createTodo(t, c, fmt.Sprintf("todo %02d", i), i%5 == 0)
+	}
+
+	var titles []string
+	for todo, err := range c.Todos(ctx, client.ListTodosOptions{Limit: 10}) {
+		if err != nil {
+			t.Fatalf("Todos: %v", err)
+		}
+		titles = append(titles, todo.Title)
+	}
+
+	if len(titles) != 25 {
+		t.Fatalf("iterated over %d todos, want 25", len(titles))
+	}
+	if titles[0] != "todo 25" || titles[24] != "todo 01" {
+		t.Fatalf("todos are not newest first: first %q, last %q", titles[0], titles[24])
+	}
+
+	completed := true
+	count := 0
+	for todo, err := range c.Todos(ctx, client.ListTodosOptions{Completed: &completed, Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Todos(completed): %v", err)
+		}
+		if !todo.Completed {
+			t.Fatalf("filter returned open todo %+v", todo)
+		}
+		count++
+	}
+	if count != 5 {
+		t.Fatalf("iterated over %d completed todos, want 5", count)
+	}
+
+	// Breaking out of the loop must stop paging.
+	for range c.Todos(ctx, client.ListTodosOptions{Limit: 10}) {
+		break
+	}
+}
+
+func TestListTodosReturnsPageAndTotal(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	createTodo(t, c, "write report", false)
+	createTodo(t, c, "review report", true)
+	createTodo(t, c, "water plants", false)
+
+	page, err := c.ListTodos(ctx, client.ListTodosOptions{Query: "REPORT", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListTodos: %v", err)
+	}
+	if page.Total != 2 || len(page.Items) != 1 || page.Items[0].Title != "review report" {
+		t.Fatalf("ListTodos returned %+v", page)
+	}
+
+	page, err = c.ListTodos(ctx, client.ListTodosOptions{})
+	if err != nil {
+		t.Fatalf("ListTodos: %v", err)
+	}
+	if page.Total != 3 || len(page.Items) != 3 || page.Limit != 20 {
+		t.Fatalf("ListTodos without options returned %+v", page)
+	}
+
+	_, err = c.ListTodos(ctx, client.ListTodosOptions{Limit: 500})
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("ListTodos with a large limit: got %v, want ErrBadRequest", err)
+	}
+}
+
+func TestComments(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	todo := createTodo(t, c, "discuss plan", false)
+
+	first, err := c.CreateComment(ctx, todo.ID, client.CommentRequest{Body: "first"})
+	if err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	if first.Author != "anonymous" {
+		t.Fatalf("CreateComment without authentication has author %q, want anonymous", first.Author)
+	}
+
+	reply, err := c.CreateComment(ctx, todo.ID, client.CommentRequest{Body: "reply", ParentCommentID: &first.ID})
+	if err != nil {
+		t.Fatalf("CreateComment reply: %v", err)
+	}
+	if reply.ParentCommentID == nil || *reply.ParentCommentID != first.ID {
+		t.Fatalf("reply has parent %v, want %d", reply.ParentCommentID, first.ID)
+	}
+
+	first.Body = "first, edited"
+	edited, err := c.UpdateComment(ctx, todo.ID, first)
+	if err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if edited.Body != "first, edited" || edited.EditedAt == nil {
+		t.Fatalf("UpdateComment returned %+v", edited)
+	}
+
+	if err := c.DeleteComment(ctx, todo.ID, first.ID); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+
+	var comments []models.Comment
+	for comment, err := range c.Comments(ctx, todo.ID) {
+		if err != nil {
+			t.Fatalf("Comments: %v", err)
+		}
+		comments = append(comments, comment)
+	}
+	if len(comments) != 2 || comments[0].Body != "" || comments[0].DeletedAt == nil || comments[1].Body != "reply" {
+		t.Fatalf("Comments returned %+v", comments)
+	}
+
+	_, err = c.GetComment(ctx, todo.ID, 999)
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetComment of a missing comment: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	todo := createTodo(t, c, "file taxes", false)
+	content := []byte("receipt total: 42\n")
+
+	attachment, err := c.UploadAttachment(ctx, todo.ID, "receipt.txt", bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("UploadAttachment: %v", err)
+	}
+	if attachment.Filename != "receipt.txt" || attachment.Size != int64(len(content)) || attachment.ContentType != "text/plain; charset=utf-8" {
+		t.Fatalf("UploadAttachment returned %+v", attachment)
+	}
+
+	attachments, err := c.ListAttachments(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("ListAttachments: %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].ID != attachment.ID {
+		t.Fatalf("ListAttachments returned %+v", attachments)
+	}
+
+	body, err := c.DownloadAttachment(ctx, todo.ID, attachment.ID)
+	if err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+	downloaded, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || !bytes.Equal(downloaded, content) {
+		t.Fatalf("downloaded %q, %v; want %q", downloaded, err, content)
+	}
+
+	if err := c.DeleteAttachment(ctx, todo.ID, attachment.ID); err != nil {
+		t.Fatalf("DeleteAttachment: %v", err)
+	}
+	if _, err := c.GetAttachment(ctx, todo.ID, attachment.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetAttachment after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestAttachmentWithMissingContentIsServerError(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	todo := createTodo(t, c, "file taxes", false)
+
+	attachment, err := c.UploadAttachment(ctx, todo.ID, "receipt.txt", strings.NewReader("receipt total: 42\n"))
+	if err != nil {
+		t.Fatalf("UploadAttachment: %v", err)
+	}
+
+	blob := filepath.Join(config.NewConfig().Storage.BlobDir, attachment.SHA256[:2], attachment.SHA256)
+	if err := os.Remove(blob); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	if _, err := c.DownloadAttachment(ctx, todo.ID, attachment.ID); !errors.Is(err, client.ErrServer) {
+		t.Fatalf("DownloadAttachment of a missing blob: got %v, want ErrServer", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := c.CreateWebhook(ctx, client.WebhookRequest{
+		URL:        "http://127.0.0.1:1/hooks",
+		EventTypes: []string{"todo.created"},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if created.Secret == "" || !created.Active {
+		t.Fatalf("CreateWebhook returned %+v", created)
+	}
+
+	got, err := c.GetWebhook(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if got.Secret != "" {
+		t.Fatalf("GetWebhook exposed the secret")
+	}
+
+	createTodo(t, c, "trigger delivery", false)
+
+	inactive := false
+	updated, err := c.UpdateWebhook(ctx, created.ID, client.WebhookRequest{
+		URL:        created.URL,
+		EventTypes: []string{"todo.created", "todo.deleted"},
+		Active:     &inactive,
+	})
+	if err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	if updated.Active || len(updated.EventTypes) != 2 {
+		t.Fatalf("UpdateWebhook returned %+v", updated)
+	}
+
+	count := 0
+	for delivery, err := range c.Deliveries(ctx, created.ID, "") {
+		if err != nil {
+			t.Fatalf("Deliveries: %v", err)
+		}
+		if delivery.EventType != "todo.created" {
+			t.Fatalf("unexpected delivery %+v", delivery)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("got %d deliveries, want 1", count)
+	}
+
+	replayed, err := c.ReplayDeliveries(ctx, created.ID)
+	if err != nil || replayed != 0 {
+		t.Fatalf("ReplayDeliveries = %d, %v; want 0 with nothing dead", replayed, err)
+	}
+
+	webhooks, err := c.ListWebhooks(ctx)
+	if err != nil || len(webhooks) != 1 {
+		t.Fatalf("ListWebhooks = %+v, %v", webhooks, err)
+	}
+
+	if err := c.DeleteWebhook(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := c.GetWebhook(ctx, created.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetWebhook after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestStreamTodos(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := c.StreamTodos(ctx, client.StreamOptions{Types: []events.Type{events.TodoCreated}})
+	if err != nil {
+		t.Fatalf("StreamTodos: %v", err)
+	}
+	defer stream.Close()
+
+	todo := createTodo(t, c, "streamed todo", false)
+
+	event, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if event.Type != events.TodoCreated || event.TodoID != todo.ID || event.Todo == nil || event.Todo.Title != "streamed todo" {
+		t.Fatalf("Next returned %+v", event)
+	}
+	if stream.LastEventID != event.Seq || event.Seq == 0 {
+		t.Fatalf("LastEventID = %d, want %d", stream.LastEventID, event.Seq)
+	}
+}
+
+func TestStreamAndWebhooksShareEventIDs(t *testing.T) {
+	url := startServer(t, false)
+	c := newClient(t, url)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	webhook, err := c.CreateWebhook(ctx, client.WebhookRequest{
+		URL:        "http://127.0.0.1:1/hooks",
+		EventTypes: []string{"todo.created"},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	stream, err := c.StreamTodos(ctx, client.StreamOptions{Types: []events.Type{events.TodoCreated}})
+	if err != nil {
+		t.Fatalf("StreamTodos: %v", err)
+	}
+	defer stream.Close()
+
+	createTodo(t, c, "first todo", false)
+	second := createTodo(t, c, "second todo", false)
+	first, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+
+	var deliveryIDs []string
+	for delivery, err := range c.Deliveries(ctx, webhook.ID, "") {
+		if err != nil {
+			t.Fatalf("Deliveries: %v", err)
+		}
+		deliveryIDs = append(deliveryIDs, delivery.EventID)
+	}
+	if !slices.Contains(deliveryIDs, first.ID) {
+		t.Errorf("webhook deliveries carry event ids %v, want one to be the streamed id %s", deliveryIDs, first.ID)
+	}
+
+	// A stream resumed from the webhook's event id starts after it.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/api/v1/todos/stream?types=todo.created", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", first.ID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading resumed stream: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var event events.Event
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("decoding %q: %v", data, err)
+			}
+			if event.TodoID != second.ID {
+				t.Errorf("resumed stream starts with %s of todo %d, want the creation of todo %d", event.Type, event.TodoID, second.ID)
+			}
+			break
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	c := newTestClient(t)
+
+	if err := c.Health(context.Background()); err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+}
+
+func TestAPIKeysEnforceScopes(t *testing.T) {
+	url := startServer(t, true)
+	ctx := context.Background()
+
+	if err := newClient(t, url).Health(ctx); err != nil {
+		t.Fatalf("Health without a key: %v", err)
+	}
+	if _, err := newClient(t, url).ListTodos(ctx, client.ListTodosOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("ListTodos without a key: got %v, want ErrUnauthorized", err)
+	}
+	if _, err := newClient(t, url, client.WithToken("todo_bogus")).ListTodos(ctx, client.ListTodosOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("ListTodos with an unknown key: got %v, want ErrUnauthorized", err)
+	}
+
+	admin := newClient(t, url, client.WithToken(createAdminKey(t)))
+
+	readOnly, err := admin.CreateAPIKey(ctx, client.APIKeyRequest{Name: "dashboard", Scopes: []string{models.ScopeTodosRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if readOnly.Secret == "" || readOnly.Prefix == "" || readOnly.Secret[:len(readOnly.Prefix)] != readOnly.Prefix {
+		t.Fatalf("CreateAPIKey returned %+v", readOnly)
+	}
+
+	reader := newClient(t, url, client.WithToken(readOnly.Secret))
+	if _, err := reader.ListTodos(ctx, client.ListTodosOptions{}); err != nil {
+		t.Fatalf("ListTodos with todos:read: %v", err)
+	}
+	if _, err := reader.CreateTodo(ctx, &models.Todo{Title: "not allowed"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("CreateTodo with todos:read: got %v, want ErrForbidden", err)
+	}
+	if _, err := reader.ListWebhooks(ctx); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("ListWebhooks with todos:read: got %v, want ErrForbidden", err)
+	}
+
+	if _, err := admin.CreateTodo(ctx, &models.Todo{Title: "allowed for admins"}); err != nil {
+		t.Fatalf("CreateTodo with admin: %v", err)
+	}
+
+	got, err := admin.GetAPIKey(ctx, readOnly.ID)
+	if err != nil {
+		t.Fatalf("GetAPIKey: %v", err)
+	}
+	if got.Secret != "" || got.LastUsedAt == nil {
+		t.Fatalf("GetAPIKey returned %+v, want no secret and a last use", got)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if _, err := admin.CreateAPIKey(ctx, client.APIKeyRequest{Name: "expired", Scopes: []string{models.ScopeTodosRead}, ExpiresAt: &past}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("CreateAPIKey expiring in the past: got %v, want ErrBadRequest", err)
+	}
+	if _, err := admin.CreateAPIKey(ctx, client.APIKeyRequest{Name: "typo", Scopes: []string{"todos:delete"}}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("CreateAPIKey with an unknown scope: got %v, want ErrBadRequest", err)
+	}
+
+	if err := admin.DeleteAPIKey(ctx, readOnly.ID); err != nil {
+		t.Fatalf("DeleteAPIKey: %v", err)
+	}
+	if _, err := reader.ListTodos(ctx, client.ListTodosOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("ListTodos with a revoked key: got %v, want ErrUnauthorized", err)
+	}
+
+	keys, err := admin.ListAPIKeys(ctx)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("ListAPIKeys = %+v, %v; want only the admin key", keys, err)
+	}
+}
+
+// loginAs registers an account and returns a client acting as it.
+func loginAs(t *testing.T, url, email string) *client.Client {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := newClient(t, url).Register(ctx, email, "correct horse battery"); err != nil {
+		t.Fatalf("Register %s: %v", email, err)
+	}
+	session, err := newClient(t, url).Login(ctx, email, "correct horse battery")
+	if err != nil {
+		t.Fatalf("Login %s: %v", email, err)
+	}
+	if session.Token == "" || session.User == nil || session.User.Email != email {
+		t.Fatalf("Login returned %+v", session)
+	}
+
+	return newClient(t, url, client.WithToken(session.Token))
+}
+
+func TestAccountsOnlySeeTheirOwnTodos(t *testing.T) {
+	url := startServer(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	alice := loginAs(t, url, "alice@example.com")
+	bob := loginAs(t, url, "bob@example.com")
+	anonymous := newClient(t, url)
+
+	if _, err := anonymous.Register(ctx, "Alice@Example.com", "another password"); !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("Register with a taken email: got %v, want ErrConflict", err)
+	}
+	if _, err := anonymous.Register(ctx, "carol@example.com", "short"); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("Register with a short password: got %v, want ErrBadRequest", err)
+	}
+	if _, err := anonymous.Login(ctx, "alice@example.com", "wrong password"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Login with a wrong password: got %v, want ErrUnauthorized", err)
+	}
+	if _, err := anonymous.Login(ctx, "nobody@example.com", "correct horse battery"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Login with an unknown email: got %v, want ErrUnauthorized", err)
+	}
+
+	me, err := alice.Me(ctx)
+	if err != nil || me.Email != "alice@example.com" {
+		t.Fatalf("Me = %+v, %v", me, err)
+	}
+
+	stream, err := bob.StreamTodos(ctx, client.StreamOptions{})
+	if err != nil {
+		t.Fatalf("StreamTodos: %v", err)
+	}
+	defer stream.Close()
+
+	aliceTodo := createTodo(t, alice, "alice's todo", false)
+	if aliceTodo.OwnerID == nil || *aliceTodo.OwnerID != me.ID {
+		t.Fatalf("CreateTodo owner = %v, want %d", aliceTodo.OwnerID, me.ID)
+	}
+	note, err := alice.CreateComment(ctx, aliceTodo.ID, client.CommentRequest{Body: "private note"})
+	if err != nil || note.Author != "alice@example.com" {
+		t.Fatalf("CreateComment = %+v, %v; want alice as the author", note, err)
+	}
+	bobTodo := createTodo(t, bob, "bob's todo", false)
+
+	// Bob's stream skips alice's todo and delivers his own.
+	event, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if event.TodoID != bobTodo.ID {
+		t.Fatalf("bob's stream delivered an event of todo %d, want %d", event.TodoID, bobTodo.ID)
+	}
+
+	page, err := bob.ListTodos(ctx, client.ListTodosOptions{})
+	if err != nil || page.Total != 1 || page.Items[0].ID != bobTodo.ID {
+		t.Fatalf("bob's ListTodos = %+v, %v; want only his todo", page, err)
+	}
+	if _, err := bob.GetTodo(ctx, aliceTodo.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("bob's GetTodo of alice's todo: got %v, want ErrNotFound", err)
+	}
+	if _, err := bob.UpdateTodo(ctx, &models.Todo{ID: aliceTodo.ID, Title: "taken over"}); err == nil {
+		t.Fatalf("bob's UpdateTodo of alice's todo succeeded")
+	}
+	if err := bob.DeleteTodo(ctx, aliceTodo.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("bob's DeleteTodo of alice's todo: got %v, want ErrNotFound", err)
+	}
+	if _, err := bob.ListComments(ctx, aliceTodo.ID, 0, 0); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("bob's ListComments of alice's todo: got %v, want ErrNotFound", err)
+	}
+	if _, err := bob.CreateComment(ctx, aliceTodo.ID, client.CommentRequest{Body: "hi"}); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("bob's CreateComment on alice's todo: got %v, want ErrNotFound", err)
+	}
+
+	got, err := alice.GetTodo(ctx, aliceTodo.ID)
+	if err != nil || got.Title != "alice's todo" {
+		t.Fatalf("alice's GetTodo = %+v, %v; want her todo unchanged", got, err)
+	}
+
+	// Keys that do not belong to an account see every todo.
+	admin := newClient(t, url, client.WithToken(createAdminKey(t)))
+	page, err = admin.ListTodos(ctx, client.ListTodosOptions{})
+	if err != nil || page.Total != 2 {
+		t.Fatalf("admin's ListTodos = %+v, %v; want both todos", page, err)
+	}
+	if _, err := admin.Me(ctx); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("admin's Me: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSharedTodosEnforceRoles(t *testing.T) {
+	url := startServer(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	alice := loginAs(t, url, "alice@example.com")
+	bob := loginAs(t, url, "bob@example.com")
+	carol := loginAs(t, url, "carol@example.com")
+	bobUser, err := bob.Me(ctx)
+	if err != nil {
+		t.Fatalf("Me: %v", err)
+	}
+	carolUser, err := carol.Me(ctx)
+	if err != nil {
+		t.Fatalf("Me: %v", err)
+	}
+
+	shared := createTodo(t, alice, "shared todo", false)
+	createTodo(t, alice, "private todo", false)
+
+	if _, err := bob.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "bob@example.com", Role: models.RoleAdmin}); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("bob's ShareTodo of alice's todo: got %v, want ErrNotFound", err)
+	}
+
+	grant, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "Bob@example.com", Role: models.RoleViewer})
+	if err != nil || grant.UserID != bobUser.ID || grant.Role != models.RoleViewer {
+		t.Fatalf("ShareTodo = %+v, %v; want bob as viewer", grant, err)
+	}
+
+	// Viewers read the todo but cannot change it, and are told why.
+	page, err := bob.ListTodos(ctx, client.ListTodosOptions{})
+	if err != nil || page.Total != 1 || page.Items[0].ID != shared.ID {
+		t.Fatalf("viewer's ListTodos = %+v, %v; want only the shared todo", page, err)
+	}
+	if _, err := bob.ListComments(ctx, shared.ID, 0, 0); err != nil {
+		t.Fatalf("viewer's ListComments: %v", err)
+	}
+	_, err = bob.UpdateTodo(ctx, &models.Todo{ID: shared.ID, Title: "edited by a viewer"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 403 || !strings.Contains(apiErr.Details, "requires the editor role") {
+		t.Fatalf("viewer's UpdateTodo: got %v, want 403 with the reason", err)
+	}
+	if _, err := bob.CreateComment(ctx, shared.ID, client.CommentRequest{Body: "hi"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("viewer's CreateComment: got %v, want ErrForbidden", err)
+	}
+
+	// Sharing again changes the role.
+	if _, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "bob@example.com", Role: models.RoleEditor}); err != nil {
+		t.Fatalf("ShareTodo as editor: %v", err)
+	}
+	updated, err := bob.UpdateTodo(ctx, &models.Todo{ID: shared.ID, Title: "edited by an editor"})
+	if err != nil || updated.OwnerID == nil || *updated.OwnerID != *shared.OwnerID {
+		t.Fatalf("editor's UpdateTodo = %+v, %v; want alice to stay the owner", updated, err)
+	}
+	if _, err := bob.CreateComment(ctx, shared.ID, client.CommentRequest{Body: "done"}); err != nil {
+		t.Fatalf("editor's CreateComment: %v", err)
+	}
+	if err := bob.DeleteTodo(ctx, shared.ID); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("editor's DeleteTodo: got %v, want ErrForbidden", err)
+	}
+	if _, err := bob.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "carol@example.com", Role: models.RoleViewer}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("editor's ShareTodo: got %v, want ErrForbidden", err)
+	}
+
+	if _, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "alice@example.com", Role: models.RoleViewer}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("ShareTodo with the owner: got %v, want ErrBadRequest", err)
+	}
+	if _, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "carol@example.com", Role: "owner"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("ShareTodo with an unknown role: got %v, want ErrBadRequest", err)
+	}
+	if _, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "nobody@example.com", Role: models.RoleViewer}); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("ShareTodo with an unknown user: got %v, want ErrNotFound", err)
+	}
+
+	// Admins share and revoke; anyone may give up their own access.
+	if _, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "carol@example.com", Role: models.RoleAdmin}); err != nil {
+		t.Fatalf("ShareTodo as admin: %v", err)
+	}
+	grants, err := carol.ListGrants(ctx, shared.ID)
+	if err != nil || len(grants) != 2 || grants[0].Email != "bob@example.com" || grants[1].Role != models.RoleAdmin {
+		t.Fatalf("ListGrants = %+v, %v", grants, err)
+	}
+	if err := carol.RevokeGrant(ctx, shared.ID, bobUser.ID); err != nil {
+		t.Fatalf("admin's RevokeGrant: %v", err)
+	}
+	if _, err := bob.GetTodo(ctx, shared.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetTodo after revocation: got %v, want ErrNotFound", err)
+	}
+	if err := carol.RevokeGrant(ctx, shared.ID, carolUser.ID); err != nil {
+		t.Fatalf("RevokeGrant of one's own access: %v", err)
+	}
+	if grants, err := alice.ListGrants(ctx, shared.ID); err != nil || len(grants) != 0 {
+		t.Fatalf("ListGrants after revocations = %+v, %v; want none", grants, err)
+	}
+}
+
+func TestGranteesStreamSharedTodos(t *testing.T) {
+	url := startServer(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	alice := loginAs(t, url, "alice@example.com")
+	bob := loginAs(t, url, "bob@example.com")
+	carol := loginAs(t, url, "carol@example.com")
+
+	shared := createTodo(t, alice, "shared todo", false)
+	if _, err := alice.ShareTodo(ctx, shared.ID, client.GrantRequest{Email: "bob@example.com", Role: models.RoleViewer}); err != nil {
+		t.Fatalf("ShareTodo: %v", err)
+	}
+
+	bobStream, err := bob.StreamTodos(ctx, client.StreamOptions{})
+	if err != nil {
+		t.Fatalf("bob's StreamTodos: %v", err)
+	}
+	defer bobStream.Close()
+	carolStream, err := carol.StreamTodos(ctx, client.StreamOptions{})
+	if err != nil {
+		t.Fatalf("carol's StreamTodos: %v", err)
+	}
+	defer carolStream.Close()
+
+	createTodo(t, alice, "private todo", false)
+	if _, err := alice.UpdateTodo(ctx, &models.Todo{ID: shared.ID, Title: "edited shared todo"}); err != nil {
+		t.Fatalf("UpdateTodo: %v", err)
+	}
+	if err := alice.DeleteTodo(ctx, shared.ID); err != nil {
+		t.Fatalf("DeleteTodo: %v", err)
+	}
+	own := createTodo(t, carol, "carol's todo", false)
+
+	// The grantee hears of the shared todo until it is deleted, and of
+	// nothing else of alice's.
+	for _, want := range []events.Type{events.TodoUpdated, events.TodoDeleted} {
+		event, err := bobStream.Next()
+		if err != nil {
+			t.Fatalf("bob's Next: %v", err)
+		}
+		if event.Type != want || event.TodoID != shared.ID {
+			t.Fatalf("bob received %s of todo %d, want %s of the shared todo %d", event.Type, event.TodoID, want, shared.ID)
+		}
+	}
+
+	event, err := carolStream.Next()
+	if err != nil {
+		t.Fatalf("carol's Next: %v", err)
+	}
+	if event.TodoID != own.ID {
+		t.Errorf("carol received %s of todo %d, want only her own todo %d", event.Type, event.TodoID, own.ID)
+	}
+}
+
+func TestShareLinksOpenReadOnlyViews(t *testing.T) {
+	t.Setenv("TODO_SHARE_RATE_LIMIT", "10")
+	url := startServer(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	alice := loginAs(t, url, "alice@example.com")
+	bob := loginAs(t, url, "bob@example.com")
+	anonymous := newClient(t, url)
+
+	todo, err := alice.CreateTodo(ctx, &models.Todo{Title: "shared plan", Description: "step one"})
+	if err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if _, err := alice.CreateComment(ctx, todo.ID, client.CommentRequest{Body: "looks good"}); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	link, err := alice.CreateShareLink(ctx, todo.ID, client.ShareLinkRequest{})
+	if err != nil || !strings.HasPrefix(link.Token, "shr_") || link.URL != "/api/v1/shared/"+link.Token {
+		t.Fatalf("CreateShareLink = %+v, %v", link, err)
+	}
+
+	shared, err := anonymous.OpenSharedTodo(ctx, link.Token, "")
+	if err != nil || shared.Title != "shared plan" || len(shared.Comments) != 1 || shared.Comments[0].Body != "looks good" {
+		t.Fatalf("OpenSharedTodo = %+v, %v", shared, err)
+	}
+	if _, err := anonymous.OpenSharedTodo(ctx, "shr_unknown", ""); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("OpenSharedTodo with an unknown token: got %v, want ErrNotFound", err)
+	}
+
+	links, err := alice.ListShareLinks(ctx, todo.ID)
+	if err != nil || len(links) != 1 || links[0].AccessCount != 1 || links[0].LastAccessedAt == nil || links[0].Token != "" {
+		t.Fatalf("ListShareLinks = %+v, %v; want one access and no token", links, err)
+	}
+
+	// Protected links need their password.
+	protected, err := alice.CreateShareLink(ctx, todo.ID, client.ShareLinkRequest{Password: "open sesame"})
+	if err != nil || !protected.HasPassword {
+		t.Fatalf("CreateShareLink with a password = %+v, %v", protected, err)
+	}
+	if _, err := anonymous.OpenSharedTodo(ctx, protected.Token, ""); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("OpenSharedTodo without the password: got %v, want ErrForbidden", err)
+	}
+	if _, err := anonymous.OpenSharedTodo(ctx, protected.Token, "wrong password"); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("OpenSharedTodo with a wrong password: got %v, want ErrForbidden", err)
+	}
+	if _, err := anonymous.OpenSharedTodo(ctx, protected.Token, "open sesame"); err != nil {
+		t.Fatalf("OpenSharedTodo with the password: %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if _, err := alice.CreateShareLink(ctx, todo.ID, client.ShareLinkRequest{ExpiresAt: &past}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("CreateShareLink expiring in the past: got %v, want ErrBadRequest", err)
+	}
+
+	// Only admins of the todo manage its links.
+	if _, err := bob.CreateShareLink(ctx, todo.ID, client.ShareLinkRequest{}); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("stranger's CreateShareLink: got %v, want ErrNotFound", err)
+	}
+	if _, err := alice.ShareTodo(ctx, todo.ID, client.GrantRequest{Email: "bob@example.com", Role: models.RoleEditor}); err != nil {
+		t.Fatalf("ShareTodo: %v", err)
+	}
+	if _, err := bob.ListShareLinks(ctx, todo.ID); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("editor's ListShareLinks: got %v, want ErrForbidden", err)
+	}
+
+	if err := alice.RevokeShareLink(ctx, todo.ID, link.ID); err != nil {
+		t.Fatalf("RevokeShareLink: %v", err)
+	}
+	if _, err := anonymous.OpenSharedTodo(ctx, link.Token, ""); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("OpenSharedTodo after revocation: got %v, want ErrNotFound", err)
+	}
+
+	// Six requests so far; the rest of the burst of ten is allowed.
+	for i := 0; i < 4; i++ {
+		if _, err := anonymous.OpenSharedTodo(ctx, protected.Token, "open sesame"); err != nil {
+			t.Fatalf("OpenSharedTodo %d: %v", i, err)
+		}
+	}
+	_, err = anonymous.OpenSharedTodo(ctx, protected.Token, "open sesame")
+	var apiErr *client.Error
+	if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		t.Fatalf("OpenSharedTodo past the limit: got %v, want ErrRateLimited with Retry-After", err)
+	}
+}
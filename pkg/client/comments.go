@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"todo-api/internal/models"
+)
+
+// CommentRequest writes a comment, replying to ParentCommentID when it is
+// set. The server names the caller as its author.
+type CommentRequest struct {
+	Body            string `json:"body"`
+	ParentCommentID *int64 `json:"parent_comment_id,omitempty"`
+}
+
+// ListComments returns a page of the comments of a todo in chronological
+// order. Deleted comments are included with an empty body.
+func (c *Client) ListComments(ctx context.Context, todoID int64, limit, offset int) (*Page[models.Comment], error) {
+	var page Page[models.Comment]
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d/comments", todoID), pagination(nil, limit, offset), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Comments iterates over every comment of a todo, 100 per request.
+func (c *Client) Comments(ctx context.Context, todoID int64) iter.Seq2[models.Comment, error] {
+	return paginate(func(offset int) (*Page[models.Comment], error) {
+		return c.ListComments(ctx, todoID, 100, offset)
+	}, 0)
+}
+
+func (c *Client) GetComment(ctx context.Context, todoID, commentID int64) (*models.Comment, error) {
+	var comment models.Comment
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d/comments/%d", todoID, commentID), nil, nil, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// CreateComment adds a comment, or a reply when ParentCommentID is set.
+func (c *Client) CreateComment(ctx context.Context, todoID int64, req CommentRequest) (*models.Comment, error) {
+	var created models.Comment
+	if _, err := c.call(ctx, http.MethodPost, idPath("/todos/%d/comments", todoID), nil, req, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateComment replaces the body of the comment with comment.ID.
+func (c *Client) UpdateComment(ctx context.Context, todoID int64, comment *models.Comment) (*models.Comment, error) {
+	var updated models.Comment
+	if _, err := c.call(ctx, http.MethodPut, idPath("/todos/%d/comments/%d", todoID, comment.ID), nil, CommentRequest{Body: comment.Body}, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) DeleteComment(ctx context.Context, todoID, commentID int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/todos/%d/comments/%d", todoID, commentID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"todo-api/internal/models"
+)
+
+// GrantRequest shares a todo with the user registered with Email.
+type GrantRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// ListGrants returns who a todo is shared with.
+func (c *Client) ListGrants(ctx context.Context, todoID int64) ([]models.Grant, error) {
+	var grants []models.Grant
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d/grants", todoID), nil, nil, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ShareTodo grants a user a role on a todo, replacing the role they had.
+// Callers without the admin role on the todo get ErrForbidden.
+func (c *Client) ShareTodo(ctx context.Context, todoID int64, req GrantRequest) (*models.Grant, error) {
+	var grant models.Grant
+	if _, err := c.call(ctx, http.MethodPost, idPath("/todos/%d/grants", todoID), nil, req, &grant); err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (c *Client) RevokeGrant(ctx context.Context, todoID, userID int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/todos/%d/grants/%d", todoID, userID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,12 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Health checks that the server is up.
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodGet, "/health", nil, nil, nil)
+	return err
+}
@@ -0,0 +1,157 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"todo-api/internal/models"
+	"todo-api/pkg/client"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "todo-api"
+)
+
+// startOIDCServer starts the server accepting JWTs signed by a locally
+// generated key, published by an httptest JWKS endpoint, and returns a
+// function issuing such tokens.
+func startOIDCServer(t *testing.T) (string, func(claims jwt.MapClaims) string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": "test",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+		}}})
+	}))
+	t.Cleanup(jwks.Close)
+
+	t.Setenv("TODO_OIDC_JWKS_URL", jwks.URL)
+	t.Setenv("TODO_OIDC_ISSUER", testIssuer)
+	t.Setenv("TODO_OIDC_AUDIENCE", testAudience)
+	url := startServer(t, true)
+
+	issue := func(claims jwt.MapClaims) string {
+		now := time.Now()
+		all := jwt.MapClaims{"iss": testIssuer, "aud": testAudience, "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+		for name, value := range claims {
+			all[name] = value
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, all)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return signed
+	}
+
+	return url, issue
+}
+
+func TestJWTsAuthenticateLinkedUsers(t *testing.T) {
+	url, issue := startOIDCServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	alice := loginAs(t, url, "alice@example.com")
+	aliceTodo := createTodo(t, alice, "alice's todo", false)
+
+	// Local registration does not verify emails, so even a verified email
+	// never links an identity to an existing account: whoever registered
+	// the address first would get the identity's todos.
+	for _, claims := range []jwt.MapClaims{
+		{"sub": "idp-evil", "email": "alice@example.com"},
+		{"sub": "idp-alice", "email": "Alice@example.com", "email_verified": true},
+	} {
+		claimer := newClient(t, url, client.WithToken(issue(claims)))
+		if _, err := claimer.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+			t.Fatalf("Me with a JWT of %v: got %v, want ErrUnauthorized", claims, err)
+		}
+		if _, err := claimer.GetTodo(ctx, aliceTodo.ID); !errors.Is(err, client.ErrUnauthorized) {
+			t.Fatalf("GetTodo with a JWT of %v: got %v, want ErrUnauthorized", claims, err)
+		}
+	}
+
+	// The account holder links the identity explicitly instead.
+	aliceToken := issue(jwt.MapClaims{"sub": "idp-alice", "email": "Alice@example.com", "email_verified": true})
+	if _, err := alice.LinkIdentity(ctx, "not a jwt"); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("LinkIdentity with an invalid JWT: got %v, want ErrBadRequest", err)
+	}
+	if linked, err := alice.LinkIdentity(ctx, aliceToken); err != nil || linked.Email != "alice@example.com" {
+		t.Fatalf("LinkIdentity = %+v, %v; want alice's account", linked, err)
+	}
+	if _, err := alice.LinkIdentity(ctx, aliceToken); err != nil {
+		t.Fatalf("LinkIdentity again: %v", err)
+	}
+	aliceJWT := newClient(t, url, client.WithToken(aliceToken))
+	me, err := aliceJWT.Me(ctx)
+	if err != nil || me.Email != "alice@example.com" {
+		t.Fatalf("Me with alice's JWT = %+v, %v; want her account", me, err)
+	}
+	if got, err := aliceJWT.GetTodo(ctx, aliceTodo.ID); err != nil || got.Title != aliceTodo.Title {
+		t.Fatalf("GetTodo with alice's JWT = %+v, %v", got, err)
+	}
+
+	// Linking takes a login token of the account, and an identity linked
+	// to one account cannot be moved to another.
+	if _, err := aliceJWT.LinkIdentity(ctx, aliceToken); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("LinkIdentity with a JWT: got %v, want ErrForbidden", err)
+	}
+	bob := loginAs(t, url, "bob@example.com")
+	if _, err := bob.LinkIdentity(ctx, aliceToken); !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("bob's LinkIdentity of alice's identity: got %v, want ErrConflict", err)
+	}
+
+	// A new subject gets an account of its own on first use.
+	carol := newClient(t, url, client.WithToken(issue(jwt.MapClaims{"sub": "idp-carol", "email": "carol@example.com"})))
+	carolTodo := createTodo(t, carol, "carol's todo", false)
+	if carolTodo.OwnerID == nil || *carolTodo.OwnerID == me.ID {
+		t.Fatalf("carol's todo owner = %v, want a new account", carolTodo.OwnerID)
+	}
+	if _, err := carol.GetTodo(ctx, aliceTodo.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("carol's GetTodo of alice's todo: got %v, want ErrNotFound", err)
+	}
+	if _, err := carol.ListAPIKeys(ctx); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("carol's ListAPIKeys: got %v, want ErrForbidden", err)
+	}
+
+	// The admin role grants the admin scope.
+	carolAdmin := newClient(t, url, client.WithToken(issue(jwt.MapClaims{"sub": "idp-carol", "roles": []string{"admin"}})))
+	if _, err := carolAdmin.ListAPIKeys(ctx); err != nil {
+		t.Fatalf("ListAPIKeys with the admin role: %v", err)
+	}
+
+	expired := newClient(t, url, client.WithToken(issue(jwt.MapClaims{"sub": "idp-carol", "exp": time.Now().Add(-time.Hour).Unix()})))
+	if _, err := expired.ListTodos(ctx, client.ListTodosOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("ListTodos with an expired JWT: got %v, want ErrUnauthorized", err)
+	}
+	wrongAudience := newClient(t, url, client.WithToken(issue(jwt.MapClaims{"sub": "idp-carol", "aud": "another-api"})))
+	if _, err := wrongAudience.ListTodos(ctx, client.ListTodosOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("ListTodos with a JWT for another audience: got %v, want ErrUnauthorized", err)
+	}
+
+	if _, err := alice.CreateTodo(ctx, &models.Todo{Title: "API keys still work"}); err != nil {
+		t.Fatalf("CreateTodo with alice's session key: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/pkg/client"
+)
+
+var fastRetries = client.RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  5 * time.Millisecond,
+}
+
+// newStubClient returns a client for a server that answers with the given
+// statuses in turn, repeating the last one, and a counter of requests seen.
+func newStubClient(t *testing.T, policy client.RetryPolicy, headers http.Header, statuses ...int) (*client.Client, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		for key, values := range headers {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status >= 400 {
+			w.Write([]byte(`{"error":"stub failure","details":"attempt failed"}`))
+			return
+		}
+		w.Write([]byte(`{"id":1,"title":"stub todo"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	c, err := client.New(ts.URL+"/api/v1", client.WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return c, &calls
+}
+
+func TestRetriesServerErrorsOnIdempotentRequests(t *testing.T) {
+	c, calls := newStubClient(t, fastRetries, nil, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+
+	todo, err := c.GetTodo(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if todo.Title != "stub todo" || calls.Load() != 3 {
+		t.Fatalf("got %+v after %d attempts", todo, calls.Load())
+	}
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	c, calls := newStubClient(t, fastRetries, nil, http.StatusInternalServerError)
+
+	err := c.DeleteTodo(context.Background(), 1)
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 || apiErr.Message != "stub failure" {
+		t.Fatalf("DeleteTodo: got %v", err)
+	}
+	if !errors.Is(err, client.ErrServer) || calls.Load() != 3 {
+		t.Fatalf("got %v after %d attempts", err, calls.Load())
+	}
+}
+
+func TestDoesNotRetryPostOnServerError(t *testing.T) {
+	c, calls := newStubClient(t, fastRetries, nil, http.StatusInternalServerError, http.StatusCreated)
+
+	_, err := c.CreateTodo(context.Background(), &models.Todo{Title: "not twice"})
+	if !errors.Is(err, client.ErrServer) || calls.Load() != 1 {
+		t.Fatalf("got %v after %d attempts", err, calls.Load())
+	}
+}
+
+func TestRetriesPostWhenRateLimited(t *testing.T) {
+	headers := http.Header{"Retry-After": {"1"}}
+	c, calls := newStubClient(t, fastRetries, headers, http.StatusTooManyRequests, http.StatusCreated)
+
+	start := time.Now()
+	if _, err := c.CreateTodo(context.Background(), &models.Todo{Title: "eventually"}); err != nil {
+		t.Fatalf("CreateTodo: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("got %d attempts, want 2", calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %v, before the Retry-After of 1s", elapsed)
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	c, calls := newStubClient(t, fastRetries, nil, http.StatusNotFound, http.StatusOK)
+
+	_, err := c.GetTodo(context.Background(), 1)
+	if !errors.Is(err, client.ErrNotFound) || calls.Load() != 1 {
+		t.Fatalf("got %v after %d attempts", err, calls.Load())
+	}
+}
+
+func TestContextCancelsBackoff(t *testing.T) {
+	policy := client.RetryPolicy{MaxAttempts: 5, MinBackoff: time.Hour, MaxBackoff: time.Hour}
+	c, calls := newStubClient(t, policy, nil, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetTodo(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || calls.Load() != 1 {
+		t.Fatalf("got %v after %d attempts", err, calls.Load())
+	}
+}
+
+func TestBackoffStaysWithinPolicy(t *testing.T) {
+	policy := client.RetryPolicy{MaxAttempts: 10, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt := 1; attempt <= 40; attempt++ {
+		window := min(policy.MinBackoff<<min(attempt-1, 20), policy.MaxBackoff)
+		if delay := client.Backoff(policy, attempt); delay <= 0 || delay > window {
+			t.Fatalf("Backoff(attempt %d) = %v, want within (0, %v]", attempt, delay, window)
+		}
+	}
+}
+
+func TestNewRejectsRelativeURLs(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8082", "/api/v1", "ftp://example.com"} {
+		if _, err := client.New(baseURL); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", baseURL)
+		}
+	}
+}
+
+func TestDoesNotWaitOutLongRetryAfter(t *testing.T) {
+	headers := http.Header{"Retry-After": {"3600"}}
+	c, calls := newStubClient(t, fastRetries, headers, http.StatusTooManyRequests, http.StatusCreated)
+
+	_, err := c.CreateTodo(context.Background(), &models.Todo{Title: "tomorrow"})
+	if !errors.Is(err, client.ErrRateLimited) || calls.Load() != 1 {
+		t.Fatalf("got %v after %d attempts, want ErrRateLimited at once", err, calls.Load())
+	}
+}
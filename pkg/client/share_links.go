@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// ShareLinkRequest sets the optional expiry and password of a new share
+// link.
+type ShareLinkRequest struct {
+	Password  string     `json:"password,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ListShareLinks returns the share links of a todo, without their tokens.
+func (c *Client) ListShareLinks(ctx context.Context, todoID int64) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d/share-links", todoID), nil, nil, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// CreateShareLink creates a public link to a todo. The returned link holds
+// its token, which cannot be retrieved later.
+func (c *Client) CreateShareLink(ctx context.Context, todoID int64, req ShareLinkRequest) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if _, err := c.call(ctx, http.MethodPost, idPath("/todos/%d/share-links", todoID), nil, req, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (c *Client) RevokeShareLink(ctx context.Context, todoID, linkID int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/todos/%d/share-links/%d", todoID, linkID), nil, nil, nil)
+	return err
+}
+
+// OpenSharedTodo opens a share link by its token, which needs no API key.
+// password is sent for protected links; a missing or wrong one gives
+// ErrForbidden.
+func (c *Client) OpenSharedTodo(ctx context.Context, token, password string) (*models.SharedTodo, error) {
+	req := request{method: http.MethodGet, path: apiPrefix + "/shared/" + url.PathEscape(token)}
+	if password != "" {
+		req.header = http.Header{"X-Share-Password": {password}}
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var shared models.SharedTodo
+	if err := json.NewDecoder(resp.Body).Decode(&shared); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &shared, nil
+}
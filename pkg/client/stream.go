@@ -0,0 +1,133 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"todo-api/internal/events"
+)
+
+// EventReset is the type of the event sent first on a resumed stream when
+// some events after the requested sequence were no longer available.
+const EventReset events.Type = "reset"
+
+// StreamOptions filters a change stream. LastEventID resumes after the
+// event with that sequence number.
+type StreamOptions struct {
+	Types       []events.Type
+	TodoID      int64
+	Completed   *bool
+	LastEventID uint64
+}
+
+// EventStream reads Server-Sent Events from the todo change stream.
+type EventStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// LastEventID is the sequence of the last event read, for resuming.
+	LastEventID uint64
+}
+
+// StreamTodos opens the todo change stream. The stream ends with an error
+// from Next when the server closes it; open a new one with LastEventID to
+// resume.
+func (c *Client) StreamTodos(ctx context.Context, opts StreamOptions) (*EventStream, error) {
+	query := url.Values{}
+	if len(opts.Types) > 0 {
+		types := make([]string, len(opts.Types))
+		for i, eventType := range opts.Types {
+			types[i] = string(eventType)
+		}
+		query.Set("types", strings.Join(types, ","))
+	}
+	if opts.TodoID != 0 {
+		query.Set("todo_id", strconv.FormatInt(opts.TodoID, 10))
+	}
+	if opts.Completed != nil {
+		query.Set("completed", strconv.FormatBool(*opts.Completed))
+	}
+	if opts.LastEventID != 0 {
+		query.Set("last_event_id", strconv.FormatUint(opts.LastEventID, 10))
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: apiPrefix + "/todos/stream", query: query})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventStream{
+		body:        resp.Body,
+		reader:      bufio.NewReader(resp.Body),
+		LastEventID: opts.LastEventID,
+	}, nil
+}
+
+// Next blocks until the next event arrives. A reset event carries only its
+// Type and Seq.
+func (s *EventStream) Next() (events.Event, error) {
+	var id, name string
+	var data strings.Builder
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return events.Event{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if data.Len() == 0 {
+				continue
+			}
+			return s.dispatch(id, name, data.String())
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			// Comment, such as a heartbeat.
+		case "id":
+			id = value
+		case "event":
+			name = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+}
+
+func (s *EventStream) dispatch(id, name, data string) (events.Event, error) {
+	var event events.Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return events.Event{}, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	if seq, err := strconv.ParseUint(id, 10, 64); err == nil {
+		event.Seq = seq
+		s.LastEventID = seq
+	}
+	if name != "" {
+		event.Type = events.Type(name)
+	}
+
+	return event, nil
+}
+
+// Close ends the stream.
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
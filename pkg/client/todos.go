@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"todo-api/internal/models"
+)
+
+// defaultPageLimit is the page size the server uses when none is given.
+const defaultPageLimit = 20
+
+// Page is one page of a paginated listing.
+type Page[T any] struct {
+	Items  []T   `json:"data"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+// ListTodosOptions filters and pages a todo listing.
+type ListTodosOptions struct {
+	Completed *bool
+	// Query is matched against title and description.
+	Query string
+	// Limit defaults to 20 on the server and may be at most 100.
+	Limit  int
+	Offset int
+}
+
+func (o ListTodosOptions) values() url.Values {
+	query := url.Values{}
+	if o.Completed != nil {
+		query.Set("completed", strconv.FormatBool(*o.Completed))
+	}
+	if o.Query != "" {
+		query.Set("q", o.Query)
+	}
+	query = pagination(query, o.Limit, o.Offset)
+	if len(query) == 0 {
+		// An explicit offset asks the server for a paged response.
+		query.Set("offset", "0")
+	}
+	return query
+}
+
+// ListTodos returns one page of todos, newest first.
+func (c *Client) ListTodos(ctx context.Context, opts ListTodosOptions) (*Page[models.Todo], error) {
+	var todos []models.Todo
+	resp, err := c.call(ctx, http.MethodGet, apiPrefix+"/todos", opts.values(), nil, &todos)
+	if err != nil {
+		return nil, err
+	}
+
+	total, _ := strconv.ParseInt(resp.Header.Get("X-Total-Count"), 10, 64)
+	if todos == nil {
+		todos = []models.Todo{}
+	}
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+
+	return &Page[models.Todo]{Items: todos, Total: total, Limit: limit, Offset: opts.Offset}, nil
+}
+
+// Todos iterates over every todo matching opts, fetching pages of
+// opts.Limit (100 when unset) as needed. Iteration stops at the first error.
+func (c *Client) Todos(ctx context.Context, opts ListTodosOptions) iter.Seq2[models.Todo, error] {
+	if opts.Limit == 0 {
+		opts.Limit = 100
+	}
+
+	return paginate(func(offset int) (*Page[models.Todo], error) {
+		opts.Offset = offset
+		return c.ListTodos(ctx, opts)
+	}, opts.Offset)
+}
+
+func (c *Client) GetTodo(ctx context.Context, id int64) (*models.Todo, error) {
+	var todo models.Todo
+	if _, err := c.call(ctx, http.MethodGet, idPath("/todos/%d", id), nil, nil, &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// CreateTodo creates a todo from its title, description and completed
+// state, and returns it as stored.
+func (c *Client) CreateTodo(ctx context.Context, todo *models.Todo) (*models.Todo, error) {
+	var created models.Todo
+	if _, err := c.call(ctx, http.MethodPost, apiPrefix+"/todos", nil, todo, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateTodo replaces the title, description and completed state of the
+// todo with todo.ID.
+func (c *Client) UpdateTodo(ctx context.Context, todo *models.Todo) (*models.Todo, error) {
+	var updated models.Todo
+	if _, err := c.call(ctx, http.MethodPut, idPath("/todos/%d", todo.ID), nil, todo, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) DeleteTodo(ctx context.Context, id int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/todos/%d", id), nil, nil, nil)
+	return err
+}
+
+// paginate turns a page fetcher into an iterator starting at offset.
+func paginate[T any](fetch func(offset int) (*Page[T], error), offset int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			page, err := fetch(offset)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			offset += len(page.Items)
+			if len(page.Items) == 0 || int64(offset) >= page.Total {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"todo-api/internal/models"
+)
+
+// WebhookRequest is the body sent when creating or updating a webhook
+// subscription. An empty Secret is generated on creation and kept on update.
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+type replayResponse struct {
+	Replayed int64 `json:"replayed"`
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if _, err := c.call(ctx, http.MethodGet, apiPrefix+"/webhooks", nil, nil, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (c *Client) GetWebhook(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if _, err := c.call(ctx, http.MethodGet, idPath("/webhooks/%d", id), nil, nil, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// CreateWebhook subscribes a URL to todo events. The returned subscription
+// is the only one carrying the secret.
+func (c *Client) CreateWebhook(ctx context.Context, webhook WebhookRequest) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if _, err := c.call(ctx, http.MethodPost, apiPrefix+"/webhooks", nil, webhook, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (c *Client) UpdateWebhook(ctx context.Context, id int64, webhook WebhookRequest) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if _, err := c.call(ctx, http.MethodPut, idPath("/webhooks/%d", id), nil, webhook, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id int64) error {
+	_, err := c.call(ctx, http.MethodDelete, idPath("/webhooks/%d", id), nil, nil, nil)
+	return err
+}
+
+// ListDeliveries returns a page of the deliveries of a webhook, optionally
+// only those with status pending, delivered or dead.
+func (c *Client) ListDeliveries(ctx context.Context, webhookID int64, status string, limit, offset int) (*Page[models.WebhookDelivery], error) {
+	query := pagination(nil, limit, offset)
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var page Page[models.WebhookDelivery]
+	if _, err := c.call(ctx, http.MethodGet, idPath("/webhooks/%d/deliveries", webhookID), query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Deliveries iterates over the deliveries of a webhook, 100 per request.
+func (c *Client) Deliveries(ctx context.Context, webhookID int64, status string) iter.Seq2[models.WebhookDelivery, error] {
+	return paginate(func(offset int) (*Page[models.WebhookDelivery], error) {
+		return c.ListDeliveries(ctx, webhookID, status, 100, offset)
+	}, 0)
+}
+
+// ReplayDeliveries requeues every dead delivery of a webhook and returns
+// how many were requeued.
+func (c *Client) ReplayDeliveries(ctx context.Context, webhookID int64) (int64, error) {
+	var resp replayResponse
+	if _, err := c.call(ctx, http.MethodPost, idPath("/webhooks/%d/deliveries/replay", webhookID), nil, nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Replayed, nil
+}
+
+// ReplayDelivery requeues a single dead delivery.
+func (c *Client) ReplayDelivery(ctx context.Context, webhookID, deliveryID int64) (int64, error) {
+	var resp replayResponse
+	if _, err := c.call(ctx, http.MethodPost, idPath("/webhooks/%d/deliveries/%d/replay", webhookID, deliveryID), nil, nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Replayed, nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ParsePagination reads the limit and offset query parameters, applying
+// DefaultPageLimit when limit is omitted.
+func ParsePagination(c *gin.Context) (limit, offset int, err error) {
+	limit = DefaultPageLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be a number between 1 and %d", MaxPageLimit)
+		}
+	}
+
+	if raw := c.Query("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative number")
+		}
+	}
+
+	return limit, offset, nil
+}
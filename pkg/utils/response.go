@@ -1,14 +1,21 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"todo-api/internal/logging"
 )
 
+// ErrorResponse is the body of every error. RequestID repeats the
+// X-Request-ID header, so a report can be matched with the server's logs.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Error     string `json:"error"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type SuccessResponse struct {
@@ -16,25 +23,57 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+func errorResponse(c *gin.Context, message, details string) ErrorResponse {
+	return ErrorResponse{
+		Error:     message,
+		Details:   details,
+		RequestID: logging.RequestID(c.Request.Context()),
+	}
+}
+
 func BadRequest(c *gin.Context, message, details string) {
-	c.JSON(http.StatusBadRequest, ErrorResponse{
-		Error:   message,
-		Details: details,
-	})
+	c.JSON(http.StatusBadRequest, errorResponse(c, message, details))
 }
 
 func NotFound(c *gin.Context, message, details string) {
-	c.JSON(http.StatusNotFound, ErrorResponse{
-		Error:   message,
-		Details: details,
-	})
+	c.JSON(http.StatusNotFound, errorResponse(c, message, details))
+}
+
+// Unauthorized asks the client to authenticate with a bearer token.
+func Unauthorized(c *gin.Context, message, details string) {
+	c.Header("WWW-Authenticate", `Bearer realm="todo-api"`)
+	c.JSON(http.StatusUnauthorized, errorResponse(c, message, details))
+}
+
+func Forbidden(c *gin.Context, message, details string) {
+	c.JSON(http.StatusForbidden, errorResponse(c, message, details))
 }
 
+func Conflict(c *gin.Context, message, details string) {
+	c.JSON(http.StatusConflict, errorResponse(c, message, details))
+}
+
+// InternalServerError also logs the failure, since it points at a problem
+// on the server rather than in the request.
 func InternalServerError(c *gin.Context, message, details string) {
-	c.JSON(http.StatusInternalServerError, ErrorResponse{
-		Error:   message,
-		Details: details,
-	})
+	slog.ErrorContext(c.Request.Context(), message, "error", details)
+	c.JSON(http.StatusInternalServerError, errorResponse(c, message, details))
+}
+
+func RequestEntityTooLarge(c *gin.Context, message, details string) {
+	c.JSON(http.StatusRequestEntityTooLarge, errorResponse(c, message, details))
+}
+
+func UnsupportedMediaType(c *gin.Context, message, details string) {
+	c.JSON(http.StatusUnsupportedMediaType, errorResponse(c, message, details))
+}
+
+func UnprocessableEntity(c *gin.Context, message, details string) {
+	c.JSON(http.StatusUnprocessableEntity, errorResponse(c, message, details))
+}
+
+func TooManyRequests(c *gin.Context, message, details string) {
+	c.JSON(http.StatusTooManyRequests, errorResponse(c, message, details))
 }
 
 func Created(c *gin.Context, data interface{}) {
@@ -56,5 +95,43 @@ func HandleIDError(c *gin.Context, err error) {
 }
 
 func HandleJSONError(c *gin.Context, err error) {
+	if bodyTooLarge(c, err) {
+		return
+	}
 	BadRequest(c, "Invalid JSON format", err.Error())
-}
\ No newline at end of file
+}
+
+// HandleBodyError answers a failure to read the request body.
+func HandleBodyError(c *gin.Context, err error) {
+	if bodyTooLarge(c, err) {
+		return
+	}
+	BadRequest(c, "Failed to read request body", err.Error())
+}
+
+// bodyTooLarge answers 413 when err comes from reading past the body size
+// limit.
+func bodyTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	RequestEntityTooLarge(c, "Request body too large", fmt.Sprintf("the limit is %d bytes", tooLarge.Limit))
+	return true
+}
+
+type PaginatedResponse struct {
+	Data   interface{} `json:"data"`
+	Total  int64       `json:"total" example:"42"`
+	Limit  int         `json:"limit" example:"20"`
+	Offset int         `json:"offset" example:"0"`
+}
+
+func Paginated(c *gin.Context, data interface{}, total int64, limit, offset int) {
+	c.JSON(http.StatusOK, PaginatedResponse{
+		Data:   data,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}